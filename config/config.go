@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/spf13/viper"
@@ -15,13 +16,23 @@ type Config struct {
 	SMTP     SMTPConfig
 	Logger   LoggerConfig
 	ImageKit ImageKitConfig
+	Storage  StorageConfig
+	Image    ImageConfig
+	Scanner  ScannerConfig
+	OAuth    OAuthConfig
+	Account  AccountConfig
+	Regions  RegionsConfig
+	Geocoder GeocoderConfig
 }
 
 type AppConfig struct {
-	Name  string
-	Env   string
-	Port  string
-	Debug bool
+	Name    string
+	Env     string
+	Port    string
+	Debug   bool
+	BaseURL string
+	// FrontendURL is where emailed links that need a form (e.g. invitations) point; falls back to the API
+	FrontendURL string
 }
 
 type DatabaseConfig struct {
@@ -32,11 +43,14 @@ type DatabaseConfig struct {
 	Name     string
 	SSLMode  string
 	TimeZone string
+	// AutoMigrate applies pending migrations on startup
+	AutoMigrate bool
 }
 
 type JWTConfig struct {
-	Secret      string
-	ExpiredHour int
+	Secret               string
+	ExpiredHour          int
+	ImpersonationMinutes int
 }
 
 type CORSConfig struct {
@@ -44,11 +58,11 @@ type CORSConfig struct {
 }
 
 type SMTPConfig struct {
-	Email    string
-	Password string
-	Host     string
-	Port     int
-	FromName string
+	Email     string
+	Password  string
+	Host      string
+	Port      int
+	FromName  string
 	FromEmail string
 }
 
@@ -67,6 +81,123 @@ type ImageKitConfig struct {
 	UrlEndpoint string
 }
 
+type StorageConfig struct {
+	// Provider is "local", "s3" or "imagekit"; when empty ImageKit is used if it has
+	// a private key, otherwise the local filesystem
+	Provider string
+	// SigningKey signs upload and download URLs served by the API; defaults to JWT_SECRET
+	SigningKey string
+	Local      LocalStorageConfig
+	S3         S3StorageConfig
+	Tus        TusStorageConfig
+	// Quotas limit what users of each role (USER, ADMIN, SUPERADMIN) may store
+	Quotas map[string]QuotaConfig
+	GC     GCStorageConfig
+}
+
+type LocalStorageConfig struct {
+	// Path is the directory files are written to
+	Path string
+	// URL is the public base URL of the files; defaults to APP_BASE_URL/files
+	URL string
+}
+
+// TusStorageConfig configures resumable (tus) uploads, which are staged on the local disk
+type TusStorageConfig struct {
+	// Path is the directory chunks are staged in until the upload is complete
+	Path string
+	// ExpiryHours is how long an unfinished upload can be resumed
+	ExpiryHours int
+}
+
+// GCStorageConfig configures the removal of profile and product images no user or product uses
+type GCStorageConfig struct {
+	// GraceHours is how long an unused image is kept, giving clients time to attach it
+	GraceHours int
+	// DryRun only logs what would be deleted
+	DryRun bool
+}
+
+// QuotaConfig limits the attachments of one role. A zero value keeps the default limit
+// of the role, a negative one lifts the limit.
+type QuotaConfig struct {
+	// MaxMB is the total size of the files a user may store
+	MaxMB int64
+	// MaxFiles is the number of files a user may store
+	MaxFiles int
+	// UploadsPerHour is the number of uploads a user may start in an hour
+	UploadsPerHour int
+}
+
+// S3StorageConfig configures an S3-compatible object store (AWS S3, MinIO, R2, ...)
+type S3StorageConfig struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+	// PublicURL is the base URL objects are served from (e.g. a CDN); defaults to the bucket URL
+	PublicURL string
+}
+
+type ImageConfig struct {
+	// Variants are generated for uploaded images; the usecase applies defaults when empty
+	Variants []ImageVariantConfig
+	// JPEGQuality is the quality (1-100) of re-encoded JPEG images
+	JPEGQuality int
+}
+
+type ImageVariantConfig struct {
+	Name string
+	// MaxSize is the longest edge in pixels
+	MaxSize int
+	// Format is "jpeg" or "webp"
+	Format string
+}
+
+// ScannerConfig configures malware scanning of uploaded files; scanning is off when no address is set
+type ScannerConfig struct {
+	// ClamAVAddress is the clamd address, "host:port" or "unix:///path/to/clamd.sock"
+	ClamAVAddress string
+	// TimeoutSeconds limits a single scan
+	TimeoutSeconds int
+}
+
+type AccountConfig struct {
+	// DeletionGraceDays is how long a self-service deletion request can be cancelled before erasure
+	DeletionGraceDays int
+	// ReactivationDays is how long a deactivated account can be reactivated before it is erased
+	ReactivationDays int
+}
+
+type RegionsConfig struct {
+	// DatasetPath points to the Kemendagri region CSV seeded on start; when empty the
+	// regions already in the database are used
+	DatasetPath string
+}
+
+type GeocoderConfig struct {
+	// Provider is "nominatim", or empty to only use region centroids
+	Provider  string
+	URL       string
+	UserAgent string
+}
+
+type OAuthConfig struct {
+	Providers []OIDCProviderConfig
+}
+
+// OIDCProviderConfig configures one OpenID Connect provider (Google, Microsoft, Keycloak, ...)
+type OIDCProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	RedirectURL  string
+}
+
 func LoadConfig() (*Config, error) {
 	viper.SetConfigFile(".env")
 	viper.AutomaticEnv()
@@ -75,25 +206,34 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
+	imageConfig, err := loadImageConfig()
+	if err != nil {
+		return nil, err
+	}
+
 	config := &Config{
 		App: AppConfig{
-			Name:  viper.GetString("APP_NAME"),
-			Env:   viper.GetString("APP_ENV"),
-			Port:  viper.GetString("APP_PORT"),
-			Debug: viper.GetBool("APP_DEBUG"),
+			Name:        viper.GetString("APP_NAME"),
+			Env:         viper.GetString("APP_ENV"),
+			Port:        viper.GetString("APP_PORT"),
+			Debug:       viper.GetBool("APP_DEBUG"),
+			BaseURL:     viper.GetString("APP_BASE_URL"),
+			FrontendURL: viper.GetString("APP_FRONTEND_URL"),
 		},
 		Database: DatabaseConfig{
-			Host:     viper.GetString("DB_HOST"),
-			Port:     viper.GetString("DB_PORT"),
-			User:     viper.GetString("DB_USER"),
-			Password: viper.GetString("DB_PASSWORD"),
-			Name:     viper.GetString("DB_NAME"),
-			SSLMode:  viper.GetString("DB_SSL_MODE"),
-			TimeZone: viper.GetString("DB_TIMEZONE"),
+			Host:        viper.GetString("DB_HOST"),
+			Port:        viper.GetString("DB_PORT"),
+			User:        viper.GetString("DB_USER"),
+			Password:    viper.GetString("DB_PASSWORD"),
+			Name:        viper.GetString("DB_NAME"),
+			SSLMode:     viper.GetString("DB_SSL_MODE"),
+			TimeZone:    viper.GetString("DB_TIMEZONE"),
+			AutoMigrate: viper.GetBool("DB_AUTO_MIGRATE"),
 		},
 		JWT: JWTConfig{
-			Secret:      viper.GetString("JWT_SECRET"),
-			ExpiredHour: viper.GetInt("JWT_EXPIRED_HOUR"),
+			Secret:               viper.GetString("JWT_SECRET"),
+			ExpiredHour:          viper.GetInt("JWT_EXPIRED_HOUR"),
+			ImpersonationMinutes: viper.GetInt("JWT_IMPERSONATION_MINUTES"),
 		},
 		CORS: CORSConfig{
 			AllowedOrigins: strings.Split(viper.GetString("CORS_ALLOWED_ORIGINS"), ","),
@@ -119,11 +259,158 @@ func LoadConfig() (*Config, error) {
 			PrivateKey:  viper.GetString("IMAGEKIT_PRIVATE_KEY"),
 			UrlEndpoint: viper.GetString("IMAGEKIT_URL_ENDPOINT"),
 		},
+		Storage: StorageConfig{
+			Provider:   strings.ToLower(viper.GetString("STORAGE_PROVIDER")),
+			SigningKey: viper.GetString("STORAGE_SIGNING_KEY"),
+			Local: LocalStorageConfig{
+				Path: viper.GetString("STORAGE_LOCAL_PATH"),
+				URL:  viper.GetString("STORAGE_LOCAL_URL"),
+			},
+			S3: S3StorageConfig{
+				Endpoint:  viper.GetString("S3_ENDPOINT"),
+				Region:    viper.GetString("S3_REGION"),
+				Bucket:    viper.GetString("S3_BUCKET"),
+				AccessKey: viper.GetString("S3_ACCESS_KEY"),
+				SecretKey: viper.GetString("S3_SECRET_KEY"),
+				UseSSL:    viper.GetBool("S3_USE_SSL"),
+				PublicURL: viper.GetString("S3_PUBLIC_URL"),
+			},
+			Tus: TusStorageConfig{
+				Path:        viper.GetString("STORAGE_TUS_PATH"),
+				ExpiryHours: viper.GetInt("STORAGE_TUS_EXPIRY_HOURS"),
+			},
+			Quotas: loadQuotaConfig(),
+			GC: GCStorageConfig{
+				GraceHours: viper.GetInt("STORAGE_GC_GRACE_HOURS"),
+				DryRun:     viper.GetBool("STORAGE_GC_DRY_RUN"),
+			},
+		},
+		Image: imageConfig,
+		Scanner: ScannerConfig{
+			ClamAVAddress:  viper.GetString("CLAMAV_ADDRESS"),
+			TimeoutSeconds: viper.GetInt("CLAMAV_TIMEOUT_SECONDS"),
+		},
+		OAuth: loadOAuthConfig(),
+		Account: AccountConfig{
+			DeletionGraceDays: viper.GetInt("ACCOUNT_DELETION_GRACE_DAYS"),
+			ReactivationDays:  viper.GetInt("ACCOUNT_REACTIVATION_DAYS"),
+		},
+		Regions: RegionsConfig{
+			DatasetPath: viper.GetString("REGIONS_DATASET_PATH"),
+		},
+		Geocoder: GeocoderConfig{
+			Provider:  strings.ToLower(viper.GetString("GEOCODER_PROVIDER")),
+			URL:       viper.GetString("GEOCODER_URL"),
+			UserAgent: viper.GetString("GEOCODER_USER_AGENT"),
+		},
+	}
+
+	if config.Storage.SigningKey == "" {
+		config.Storage.SigningKey = config.JWT.Secret
 	}
 
 	return config, nil
 }
 
+// loadOAuthConfig reads OIDC_PROVIDERS (e.g. "google,microsoft") and the
+// OIDC_<NAME>_* variables of every listed provider
+func loadOAuthConfig() OAuthConfig {
+	var providers []OIDCProviderConfig
+
+	for _, name := range strings.Split(viper.GetString("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		scopes := strings.Fields(strings.ReplaceAll(viper.GetString(prefix+"SCOPES"), ",", " "))
+		if len(scopes) == 0 {
+			scopes = []string{"openid", "email", "profile"}
+		}
+
+		providers = append(providers, OIDCProviderConfig{
+			Name:         name,
+			Issuer:       viper.GetString(prefix + "ISSUER"),
+			ClientID:     viper.GetString(prefix + "CLIENT_ID"),
+			ClientSecret: viper.GetString(prefix + "CLIENT_SECRET"),
+			Scopes:       scopes,
+			RedirectURL:  viper.GetString(prefix + "REDIRECT_URL"),
+		})
+	}
+
+	return OAuthConfig{Providers: providers}
+}
+
+// loadQuotaConfig reads the STORAGE_QUOTA_<ROLE>_MB, _FILES and _UPLOADS_PER_HOUR
+// variables of every role
+func loadQuotaConfig() map[string]QuotaConfig {
+	quotas := map[string]QuotaConfig{}
+	for _, role := range []string{"USER", "ADMIN", "SUPERADMIN"} {
+		prefix := "STORAGE_QUOTA_" + role + "_"
+		quotas[role] = QuotaConfig{
+			MaxMB:          viper.GetInt64(prefix + "MB"),
+			MaxFiles:       viper.GetInt(prefix + "FILES"),
+			UploadsPerHour: viper.GetInt(prefix + "UPLOADS_PER_HOUR"),
+		}
+	}
+
+	return quotas
+}
+
+// loadImageConfig reads IMAGE_VARIANTS as comma separated name:max_size[:format]
+// entries, e.g. "thumbnail:200:webp,medium:800,large:1600"
+func loadImageConfig() (ImageConfig, error) {
+	cfg := ImageConfig{JPEGQuality: viper.GetInt("IMAGE_JPEG_QUALITY")}
+
+	for _, entry := range strings.Split(viper.GetString("IMAGE_VARIANTS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, ":")
+		if len(parts) < 2 || len(parts) > 3 {
+			return cfg, fmt.Errorf("invalid IMAGE_VARIANTS entry %q, expected name:max_size[:format]", entry)
+		}
+		maxSize, err := strconv.Atoi(parts[1])
+		if err != nil || maxSize <= 0 {
+			return cfg, fmt.Errorf("invalid IMAGE_VARIANTS size in %q", entry)
+		}
+		format := "jpeg"
+		if len(parts) == 3 {
+			format = strings.ToLower(parts[2])
+		}
+		if format != "jpeg" && format != "webp" {
+			return cfg, fmt.Errorf("invalid IMAGE_VARIANTS format in %q, use jpeg or webp", entry)
+		}
+
+		cfg.Variants = append(cfg.Variants, ImageVariantConfig{
+			Name:    strings.ToLower(parts[0]),
+			MaxSize: maxSize,
+			Format:  format,
+		})
+	}
+
+	return cfg, nil
+}
+
+// APIBaseURL returns the public base URL of the API (including the /api prefix)
+func (c *AppConfig) APIBaseURL() string {
+	if c.BaseURL != "" {
+		return strings.TrimRight(c.BaseURL, "/") + "/api"
+	}
+	return fmt.Sprintf("http://localhost:%s/api", c.Port)
+}
+
+// FrontendBaseURL returns the base URL for user-facing pages, or the API base URL when no frontend is configured
+func (c *AppConfig) FrontendBaseURL() string {
+	if c.FrontendURL != "" {
+		return strings.TrimRight(c.FrontendURL, "/")
+	}
+	return c.APIBaseURL()
+}
+
 func (c *DatabaseConfig) DSN() string {
 	return fmt.Sprintf(
 		"host=%s user=%s password=%s dbname=%s port=%s sslmode=%s TimeZone=%s",
@@ -0,0 +1,43 @@
+package dto
+
+// AccountDeletionRequest confirms a self-service account deletion
+type AccountDeletionRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
+// DeactivateAccountRequest confirms a self-service account deactivation
+type DeactivateAccountRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
+// DeactivateAccountResponse tells the user until when the account can be reactivated
+type DeactivateAccountResponse struct {
+	DeactivatedAt      string `json:"deactivated_at"`
+	ReactivatableUntil string `json:"reactivatable_until"`
+}
+
+// ReactivationRequest asks for a new reactivation link
+type ReactivationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// AccountDeletionResponse represents a pending account deletion
+type AccountDeletionResponse struct {
+	RequestedAt string `json:"requested_at"`
+	ScheduledAt string `json:"scheduled_at"`
+}
+
+// AccountSessionsExport lists the ways the account can currently be signed in to
+type AccountSessionsExport struct {
+	RefreshTokenExpiresAt *string                `json:"refresh_token_expires_at"`
+	APIKeys               []APIKeyResponse       `json:"api_keys"`
+	LinkedIdentities      []LinkedIdentityExport `json:"linked_identities"`
+}
+
+// LinkedIdentityExport represents an external login provider linked to the account
+type LinkedIdentityExport struct {
+	Provider  string  `json:"provider"`
+	Subject   string  `json:"subject"`
+	Email     *string `json:"email,omitempty"`
+	CreatedAt string  `json:"created_at"`
+}
@@ -1,49 +1,125 @@
 package dto
 
-// CreateAddressRequest represents create address request
+// CreateAddressRequest represents create address request.
+// Province, city, district and sub district names are taken from the region codes,
+// which can be omitted when a village suggestion_id from /api/regions/search is sent.
 type CreateAddressRequest struct {
-	Label         string `json:"label" validate:"required,max=100"`
-	RecipientName string `json:"recipient_name" validate:"required,max=255"`
-	Phone         string `json:"phone" validate:"required,max=20"`
-	Province      string `json:"province" validate:"required,max=100"`
-	City          string `json:"city" validate:"required,max=100"`
-	District      string `json:"district" validate:"required,max=100"`
-	SubDistrict   string `json:"sub_district" validate:"required,max=100"`
-	PostalCode    string `json:"postal_code" validate:"required,max=10"`
-	FullAddress   string `json:"full_address" validate:"required"`
-	IsPrimary     bool   `json:"is_primary"`
-}
-
-// UpdateAddressRequest represents update address request
+	Label         string   `json:"label" validate:"required,max=100"`
+	RecipientName string   `json:"recipient_name" validate:"required,max=255"`
+	Phone         string   `json:"phone" validate:"required,max=20"`
+	SuggestionID  string   `json:"suggestion_id" validate:"omitempty,max=13"`
+	ProvinceCode  string   `json:"province_code" validate:"required_without=SuggestionID,max=13"`
+	RegencyCode   string   `json:"regency_code" validate:"required_without=SuggestionID,max=13"`
+	DistrictCode  string   `json:"district_code" validate:"required_without=SuggestionID,max=13"`
+	VillageCode   string   `json:"village_code" validate:"required_without=SuggestionID,max=13"`
+	PostalCode    string   `json:"postal_code" validate:"omitempty,max=10"` // defaults to the village postal code
+	FullAddress   string   `json:"full_address" validate:"required"`
+	IsPrimary     bool     `json:"is_primary"`
+	Latitude      *float64 `json:"latitude" validate:"omitempty,latitude"` // geocoded when latitude and longitude are omitted
+	Longitude     *float64 `json:"longitude" validate:"omitempty,longitude"`
+}
+
+// UpdateAddressRequest represents update address request.
+// Region codes are optional, but must be sent together.
 type UpdateAddressRequest struct {
-	ID            string `json:"id"`
-	Label         string `json:"label" validate:"omitempty,max=100"`
-	RecipientName string `json:"recipient_name" validate:"omitempty,max=255"`
-	Phone         string `json:"phone" validate:"omitempty,max=20"`
-	Province      string `json:"province" validate:"omitempty,max=100"`
-	City          string `json:"city" validate:"omitempty,max=100"`
-	District      string `json:"district" validate:"omitempty,max=100"`
-	SubDistrict   string `json:"sub_district" validate:"omitempty,max=100"`
-	PostalCode    string `json:"postal_code" validate:"omitempty,max=10"`
-	FullAddress   string `json:"full_address" validate:"omitempty"`
-	IsPrimary     *bool  `json:"is_primary"`
+	ID            string   `json:"-"`
+	Label         string   `json:"label" validate:"omitempty,max=100"`
+	RecipientName string   `json:"recipient_name" validate:"omitempty,max=255"`
+	Phone         string   `json:"phone" validate:"omitempty,max=20"`
+	ProvinceCode  string   `json:"province_code" validate:"required_with=RegencyCode DistrictCode VillageCode,max=13"`
+	RegencyCode   string   `json:"regency_code" validate:"required_with=ProvinceCode DistrictCode VillageCode,max=13"`
+	DistrictCode  string   `json:"district_code" validate:"required_with=ProvinceCode RegencyCode VillageCode,max=13"`
+	VillageCode   string   `json:"village_code" validate:"required_with=ProvinceCode RegencyCode DistrictCode,max=13"`
+	PostalCode    string   `json:"postal_code" validate:"omitempty,max=10"`
+	FullAddress   string   `json:"full_address" validate:"omitempty"`
+	IsPrimary     *bool    `json:"is_primary"`
+	Latitude      *float64 `json:"latitude" validate:"omitempty,latitude"` // a changed location is geocoded again when omitted
+	Longitude     *float64 `json:"longitude" validate:"omitempty,longitude"`
+}
+
+// NearbyAddressRequest represents query parameters for addresses within a radius
+type NearbyAddressRequest struct {
+	Latitude  *float64 `form:"latitude" validate:"required,latitude"`
+	Longitude *float64 `form:"longitude" validate:"required,longitude"`
+	RadiusKm  float64  `form:"radius_km,default=10" validate:"gt=0,lte=500"`
+	Limit     int      `form:"limit,default=20" validate:"min=1,max=100"`
 }
 
 // AddressResponse represents address response
 type AddressResponse struct {
-	ID            string `json:"id"`
-	UserID        string `json:"user_id"`
-	Label         string `json:"label"`
-	RecipientName string `json:"recipient_name"`
-	Phone         string `json:"phone"`
-	Province      string `json:"province"`
-	City          string `json:"city"`
-	District      string `json:"district"`
-	SubDistrict   string `json:"sub_district"`
-	PostalCode    string `json:"postal_code"`
-	FullAddress   string `json:"full_address"`
-	IsPrimary     bool   `json:"is_primary"`
-	IsActive      bool   `json:"is_active"`
-	CreatedAt     string `json:"created_at"`
-	UpdatedAt     string `json:"updated_at"`
+	ID               string   `json:"id"`
+	UserID           string   `json:"user_id"`
+	Label            string   `json:"label"`
+	RecipientName    string   `json:"recipient_name"`
+	Phone            string   `json:"phone"`
+	Province         string   `json:"province"`
+	City             string   `json:"city"`
+	District         string   `json:"district"`
+	SubDistrict      string   `json:"sub_district"`
+	PostalCode       string   `json:"postal_code"`
+	FullAddress      string   `json:"full_address"`
+	ProvinceCode     *string  `json:"province_code"`
+	RegencyCode      *string  `json:"regency_code"`
+	DistrictCode     *string  `json:"district_code"`
+	VillageCode      *string  `json:"village_code"`
+	Latitude         *float64 `json:"latitude"`
+	Longitude        *float64 `json:"longitude"`
+	CoordinateSource *string  `json:"coordinate_source"`     // manual, geocoder or region_centroid
+	DistanceKm       *float64 `json:"distance_km,omitempty"` // only set by distance queries
+	Version          int      `json:"version"`
+	VersionID        *string  `json:"version_id"` // pin this to keep the address as it is now
+	IsPrimary        bool     `json:"is_primary"`
+	IsActive         bool     `json:"is_active"`
+	CreatedAt        string   `json:"created_at"`
+	UpdatedAt        string   `json:"updated_at"`
+}
+
+// AddressVersionResponse represents an immutable snapshot of an address
+type AddressVersionResponse struct {
+	ID            string   `json:"id"`
+	AddressID     string   `json:"address_id"`
+	Version       int      `json:"version"`
+	Label         string   `json:"label"`
+	RecipientName string   `json:"recipient_name"`
+	Phone         string   `json:"phone"`
+	Province      string   `json:"province"`
+	City          string   `json:"city"`
+	District      string   `json:"district"`
+	SubDistrict   string   `json:"sub_district"`
+	PostalCode    string   `json:"postal_code"`
+	FullAddress   string   `json:"full_address"`
+	ProvinceCode  *string  `json:"province_code"`
+	RegencyCode   *string  `json:"regency_code"`
+	DistrictCode  *string  `json:"district_code"`
+	VillageCode   *string  `json:"village_code"`
+	Latitude      *float64 `json:"latitude"`
+	Longitude     *float64 `json:"longitude"`
+	CreatedAt     string   `json:"created_at"`
+	CreatedBy     *string  `json:"created_by"`
+}
+
+// AddressImportRowError lists the problems of one imported row
+type AddressImportRowError struct {
+	Row    int               `json:"row"` // spreadsheet row number; the header is row 1
+	Errors map[string]string `json:"errors"`
+}
+
+// AddressImportResponse is the report of an address import. Nothing is saved
+// unless every row is valid and the import is not a dry run.
+type AddressImportResponse struct {
+	DryRun    bool                    `json:"dry_run"`
+	TotalRows int                     `json:"total_rows"`
+	ValidRows int                     `json:"valid_rows"`
+	Imported  int                     `json:"imported"`
+	Errors    []AddressImportRowError `json:"errors"`
+}
+
+// AddressImportQuery holds the query parameters of an address import
+type AddressImportQuery struct {
+	DryRun bool `form:"dry_run"`
+}
+
+// AddressExportRequest holds the query parameters of an address export
+type AddressExportRequest struct {
+	Format string `form:"format" validate:"omitempty,oneof=csv xlsx"`
 }
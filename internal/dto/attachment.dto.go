@@ -4,6 +4,93 @@ type AttachmentRequest struct {
 	File string `json:"file"`
 }
 
+// AttachmentListRequest filters the attachment list
+type AttachmentListRequest struct {
+	Kind string `form:"kind" validate:"omitempty,oneof=IMAGE DOCUMENT PRODUCT_IMAGE PROFILE_IMAGE"`
+}
+
 type AttachmentResponse struct {
-	URL string `json:"url"`
+	ID         string                      `json:"id"`
+	Kind       string                      `json:"kind"`
+	FileName   string                      `json:"file_name"`
+	URL        string                      `json:"url"`
+	MimeType   string                      `json:"mime_type"`
+	Size       int64                       `json:"size"`
+	Checksum   string                      `json:"checksum"`
+	Status     string                      `json:"status"`
+	Visibility string                      `json:"visibility"`
+	Width      *int                        `json:"width,omitempty"`
+	Height     *int                        `json:"height,omitempty"`
+	Variants   []AttachmentVariantResponse `json:"variants"`
+	CreatedAt  string                      `json:"created_at"`
+}
+
+// AttachmentVariantResponse is a resized copy of an image attachment
+type AttachmentVariantResponse struct {
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	MimeType string `json:"mime_type"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	Size     int64  `json:"size"`
+}
+
+// PresignAttachmentRequest declares a file the client will upload directly to storage
+type PresignAttachmentRequest struct {
+	Kind        string `json:"kind" validate:"required,oneof=IMAGE DOCUMENT PRODUCT_IMAGE PROFILE_IMAGE"`
+	FileName    string `json:"file_name" validate:"required,max=255"`
+	ContentType string `json:"content_type" validate:"required"`
+	Size        int64  `json:"size" validate:"required,min=1"`
+	// Visibility defaults to PRIVATE for documents and PUBLIC otherwise
+	Visibility string `json:"visibility" validate:"omitempty,oneof=PUBLIC PRIVATE"`
+}
+
+// PresignAttachmentResponse tells the client how to send the file. Fields, when set,
+// are sent as a multipart form with the file in FileField; otherwise the file is the
+// request body, sent with Headers.
+type PresignAttachmentResponse struct {
+	AttachmentID string            `json:"attachment_id"`
+	Method       string            `json:"method"`
+	URL          string            `json:"url"`
+	Headers      map[string]string `json:"headers,omitempty"`
+	Fields       map[string]string `json:"fields,omitempty"`
+	FileField    string            `json:"file_field,omitempty"`
+	ExpiresAt    string            `json:"expires_at"`
+}
+
+// StorageUsageResponse shows what a user stores against the quota of their role
+type StorageUsageResponse struct {
+	Role           string               `json:"role"`
+	Bytes          StorageLimitResponse `json:"bytes"`
+	Files          StorageLimitResponse `json:"files"`
+	UploadsPerHour StorageLimitResponse `json:"uploads_per_hour"`
+}
+
+// StorageLimitResponse is the usage of one limit; Limit and Remaining are null when it is unlimited
+type StorageLimitResponse struct {
+	Used      int64  `json:"used"`
+	Limit     *int64 `json:"limit"`
+	Remaining *int64 `json:"remaining"`
+}
+
+// AttachmentGCReport lists the unreferenced attachments a garbage collection run
+// deleted, or would delete on a dry run
+type AttachmentGCReport struct {
+	DryRun bool `json:"dry_run"`
+	// CreatedBefore is the end of the grace period; newer attachments are kept
+	CreatedBefore string              `json:"created_before"`
+	Attachments   []AttachmentGCEntry `json:"attachments"`
+	Count         int                 `json:"count"`
+	Bytes         int64               `json:"bytes"`
+	Failed        int                 `json:"failed"`
+}
+
+// AttachmentGCEntry is an attachment collected by the garbage collection
+type AttachmentGCEntry struct {
+	ID        string `json:"id"`
+	OwnerID   string `json:"owner_id"`
+	Kind      string `json:"kind"`
+	FileName  string `json:"file_name"`
+	Size      int64  `json:"size"`
+	CreatedAt string `json:"created_at"`
 }
@@ -0,0 +1,39 @@
+package dto
+
+// CreateInvitationRequest represents admin invitation request
+type CreateInvitationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Name  string `json:"name" validate:"omitempty,min=2"`
+	Role  string `json:"role" validate:"required,oneof=USER ADMIN SUPERADMIN"`
+}
+
+// AcceptInvitationRequest represents the invitee setting their password
+type AcceptInvitationRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Name     string `json:"name" validate:"omitempty,min=2"`
+	Password string `json:"password" validate:"required,min=6"`
+}
+
+// InvitationResponse represents invitation response
+type InvitationResponse struct {
+	ID         string  `json:"id"`
+	UserID     *string `json:"user_id,omitempty"`
+	Email      string  `json:"email"`
+	Role       string  `json:"role"`
+	Status     string  `json:"status"`
+	InvitedBy  *string `json:"invited_by,omitempty"`
+	SentCount  int     `json:"sent_count"`
+	LastSentAt string  `json:"last_sent_at"`
+	ExpiresAt  string  `json:"expires_at"`
+	AcceptedAt *string `json:"accepted_at,omitempty"`
+	RevokedAt  *string `json:"revoked_at,omitempty"`
+	CreatedAt  string  `json:"created_at"`
+}
+
+// InvitationPreviewResponse is shown to the invitee before they accept
+type InvitationPreviewResponse struct {
+	Email     string `json:"email"`
+	Name      string `json:"name"`
+	Role      string `json:"role"`
+	ExpiresAt string `json:"expires_at"`
+}
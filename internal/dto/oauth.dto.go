@@ -0,0 +1,15 @@
+package dto
+
+// OAuthProviderResponse represents a configured social login provider
+type OAuthProviderResponse struct {
+	Name     string `json:"name"`
+	LoginURL string `json:"login_url"`
+}
+
+// OAuthCallbackRequest represents the query parameters of the provider callback
+type OAuthCallbackRequest struct {
+	Code             string `form:"code"`
+	State            string `form:"state"`
+	Error            string `form:"error"`
+	ErrorDescription string `form:"error_description"`
+}
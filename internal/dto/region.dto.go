@@ -0,0 +1,38 @@
+package dto
+
+// RegionResponse represents an administrative region
+type RegionResponse struct {
+	Code       string  `json:"code"`
+	ParentCode *string `json:"parent_code,omitempty"`
+	Level      string  `json:"level"`
+	Name       string  `json:"name"`
+	PostalCode *string `json:"postal_code,omitempty"`
+	FullName   string  `json:"full_name"`
+}
+
+// RegionSearchRequest represents region autocomplete query parameters
+type RegionSearchRequest struct {
+	Query string `form:"q" validate:"required,min=2,max=100"`
+	Level string `form:"level" validate:"omitempty,oneof=province regency district village"`
+	Limit int    `form:"limit,default=10" validate:"min=1,max=50"`
+}
+
+// RegionSuggestionResponse is an autocomplete suggestion. Its ID is the region
+// code, which can be sent as suggestion_id when creating an address.
+type RegionSuggestionResponse struct {
+	ID           string  `json:"id"`
+	Level        string  `json:"level"`
+	Name         string  `json:"name"`
+	FullName     string  `json:"full_name"`
+	PostalCode   *string `json:"postal_code,omitempty"`
+	ProvinceCode string  `json:"province_code"`
+	RegencyCode  string  `json:"regency_code,omitempty"`
+	DistrictCode string  `json:"district_code,omitempty"`
+	VillageCode  string  `json:"village_code,omitempty"`
+}
+
+// PostalCodeCandidateResponse is a district using a postal code, with its villages that use it
+type PostalCodeCandidateResponse struct {
+	RegionSuggestionResponse
+	Villages []RegionSuggestionResponse `json:"villages"`
+}
@@ -34,6 +34,16 @@ type UserResponse struct {
 	ID    string `json:"id"`
 	Email string `json:"email"`
 	Name  string `json:"name"`
+	// Avatar is included in profile responses
+	Avatar *AvatarResponse `json:"avatar,omitempty"`
+}
+
+// AvatarResponse is the user's profile image, or a generated SVG with their initials
+// given as data URIs when they have none
+type AvatarResponse struct {
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	IsDefault    bool   `json:"is_default"`
 }
 
 // AuthResponse represents authentication response
@@ -43,3 +53,28 @@ type AuthResponse struct {
 	ExpiresIn    int64        `json:"expires_in"` // seconds
 	User         UserResponse `json:"user"`
 }
+
+// MagicLinkRequest represents passwordless sign-in link request
+type MagicLinkRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ImpersonateRequest represents admin impersonation request
+type ImpersonateRequest struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+// ImpersonationResponse represents a short-lived access token issued to an admin acting as another user
+type ImpersonationResponse struct {
+	AccessToken  string       `json:"access_token"`
+	ExpiresIn    int64        `json:"expires_in"` // seconds
+	User         UserResponse `json:"user"`
+	Impersonator UserResponse `json:"impersonator"`
+}
+
+// RequestMetadata carries client details recorded in the audit trail
+type RequestMetadata struct {
+	IP        string
+	UserAgent string
+	RequestID string
+}
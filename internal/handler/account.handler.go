@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/amirullazmi0/kratify-backend/internal/dto"
+	"github.com/amirullazmi0/kratify-backend/internal/usecase"
+	"github.com/amirullazmi0/kratify-backend/pkg/response"
+	"github.com/amirullazmi0/kratify-backend/pkg/validator"
+
+	"github.com/gin-contrib/requestid"
+	"github.com/gin-gonic/gin"
+)
+
+type AccountHandler struct {
+	usecase usecase.AccountUsecase
+}
+
+func NewAccountHandler(usecase usecase.AccountUsecase) *AccountHandler {
+	return &AccountHandler{usecase: usecase}
+}
+
+// ExportData godoc
+// @Summary Export my data
+// @Description Download a ZIP archive with profile, addresses, attachments metadata and sessions as JSON
+// @Tags users
+// @Produce application/zip
+// @Security BearerAuth
+// @Success 200 {file} file
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/users/me/export [get]
+func (h *AccountHandler) ExportData(c *gin.Context) {
+	archive, err := h.usecase.ExportData(c.GetString("user_id"))
+	if err != nil {
+		response.Error(c, http.StatusNotFound, err.Error(), nil)
+		return
+	}
+
+	fileName := fmt.Sprintf("account-export-%s.zip", time.Now().Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "application/zip", archive)
+}
+
+// RequestDeletion godoc
+// @Summary Request account deletion
+// @Description Schedule the account for permanent erasure after a grace period; can be cancelled until then
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.AccountDeletionRequest true "Account Deletion Request"
+// @Success 202 {object} response.Response{data=dto.AccountDeletionResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Router /api/users/me/deletion [post]
+func (h *AccountHandler) RequestDeletion(c *gin.Context) {
+	var req dto.AccountDeletionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	// Validate request
+	if err := validator.Validate(&req); err != nil {
+		response.ValidationError(c, validator.FormatValidationErrors(err))
+		return
+	}
+
+	result, err := h.usecase.RequestDeletion(c.GetString("user_id"), &req, requestMetadata(c))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	response.Success(c, http.StatusAccepted, "Account deletion scheduled", result)
+}
+
+// CancelDeletion godoc
+// @Summary Cancel account deletion
+// @Description Cancel a pending account deletion request
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Router /api/users/me/deletion [delete]
+func (h *AccountHandler) CancelDeletion(c *gin.Context) {
+	if err := h.usecase.CancelDeletion(c.GetString("user_id"), requestMetadata(c)); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Account deletion cancelled", nil)
+}
+
+// Deactivate godoc
+// @Summary Deactivate my account
+// @Description Sign out everywhere and block login; a reactivation link is emailed and stays valid for a limited window, after which the account is erased
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.DeactivateAccountRequest true "Deactivate Account Request"
+// @Success 200 {object} response.Response{data=dto.DeactivateAccountResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Router /api/users/me/deactivate [post]
+func (h *AccountHandler) Deactivate(c *gin.Context) {
+	var req dto.DeactivateAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	// Validate request
+	if err := validator.Validate(&req); err != nil {
+		response.ValidationError(c, validator.FormatValidationErrors(err))
+		return
+	}
+
+	result, err := h.usecase.Deactivate(c.GetString("user_id"), &req, requestMetadata(c))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	// Clear authentication cookies
+	response.ClearAuthCookies(c)
+
+	response.Success(c, http.StatusOK, "Account deactivated. Check your email for a reactivation link.", result)
+}
+
+// RequestReactivation godoc
+// @Summary Resend reactivation link
+// @Description Email a new reactivation link for a deactivated account while the reactivation window is open
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dto.ReactivationRequest true "Reactivation Request"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /api/auth/reactivation [post]
+func (h *AccountHandler) RequestReactivation(c *gin.Context) {
+	var req dto.ReactivationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	// Validate request
+	if err := validator.Validate(&req); err != nil {
+		response.ValidationError(c, validator.FormatValidationErrors(err))
+		return
+	}
+
+	if err := h.usecase.RequestReactivation(&req); err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to send reactivation link", err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, "If the account can be reactivated, a reactivation link has been sent.", nil)
+}
+
+// Reactivate godoc
+// @Summary Reactivate account
+// @Description Reactivate a deactivated account with the emailed link
+// @Tags auth
+// @Produce json
+// @Param token query string true "Reactivation Token"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /api/auth/reactivate [get]
+func (h *AccountHandler) Reactivate(c *gin.Context) {
+	if err := h.usecase.Reactivate(c.Query("token"), requestMetadata(c)); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Account reactivated successfully. You can now log in.", nil)
+}
+
+// requestMetadata collects the client details recorded in the audit trail
+func requestMetadata(c *gin.Context) dto.RequestMetadata {
+	return dto.RequestMetadata{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		RequestID: requestid.Get(c),
+	}
+}
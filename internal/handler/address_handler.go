@@ -1,11 +1,14 @@
 package handler
 
 import (
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/amirullazmi0/kratify-backend/internal/dto"
 	"github.com/amirullazmi0/kratify-backend/internal/usecase"
 	"github.com/amirullazmi0/kratify-backend/pkg/response"
+	"github.com/amirullazmi0/kratify-backend/pkg/spreadsheet"
 	"github.com/amirullazmi0/kratify-backend/pkg/validator"
 	"github.com/gin-gonic/gin"
 )
@@ -46,15 +49,16 @@ func (h *AddressHandler) GetAddressByAuth(c *gin.Context) {
 // @Tags addresses
 // @Produce json
 // @Security BearerAuth
-// @Param address_id path string true "Address ID"
+// @Param id path string true "Address ID"
 // @Success 200 {object} response.Response{data=dto.AddressResponse}
 // @Failure 401 {object} response.Response
 // @Failure 404 {object} response.Response
-// @Router /api/addresses/{address_id} [get]
+// @Router /api/addresses/{id} [get]
 func (h *AddressHandler) GetAddressByID(c *gin.Context) {
-	addressID := c.Param("address_id")
+	userID := c.GetString("user_id")
+	addressID := c.Param("id")
 
-	result, err := h.AddressUsecase.GetAddressById(addressID)
+	result, err := h.AddressUsecase.GetAddressById(userID, addressID)
 	if err != nil {
 		response.Error(c, http.StatusNotFound, err.Error(), nil)
 		return
@@ -106,11 +110,12 @@ func (h *AddressHandler) CreateAddress(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Param id path string true "Address ID"
 // @Param request body dto.UpdateAddressRequest true "Update Address Request"
 // @Success 200 {object} response.Response{data=dto.AddressResponse}
 // @Failure 400 {object} response.Response
 // @Failure 401 {object} response.Response
-// @Router /api/addresses [put]
+// @Router /api/addresses/{id} [put]
 func (h *AddressHandler) UpdateAddress(c *gin.Context) {
 	userID := c.GetString("user_id")
 
@@ -126,6 +131,8 @@ func (h *AddressHandler) UpdateAddress(c *gin.Context) {
 		return
 	}
 
+	req.ID = c.Param("id")
+
 	result, err := h.AddressUsecase.UpdateAddress(userID, &req)
 	if err != nil {
 		response.Error(c, http.StatusBadRequest, err.Error(), nil)
@@ -141,14 +148,14 @@ func (h *AddressHandler) UpdateAddress(c *gin.Context) {
 // @Tags addresses
 // @Produce json
 // @Security BearerAuth
-// @Param address_id path string true "Address ID"
+// @Param id path string true "Address ID"
 // @Success 200 {object} response.Response
 // @Failure 400 {object} response.Response
 // @Failure 401 {object} response.Response
-// @Router /api/addresses/{address_id} [delete]
+// @Router /api/addresses/{id} [delete]
 func (h *AddressHandler) DeleteAddress(c *gin.Context) {
 	userID := c.GetString("user_id")
-	addressID := c.Param("address_id")
+	addressID := c.Param("id")
 
 	if err := h.AddressUsecase.DeleteAddress(userID, addressID); err != nil {
 		response.Error(c, http.StatusBadRequest, err.Error(), nil)
@@ -157,3 +164,216 @@ func (h *AddressHandler) DeleteAddress(c *gin.Context) {
 
 	response.Success(c, http.StatusOK, "Address deleted successfully", nil)
 }
+
+// SetPrimaryAddress godoc
+// @Summary Set primary address
+// @Description Make an address the primary one; the previous primary address is unset
+// @Tags addresses
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Address ID"
+// @Success 200 {object} response.Response{data=dto.AddressResponse}
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/addresses/{id}/primary [put]
+func (h *AddressHandler) SetPrimaryAddress(c *gin.Context) {
+	userID := c.GetString("user_id")
+	addressID := c.Param("id")
+
+	result, err := h.AddressUsecase.SetPrimaryAddress(userID, addressID)
+	if err != nil {
+		response.Error(c, http.StatusNotFound, err.Error(), nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Primary address updated successfully", result)
+}
+
+// GetNearbyAddresses godoc
+// @Summary Nearby addresses
+// @Description List the authenticated user's addresses within a radius of a point, nearest first
+// @Tags addresses
+// @Produce json
+// @Security BearerAuth
+// @Param latitude query number true "Latitude"
+// @Param longitude query number true "Longitude"
+// @Param radius_km query number false "Radius in kilometres (max 500)" default(10)
+// @Param limit query int false "Maximum addresses (1-100)" default(20)
+// @Success 200 {object} response.Response{data=[]dto.AddressResponse}
+// @Failure 401 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /api/addresses/nearby [get]
+func (h *AddressHandler) GetNearbyAddresses(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req dto.NearbyAddressRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid query parameters", err.Error())
+		return
+	}
+
+	// Validate request
+	if err := validator.Validate(&req); err != nil {
+		response.ValidationError(c, validator.FormatValidationErrors(err))
+		return
+	}
+
+	result, err := h.AddressUsecase.GetNearbyAddresses(userID, &req)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to get nearby addresses", err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Nearby addresses retrieved successfully", result)
+}
+
+// GetAddressHistory godoc
+// @Summary Address history
+// @Description List every version of an address, newest first. Also available for deleted addresses.
+// @Tags addresses
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Address ID"
+// @Success 200 {object} response.Response{data=[]dto.AddressVersionResponse}
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/addresses/{id}/versions [get]
+func (h *AddressHandler) GetAddressHistory(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	result, err := h.AddressUsecase.GetAddressHistory(userID, c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusNotFound, err.Error(), nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Address history retrieved successfully", result)
+}
+
+// GetAddressVersion godoc
+// @Summary Get address version
+// @Description Get one immutable version of an address, e.g. the one pinned by a shipment
+// @Tags addresses
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Address ID"
+// @Param version_id path string true "Address version ID"
+// @Success 200 {object} response.Response{data=dto.AddressVersionResponse}
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/addresses/{id}/versions/{version_id} [get]
+func (h *AddressHandler) GetAddressVersion(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	result, err := h.AddressUsecase.GetAddressVersion(userID, c.Param("id"), c.Param("version_id"))
+	if err != nil {
+		response.Error(c, http.StatusNotFound, err.Error(), nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Address version retrieved successfully", result)
+}
+
+// maxAddressImportSize limits the size of an uploaded address import file
+const maxAddressImportSize = 5 << 20
+
+// ImportAddresses godoc
+// @Summary Import addresses
+// @Description Import addresses from a CSV or XLSX file (same columns as the export). Every row is validated like a created address; nothing is saved unless all rows are valid. With dry_run=true only the report is returned.
+// @Tags addresses
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "CSV or XLSX file"
+// @Param dry_run query bool false "Validate without saving"
+// @Success 200 {object} response.Response{data=dto.AddressImportResponse}
+// @Success 201 {object} response.Response{data=dto.AddressImportResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 413 {object} response.Response
+// @Failure 422 {object} response.Response{error=dto.AddressImportResponse}
+// @Router /api/addresses/import [post]
+func (h *AddressHandler) ImportAddresses(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var query dto.AddressImportQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid query parameters", err.Error())
+		return
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Failed to get file from request", err.Error())
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxAddressImportSize {
+		response.Error(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("File is larger than %d MB", maxAddressImportSize>>20), nil)
+		return
+	}
+
+	format, err := spreadsheet.FormatFromFilename(header.Filename)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	result, err := h.AddressUsecase.ImportAddresses(userID, format, file, query.DryRun)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Failed to import addresses", err.Error())
+		return
+	}
+
+	switch {
+	case len(result.Errors) > 0:
+		response.Error(c, http.StatusUnprocessableEntity, "Some rows are invalid, nothing was imported", result)
+	case result.DryRun:
+		response.Success(c, http.StatusOK, "All rows are valid", result)
+	default:
+		response.Success(c, http.StatusCreated, "Addresses imported successfully", result)
+	}
+}
+
+// ExportAddresses godoc
+// @Summary Export addresses
+// @Description Download the authenticated user's addresses as CSV or XLSX, in the format accepted by the import
+// @Tags addresses
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Security BearerAuth
+// @Param format query string false "File format" Enums(csv, xlsx) default(csv)
+// @Success 200 {file} file
+// @Failure 401 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /api/addresses/export [get]
+func (h *AddressHandler) ExportAddresses(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req dto.AddressExportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid query parameters", err.Error())
+		return
+	}
+
+	// Validate request
+	if err := validator.Validate(&req); err != nil {
+		response.ValidationError(c, validator.FormatValidationErrors(err))
+		return
+	}
+	if req.Format == "" {
+		req.Format = spreadsheet.FormatCSV
+	}
+
+	data, err := h.AddressUsecase.ExportAddresses(userID, req.Format)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to export addresses", err.Error())
+		return
+	}
+
+	fileName := fmt.Sprintf("addresses-%s.%s", time.Now().Format("20060102-150405"), req.Format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, spreadsheet.ContentType(req.Format), data)
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/amirullazmi0/kratify-backend/internal/dto"
+	"github.com/amirullazmi0/kratify-backend/internal/usecase"
+	"github.com/amirullazmi0/kratify-backend/pkg/response"
+	"github.com/amirullazmi0/kratify-backend/pkg/validator"
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyHandler struct {
+	usecase usecase.APIKeyUsecase
+}
+
+func NewAPIKeyHandler(usecase usecase.APIKeyUsecase) *APIKeyHandler {
+	return &APIKeyHandler{usecase: usecase}
+}
+
+// CreateAPIKey godoc
+// @Summary Create API key
+// @Description Create a personal API key. The key is only returned once.
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.CreateAPIKeyRequest true "Create API Key Request"
+// @Success 201 {object} response.Response{data=dto.CreatedAPIKeyResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Router /api/users/api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req dto.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	// Validate request
+	if err := validator.Validate(&req); err != nil {
+		response.ValidationError(c, validator.FormatValidationErrors(err))
+		return
+	}
+
+	result, err := h.usecase.CreateAPIKey(userID, &req)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "API key created successfully. Store it now, it will not be shown again.", result)
+}
+
+// GetAPIKeys godoc
+// @Summary List API keys
+// @Description List personal API keys of the authenticated user
+// @Tags api-keys
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=[]dto.APIKeyResponse}
+// @Failure 401 {object} response.Response
+// @Router /api/users/api-keys [get]
+func (h *APIKeyHandler) GetAPIKeys(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	result, err := h.usecase.GetAPIKeys(userID)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to get API keys", err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, "API keys retrieved successfully", result)
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke API key
+// @Description Revoke a personal API key by ID
+// @Tags api-keys
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "API Key ID"
+// @Success 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/users/api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	userID := c.GetString("user_id")
+	id := c.Param("id")
+
+	if err := h.usecase.RevokeAPIKey(userID, id); err != nil {
+		response.Error(c, http.StatusNotFound, err.Error(), nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "API key revoked successfully", nil)
+}
@@ -1,10 +1,19 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
 	"net/http"
 
+	"github.com/amirullazmi0/kratify-backend/internal/dto"
+	"github.com/amirullazmi0/kratify-backend/internal/model"
+	"github.com/amirullazmi0/kratify-backend/internal/repository"
 	"github.com/amirullazmi0/kratify-backend/internal/usecase"
 	"github.com/amirullazmi0/kratify-backend/pkg/response"
+	"github.com/amirullazmi0/kratify-backend/pkg/upload"
+	"github.com/amirullazmi0/kratify-backend/pkg/validator"
 	"github.com/gin-gonic/gin"
 )
 
@@ -23,21 +32,27 @@ func NewAttachmentHandler(usecase usecase.AttachmentUsecase) *AttachmentHandler
 // @Accept multipart/form-data
 // @Produce json
 // @Param file formData file true "Image file"
+// @Param visibility formData string false "PUBLIC or PRIVATE; documents default to PRIVATE, images to PUBLIC"
 // @Success 200 {object} response.Response{data=dto.AttachmentResponse}
 // @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 413 {object} response.Response
+// @Failure 415 {object} response.Response
+// @Failure 429 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Router /api/attachments/image [post]
 func (h *AttachmentHandler) UploadImage(c *gin.Context) {
-	file, header, err := c.Request.FormFile("file")
-	if err != nil {
-		response.Error(c, http.StatusBadRequest, "Failed to get file from request", err.Error())
+	file, header, ok := h.formFile(c, model.AttachmentKindImage)
+	if !ok {
 		return
 	}
 	defer file.Close()
 
-	resp, err := h.usecase.UploadImage(file, header.Filename)
+	userID := c.GetString("user_id")
+
+	resp, err := h.usecase.UploadImage(file, header.Filename, userID, c.PostForm("visibility"))
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "Failed to upload image", err.Error())
+		uploadError(c, "Failed to upload image", err)
 		return
 	}
 
@@ -51,21 +66,27 @@ func (h *AttachmentHandler) UploadImage(c *gin.Context) {
 // @Accept multipart/form-data
 // @Produce json
 // @Param file formData file true "Document file"
+// @Param visibility formData string false "PUBLIC or PRIVATE; documents default to PRIVATE, images to PUBLIC"
 // @Success 200 {object} response.Response{data=dto.AttachmentResponse}
 // @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 413 {object} response.Response
+// @Failure 415 {object} response.Response
+// @Failure 429 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Router /api/attachments/document [post]
 func (h *AttachmentHandler) UploadDocument(c *gin.Context) {
-	file, header, err := c.Request.FormFile("file")
-	if err != nil {
-		response.Error(c, http.StatusBadRequest, "Failed to get file from request", err.Error())
+	file, header, ok := h.formFile(c, model.AttachmentKindDocument)
+	if !ok {
 		return
 	}
 	defer file.Close()
 
-	resp, err := h.usecase.UploadDocument(file, header.Filename)
+	userID := c.GetString("user_id")
+
+	resp, err := h.usecase.UploadDocument(file, header.Filename, userID, c.PostForm("visibility"))
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "Failed to upload document", err.Error())
+		uploadError(c, "Failed to upload document", err)
 		return
 	}
 
@@ -79,21 +100,27 @@ func (h *AttachmentHandler) UploadDocument(c *gin.Context) {
 // @Accept multipart/form-data
 // @Produce json
 // @Param file formData file true "Product Image file"
+// @Param visibility formData string false "PUBLIC or PRIVATE; documents default to PRIVATE, images to PUBLIC"
 // @Success 200 {object} response.Response{data=dto.AttachmentResponse}
 // @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 413 {object} response.Response
+// @Failure 415 {object} response.Response
+// @Failure 429 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Router /api/attachments/product-image [post]
 func (h *AttachmentHandler) UploadProductImage(c *gin.Context) {
-	file, header, err := c.Request.FormFile("file")
-	if err != nil {
-		response.Error(c, http.StatusBadRequest, "Failed to get file from request", err.Error())
+	file, header, ok := h.formFile(c, model.AttachmentKindProductImage)
+	if !ok {
 		return
 	}
 	defer file.Close()
 
-	resp, err := h.usecase.UploadProductImage(file, header.Filename)
+	userID := c.GetString("user_id")
+
+	resp, err := h.usecase.UploadProductImage(file, header.Filename, userID, c.PostForm("visibility"))
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "Failed to upload product image", err.Error())
+		uploadError(c, "Failed to upload product image", err)
 		return
 	}
 
@@ -107,25 +134,318 @@ func (h *AttachmentHandler) UploadProductImage(c *gin.Context) {
 // @Accept multipart/form-data
 // @Produce json
 // @Param file formData file true "Profile Image file"
+// @Param visibility formData string false "PUBLIC or PRIVATE; documents default to PRIVATE, images to PUBLIC"
 // @Success 200 {object} response.Response{data=dto.AttachmentResponse}
 // @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 413 {object} response.Response
+// @Failure 415 {object} response.Response
+// @Failure 429 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Router /api/attachments/profile-image [post]
 func (h *AttachmentHandler) UploadProfileImage(c *gin.Context) {
-	file, header, err := c.Request.FormFile("file")
-	if err != nil {
-		response.Error(c, http.StatusBadRequest, "Failed to get file from request", err.Error())
+	file, header, ok := h.formFile(c, model.AttachmentKindProfileImage)
+	if !ok {
 		return
 	}
 	defer file.Close()
 
 	userID := c.GetString("user_id")
 
-	resp, err := h.usecase.UploadProfileImage(file, header.Filename, userID)
+	resp, err := h.usecase.UploadProfileImage(file, header.Filename, userID, c.PostForm("visibility"))
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "Failed to upload profile image", err.Error())
+		uploadError(c, "Failed to upload profile image", err)
 		return
 	}
 
 	response.Success(c, http.StatusOK, "Profile image uploaded successfully", resp)
 }
+
+// GetAttachments godoc
+// @Summary List attachments
+// @Description List the authenticated user's uploaded files, newest first
+// @Tags attachments
+// @Produce json
+// @Security BearerAuth
+// @Param kind query string false "Attachment kind" Enums(IMAGE, DOCUMENT, PRODUCT_IMAGE, PROFILE_IMAGE)
+// @Success 200 {object} response.Response{data=[]dto.AttachmentResponse}
+// @Failure 401 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /api/attachments [get]
+func (h *AttachmentHandler) GetAttachments(c *gin.Context) {
+	var req dto.AttachmentListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid query parameters", err.Error())
+		return
+	}
+
+	// Validate request
+	if err := validator.Validate(&req); err != nil {
+		response.ValidationError(c, validator.FormatValidationErrors(err))
+		return
+	}
+
+	resp, err := h.usecase.GetAttachments(c.GetString("user_id"), &req)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to get attachments", err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Attachments retrieved successfully", resp)
+}
+
+// GetAttachment godoc
+// @Summary Get attachment
+// @Description Get the metadata of one of the authenticated user's files
+// @Tags attachments
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Attachment ID"
+// @Success 200 {object} response.Response{data=dto.AttachmentResponse}
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/attachments/{id} [get]
+func (h *AttachmentHandler) GetAttachment(c *gin.Context) {
+	resp, err := h.usecase.GetAttachment(c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusNotFound, err.Error(), nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Attachment retrieved successfully", resp)
+}
+
+// DeleteAttachment godoc
+// @Summary Delete attachment
+// @Description Delete one of the authenticated user's files from storage
+// @Tags attachments
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Attachment ID"
+// @Success 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api/attachments/{id} [delete]
+func (h *AttachmentHandler) DeleteAttachment(c *gin.Context) {
+	if err := h.usecase.DeleteAttachment(c.GetString("user_id"), c.Param("id")); err != nil {
+		if errors.Is(err, usecase.ErrAttachmentNotFound) {
+			response.Error(c, http.StatusNotFound, err.Error(), nil)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to delete attachment", err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Attachment deleted successfully", nil)
+}
+
+// PresignUpload godoc
+// @Summary Request a direct upload
+// @Description Check a file the client is about to upload and return a short-lived target to send it to directly: a presigned S3 PUT, ImageKit client upload parameters or a signed local URL. Call the complete endpoint once the file is sent.
+// @Tags attachments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.PresignAttachmentRequest true "File to upload"
+// @Success 201 {object} response.Response{data=dto.PresignAttachmentResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 413 {object} response.Response
+// @Failure 415 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Failure 501 {object} response.Response
+// @Router /api/attachments/presign [post]
+func (h *AttachmentHandler) PresignUpload(c *gin.Context) {
+	var req dto.PresignAttachmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	// Validate request
+	if err := validator.Validate(&req); err != nil {
+		response.ValidationError(c, validator.FormatValidationErrors(err))
+		return
+	}
+
+	resp, err := h.usecase.PresignUpload(c.GetString("user_id"), &req)
+	if err != nil {
+		if errors.Is(err, repository.ErrDirectUploadUnsupported) {
+			response.Error(c, http.StatusNotImplemented, err.Error(), nil)
+			return
+		}
+		uploadError(c, "Failed to prepare upload", err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Upload prepared successfully", resp)
+}
+
+// CompleteUpload godoc
+// @Summary Complete a direct upload
+// @Description Verify a file sent to a presigned upload target against the upload policy and register it. A rejected file is deleted.
+// @Tags attachments
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Attachment ID"
+// @Success 200 {object} response.Response{data=dto.AttachmentResponse}
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 410 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 413 {object} response.Response
+// @Failure 415 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api/attachments/{id}/complete [post]
+func (h *AttachmentHandler) CompleteUpload(c *gin.Context) {
+	resp, err := h.usecase.CompleteUpload(c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrAttachmentNotFound):
+			response.Error(c, http.StatusNotFound, err.Error(), nil)
+		case errors.Is(err, usecase.ErrUploadNotPending), errors.Is(err, usecase.ErrUploadMissing), errors.Is(err, usecase.ErrUploadMismatch):
+			response.Error(c, http.StatusConflict, err.Error(), nil)
+		case errors.Is(err, usecase.ErrUploadExpired):
+			response.Error(c, http.StatusGone, err.Error(), nil)
+		default:
+			uploadError(c, "Failed to complete upload", err)
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Upload completed successfully", resp)
+}
+
+// DownloadAttachment godoc
+// @Summary Download attachment
+// @Description Redirect the owner to a short-lived signed URL of the file, or stream it when the storage provider cannot sign URLs. This is the only way to reach private files.
+// @Tags attachments
+// @Produce octet-stream
+// @Security BearerAuth
+// @Param id path string true "Attachment ID"
+// @Param variant query string false "Variant name, e.g. thumbnail"
+// @Success 200 {file} file
+// @Success 302
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api/attachments/{id}/download [get]
+func (h *AttachmentHandler) DownloadAttachment(c *gin.Context) {
+	download, err := h.usecase.DownloadAttachment(c.GetString("user_id"), c.Param("id"), c.Query("variant"))
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrAttachmentNotFound), errors.Is(err, usecase.ErrVariantNotFound):
+			response.Error(c, http.StatusNotFound, err.Error(), nil)
+		case errors.Is(err, usecase.ErrAttachmentNotReady):
+			response.Error(c, http.StatusConflict, err.Error(), nil)
+		default:
+			response.Error(c, http.StatusInternalServerError, "Failed to download attachment", err.Error())
+		}
+		return
+	}
+
+	// Signed URLs must not outlive their expiry in a cache
+	c.Header("Cache-Control", "private, no-store")
+	if download.RedirectURL != "" {
+		c.Redirect(http.StatusFound, download.RedirectURL)
+		return
+	}
+	defer download.Content.Close()
+
+	c.DataFromReader(http.StatusOK, download.Size, download.ContentType, download.Content, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": download.FileName}),
+	})
+}
+
+// GetStorageUsage godoc
+// @Summary Get storage usage
+// @Description Show the size and number of the authenticated user's files and their uploads in the last hour against the quota of their role
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=dto.StorageUsageResponse}
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api/users/me/storage [get]
+func (h *AttachmentHandler) GetStorageUsage(c *gin.Context) {
+	resp, err := h.usecase.GetStorageUsage(c.GetString("user_id"))
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to get storage usage", err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Storage usage retrieved successfully", resp)
+}
+
+// GetGarbageReport godoc
+// @Summary Report unreferenced attachments
+// @Description Dry run of the garbage collection: list the profile images no user references that the next run would delete
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=dto.AttachmentGCReport}
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api/admin/attachments/garbage [get]
+func (h *AttachmentHandler) GetGarbageReport(c *gin.Context) {
+	report, err := h.usecase.CollectGarbage(c.Request.Context(), true)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to collect unreferenced attachments", err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Unreferenced attachments retrieved successfully", report)
+}
+
+// multipartOverhead allows for the multipart envelope around an uploaded file
+const multipartOverhead = 1 << 20
+
+// formFile reads the "file" form field. The request body is capped at the size
+// allowed by the kind's policy, so oversized uploads are rejected while reading.
+func (h *AttachmentHandler) formFile(c *gin.Context, kind string) (multipart.File, *multipart.FileHeader, bool) {
+	maxSize := h.usecase.Policy(kind).MaxSize
+	if maxSize > 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+multipartOverhead)
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			response.Error(c, http.StatusRequestEntityTooLarge, "File too large", fmt.Sprintf("the maximum is %s", upload.FormatSize(maxSize)))
+			return nil, nil, false
+		}
+		response.Error(c, http.StatusBadRequest, "Failed to get file from request", err.Error())
+		return nil, nil, false
+	}
+
+	return file, header, true
+}
+
+// uploadError responds with 413 or 415 for files rejected by an upload policy, and
+// with 403, or 429 for the hourly upload limit, when the quota would be exceeded
+func uploadError(c *gin.Context, message string, err error) {
+	var quotaErr *repository.QuotaExceededError
+	switch {
+	case errors.As(err, &quotaErr):
+		status := http.StatusForbidden
+		if quotaErr.Limit == repository.QuotaLimitUploadsPerHour {
+			status = http.StatusTooManyRequests
+		}
+		response.Error(c, status, message+": "+repository.ErrQuotaExceeded.Error(), quotaErr)
+	case errors.Is(err, usecase.ErrInvalidVisibility):
+		response.Error(c, http.StatusBadRequest, message, err.Error())
+	case errors.Is(err, upload.ErrFileTooLarge):
+		response.Error(c, http.StatusRequestEntityTooLarge, message, err.Error())
+	case errors.Is(err, upload.ErrUnsupportedType):
+		response.Error(c, http.StatusUnsupportedMediaType, message, err.Error())
+	default:
+		response.Error(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
@@ -0,0 +1,236 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/amirullazmi0/kratify-backend/internal/usecase"
+	"github.com/amirullazmi0/kratify-backend/pkg/response"
+	"github.com/amirullazmi0/kratify-backend/pkg/storage"
+	"github.com/amirullazmi0/kratify-backend/pkg/tus"
+	"github.com/gin-gonic/gin"
+)
+
+// AttachmentIDHeader carries the ID of the attachment registered for a completed resumable upload
+const AttachmentIDHeader = "X-Attachment-ID"
+
+// tusRoute is where resumable uploads are created; each upload lives under it
+const tusRoute = "/api/attachments/tus"
+
+// TusOptions godoc
+// @Summary Resumable upload capabilities
+// @Description Report the tus protocol version, extensions and maximum upload size
+// @Tags attachments
+// @Success 204
+// @Router /api/attachments/tus [options]
+func (h *AttachmentHandler) TusOptions(c *gin.Context) {
+	c.Header(tus.HeaderResumable, tus.Version)
+	c.Header(tus.HeaderVersion, tus.Version)
+	c.Header(tus.HeaderExtension, tus.Extensions)
+	c.Header(tus.HeaderMaxSize, strconv.FormatInt(h.usecase.MaxUploadSize(), 10))
+	c.Status(http.StatusNoContent)
+}
+
+// CreateResumableUpload godoc
+// @Summary Create a resumable upload
+// @Description Start a tus 1.0 upload. Upload-Metadata holds the base64 encoded "filename", optionally "filetype" and the attachment "kind" (DOCUMENT by default). The upload URL is returned in the Location header.
+// @Tags attachments
+// @Security BearerAuth
+// @Param Tus-Resumable header string true "Protocol version (1.0.0)"
+// @Param Upload-Length header int true "File size in bytes"
+// @Param Upload-Metadata header string true "Comma separated key and base64 value pairs"
+// @Success 201
+// @Failure 400 {object} response.Response
+// @Failure 412 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 413 {object} response.Response
+// @Failure 415 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Router /api/attachments/tus [post]
+func (h *AttachmentHandler) CreateResumableUpload(c *gin.Context) {
+	if !checkTusVersion(c) {
+		return
+	}
+
+	if c.GetHeader("Upload-Defer-Length") != "" {
+		response.Error(c, http.StatusBadRequest, "Upload-Defer-Length is not supported", nil)
+		return
+	}
+	length, err := strconv.ParseInt(c.GetHeader(tus.HeaderLength), 10, 64)
+	if err != nil || length < 1 {
+		response.Error(c, http.StatusBadRequest, "Invalid Upload-Length header", nil)
+		return
+	}
+	metadata, err := tus.ParseMetadata(c.GetHeader(tus.HeaderMetadata))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid Upload-Metadata header", err.Error())
+		return
+	}
+
+	upload, err := h.usecase.CreateResumableUpload(c.GetString("user_id"), length, metadata)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidUploadMetadata) {
+			response.Error(c, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+		uploadError(c, "Failed to create upload", err)
+		return
+	}
+
+	c.Header("Location", tusRoute+"/"+upload.ID)
+	c.Header(tus.HeaderExpires, upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	c.Status(http.StatusCreated)
+}
+
+// GetResumableUploadOffset godoc
+// @Summary Get the offset of a resumable upload
+// @Description Return the number of bytes received in the Upload-Offset header, so an interrupted upload can be resumed
+// @Tags attachments
+// @Security BearerAuth
+// @Param id path string true "Upload ID"
+// @Param Tus-Resumable header string true "Protocol version (1.0.0)"
+// @Success 200
+// @Failure 404
+// @Failure 410
+// @Router /api/attachments/tus/{id} [head]
+func (h *AttachmentHandler) GetResumableUploadOffset(c *gin.Context) {
+	c.Header(tus.HeaderResumable, tus.Version)
+	// HEAD responses carry no body, so only the status is sent
+	upload, err := h.usecase.GetResumableUpload(c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		c.Status(tusErrorStatus(err))
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header(tus.HeaderOffset, strconv.FormatInt(upload.Offset, 10))
+	c.Header(tus.HeaderLength, strconv.FormatInt(upload.Length, 10))
+	c.Header(tus.HeaderMetadata, tus.EncodeMetadata(upload.Metadata))
+	c.Header(tus.HeaderExpires, upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	if upload.AttachmentID != "" {
+		c.Header(AttachmentIDHeader, upload.AttachmentID)
+	}
+	c.Status(http.StatusOK)
+}
+
+// WriteResumableUpload godoc
+// @Summary Upload a chunk
+// @Description Append the request body at Upload-Offset. When the last chunk arrives the file is checked and stored, and the attachment ID is returned in the X-Attachment-ID header.
+// @Tags attachments
+// @Accept application/offset+octet-stream
+// @Security BearerAuth
+// @Param id path string true "Upload ID"
+// @Param Tus-Resumable header string true "Protocol version (1.0.0)"
+// @Param Upload-Offset header int true "Offset the chunk starts at"
+// @Success 204
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 410 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 413 {object} response.Response
+// @Failure 415 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Failure 423 {object} response.Response
+// @Router /api/attachments/tus/{id} [patch]
+func (h *AttachmentHandler) WriteResumableUpload(c *gin.Context) {
+	if !checkTusVersion(c) {
+		return
+	}
+
+	if c.ContentType() != tus.ContentType {
+		response.Error(c, http.StatusUnsupportedMediaType, "Content-Type must be "+tus.ContentType, nil)
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader(tus.HeaderOffset), 10, 64)
+	if err != nil || offset < 0 {
+		response.Error(c, http.StatusBadRequest, "Invalid Upload-Offset header", nil)
+		return
+	}
+
+	// A chunk may take longer than the server's timeouts allow for other requests
+	controller := http.NewResponseController(c.Writer)
+	controller.SetReadDeadline(time.Now().Add(storage.UploadTimeout))
+	controller.SetWriteDeadline(time.Now().Add(storage.UploadTimeout))
+
+	upload, attachment, err := h.usecase.WriteResumableChunk(c.GetString("user_id"), c.Param("id"), offset, c.Request.Body)
+	if upload != nil {
+		c.Header(tus.HeaderOffset, strconv.FormatInt(upload.Offset, 10))
+		c.Header(tus.HeaderExpires, upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+	if err != nil {
+		status := tusErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			uploadError(c, "Failed to write upload", err)
+			return
+		}
+		response.Error(c, status, err.Error(), nil)
+		return
+	}
+
+	if attachment != nil {
+		c.Header(AttachmentIDHeader, attachment.ID)
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// TerminateResumableUpload godoc
+// @Summary Terminate a resumable upload
+// @Description Discard an upload and the bytes received so far
+// @Tags attachments
+// @Security BearerAuth
+// @Param id path string true "Upload ID"
+// @Param Tus-Resumable header string true "Protocol version (1.0.0)"
+// @Success 204
+// @Failure 404 {object} response.Response
+// @Failure 423 {object} response.Response
+// @Router /api/attachments/tus/{id} [delete]
+func (h *AttachmentHandler) TerminateResumableUpload(c *gin.Context) {
+	if !checkTusVersion(c) {
+		return
+	}
+
+	if err := h.usecase.TerminateResumableUpload(c.GetString("user_id"), c.Param("id")); err != nil {
+		status := tusErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			response.Error(c, status, "Failed to terminate upload", err.Error())
+			return
+		}
+		response.Error(c, status, err.Error(), nil)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// checkTusVersion sets the Tus-Resumable response header and rejects requests for
+// other protocol versions with 412
+func checkTusVersion(c *gin.Context) bool {
+	c.Header(tus.HeaderResumable, tus.Version)
+	if c.GetHeader(tus.HeaderResumable) != tus.Version {
+		c.Header(tus.HeaderVersion, tus.Version)
+		response.Error(c, http.StatusPreconditionFailed, "Unsupported tus version, use "+tus.Version, nil)
+		return false
+	}
+	return true
+}
+
+// tusErrorStatus maps resumable upload errors to their protocol status codes;
+// other errors are 500 unless they are upload policy violations
+func tusErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, usecase.ErrResumableUploadNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, usecase.ErrUploadExpired):
+		return http.StatusGone
+	case errors.Is(err, tus.ErrOffsetMismatch):
+		return http.StatusConflict
+	case errors.Is(err, tus.ErrExceedsLength):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, tus.ErrLocked):
+		return http.StatusLocked
+	}
+	return http.StatusInternalServerError
+}
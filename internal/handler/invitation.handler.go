@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/amirullazmi0/kratify-backend/internal/dto"
+	"github.com/amirullazmi0/kratify-backend/internal/model"
+	"github.com/amirullazmi0/kratify-backend/internal/usecase"
+	"github.com/amirullazmi0/kratify-backend/pkg/response"
+	"github.com/amirullazmi0/kratify-backend/pkg/validator"
+	"github.com/gin-gonic/gin"
+)
+
+type InvitationHandler struct {
+	usecase usecase.InvitationUsecase
+}
+
+func NewInvitationHandler(usecase usecase.InvitationUsecase) *InvitationHandler {
+	return &InvitationHandler{usecase: usecase}
+}
+
+// CreateInvitation godoc
+// @Summary Invite a user
+// @Description Create a pending user with the given role and email them an invitation link
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.CreateInvitationRequest true "Create Invitation Request"
+// @Success 201 {object} response.Response{data=dto.InvitationResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Router /api/admin/invitations [post]
+func (h *InvitationHandler) CreateInvitation(c *gin.Context) {
+	var req dto.CreateInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	// Validate request
+	if err := validator.Validate(&req); err != nil {
+		response.ValidationError(c, validator.FormatValidationErrors(err))
+		return
+	}
+
+	result, err := h.usecase.CreateInvitation(c.GetString("user_id"), &req)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Invitation sent successfully", result)
+}
+
+// GetInvitations godoc
+// @Summary List invitations
+// @Description List invitations, optionally filtered by status
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Status (pending, accepted, revoked, expired)"
+// @Success 200 {object} response.Response{data=[]dto.InvitationResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Router /api/admin/invitations [get]
+func (h *InvitationHandler) GetInvitations(c *gin.Context) {
+	status := c.Query("status")
+	switch status {
+	case "", model.InvitationStatusPending, model.InvitationStatusAccepted, model.InvitationStatusRevoked, model.InvitationStatusExpired:
+	default:
+		response.Error(c, http.StatusBadRequest, "Invalid status filter", nil)
+		return
+	}
+
+	invitations, err := h.usecase.GetInvitations(status)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to get invitations", err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Invitations retrieved successfully", invitations)
+}
+
+// ResendInvitation godoc
+// @Summary Resend an invitation
+// @Description Email a new invitation link and restart the expiry window; the previous link stops working
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Invitation ID"
+// @Success 200 {object} response.Response{data=dto.InvitationResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Router /api/admin/invitations/{id}/resend [post]
+func (h *InvitationHandler) ResendInvitation(c *gin.Context) {
+	result, err := h.usecase.ResendInvitation(c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Invitation resent successfully", result)
+}
+
+// RevokeInvitation godoc
+// @Summary Revoke an invitation
+// @Description Revoke a pending invitation and remove the pending user
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Invitation ID"
+// @Success 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/admin/invitations/{id} [delete]
+func (h *InvitationHandler) RevokeInvitation(c *gin.Context) {
+	if err := h.usecase.RevokeInvitation(c.GetString("user_id"), c.Param("id")); err != nil {
+		if errors.Is(err, usecase.ErrInvitationRoleForbidden) {
+			response.Error(c, http.StatusForbidden, err.Error(), nil)
+			return
+		}
+		response.Error(c, http.StatusNotFound, err.Error(), nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Invitation revoked successfully", nil)
+}
+
+// PreviewInvitation godoc
+// @Summary View an invitation
+// @Description Show the account an invitation link is for, before the invitee sets a password
+// @Tags auth
+// @Produce json
+// @Param token query string true "Invitation Token"
+// @Success 200 {object} response.Response{data=dto.InvitationPreviewResponse}
+// @Failure 400 {object} response.Response
+// @Router /api/auth/invitations/accept [get]
+func (h *InvitationHandler) PreviewInvitation(c *gin.Context) {
+	result, err := h.usecase.PreviewInvitation(c.Query("token"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Invitation retrieved successfully", result)
+}
+
+// AcceptInvitation godoc
+// @Summary Accept an invitation
+// @Description Set a password for the invited account, activate it and sign in
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dto.AcceptInvitationRequest true "Accept Invitation Request"
+// @Success 200 {object} response.Response{data=dto.AuthResponse}
+// @Failure 400 {object} response.Response
+// @Router /api/auth/invitations/accept [post]
+func (h *InvitationHandler) AcceptInvitation(c *gin.Context) {
+	var req dto.AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	// Validate request
+	if err := validator.Validate(&req); err != nil {
+		response.ValidationError(c, validator.FormatValidationErrors(err))
+		return
+	}
+
+	result, err := h.usecase.AcceptInvitation(&req)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	// Set authentication cookies
+	response.SetAuthCookies(c, result.AccessToken, result.RefreshToken, result.ExpiresIn)
+
+	response.Success(c, http.StatusOK, "Invitation accepted successfully", result)
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/amirullazmi0/kratify-backend/internal/dto"
+	"github.com/amirullazmi0/kratify-backend/internal/usecase"
+	"github.com/amirullazmi0/kratify-backend/pkg/response"
+	"github.com/amirullazmi0/kratify-backend/pkg/validator"
+	"github.com/gin-gonic/gin"
+)
+
+// magicLinkNonceCookie binds an emailed sign-in link to the browser that requested it
+const magicLinkNonceCookie = "magic_link_nonce"
+
+type MagicLinkHandler struct {
+	usecase usecase.MagicLinkUsecase
+}
+
+func NewMagicLinkHandler(usecase usecase.MagicLinkUsecase) *MagicLinkHandler {
+	return &MagicLinkHandler{usecase: usecase}
+}
+
+// RequestMagicLink godoc
+// @Summary Request a sign-in link
+// @Description Email a single-use, short-lived passwordless sign-in link bound to this browser
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dto.MagicLinkRequest true "Magic Link Request"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /api/auth/magic-link [post]
+func (h *MagicLinkHandler) RequestMagicLink(c *gin.Context) {
+	var req dto.MagicLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	// Validate request
+	if err := validator.Validate(&req); err != nil {
+		response.ValidationError(c, validator.FormatValidationErrors(err))
+		return
+	}
+
+	nonce, err := h.usecase.RequestMagicLink(&req)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to create sign-in link", err.Error())
+		return
+	}
+
+	c.SetCookie(magicLinkNonceCookie, nonce, int(usecase.MagicLinkTTL.Seconds()), "/api/auth/magic-link", "", false, true)
+
+	response.Success(c, http.StatusOK, "If the email is registered, a sign-in link has been sent.", nil)
+}
+
+// VerifyMagicLink godoc
+// @Summary Sign in with a magic link
+// @Description Consume a sign-in link from the same browser that requested it
+// @Tags auth
+// @Produce json
+// @Param token query string true "Sign-in Token"
+// @Success 200 {object} response.Response{data=dto.AuthResponse}
+// @Failure 401 {object} response.Response
+// @Router /api/auth/magic-link/verify [get]
+func (h *MagicLinkHandler) VerifyMagicLink(c *gin.Context) {
+	nonce, _ := c.Cookie(magicLinkNonceCookie)
+
+	result, err := h.usecase.VerifyMagicLink(c.Query("token"), nonce)
+	if err != nil {
+		response.Error(c, http.StatusUnauthorized, err.Error(), nil)
+		return
+	}
+
+	c.SetCookie(magicLinkNonceCookie, "", -1, "/api/auth/magic-link", "", false, true)
+
+	// Set authentication cookies
+	response.SetAuthCookies(c, result.AccessToken, result.RefreshToken, result.ExpiresIn)
+
+	response.Success(c, http.StatusOK, "Login successful", result)
+}
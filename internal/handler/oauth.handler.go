@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/amirullazmi0/kratify-backend/internal/dto"
+	"github.com/amirullazmi0/kratify-backend/internal/usecase"
+	"github.com/amirullazmi0/kratify-backend/pkg/oidc"
+	"github.com/amirullazmi0/kratify-backend/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// oauthStateCookie carries the signed state, nonce and PKCE verifier between redirect and callback
+const oauthStateCookie = "oauth_state"
+
+type OAuthHandler struct {
+	usecase usecase.OAuthUsecase
+}
+
+func NewOAuthHandler(usecase usecase.OAuthUsecase) *OAuthHandler {
+	return &OAuthHandler{usecase: usecase}
+}
+
+// GetProviders godoc
+// @Summary List social login providers
+// @Description List configured OpenID Connect providers
+// @Tags auth
+// @Produce json
+// @Success 200 {object} response.Response{data=[]dto.OAuthProviderResponse}
+// @Router /api/auth/oauth/providers [get]
+func (h *OAuthHandler) GetProviders(c *gin.Context) {
+	response.Success(c, http.StatusOK, "Providers retrieved successfully", h.usecase.GetProviders())
+}
+
+// Login godoc
+// @Summary Start social login
+// @Description Redirect to the OpenID Connect provider (authorization code + PKCE)
+// @Tags auth
+// @Param provider path string true "Provider name"
+// @Success 302
+// @Failure 404 {object} response.Response
+// @Failure 502 {object} response.Response
+// @Router /api/auth/oauth/{provider} [get]
+func (h *OAuthHandler) Login(c *gin.Context) {
+	authURL, stateCookie, err := h.usecase.BeginLogin(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		if errors.Is(err, oidc.ErrUnknownProvider) {
+			response.Error(c, http.StatusNotFound, err.Error(), nil)
+			return
+		}
+		response.Error(c, http.StatusBadGateway, "Failed to start social login", err.Error())
+		return
+	}
+
+	c.SetCookie(oauthStateCookie, stateCookie, 600, "/api/auth/oauth", "", false, true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback godoc
+// @Summary Social login callback
+// @Description Complete the OpenID Connect login and issue access and refresh tokens
+// @Tags auth
+// @Produce json
+// @Param provider path string true "Provider name"
+// @Param code query string true "Authorization code"
+// @Param state query string true "State"
+// @Success 200 {object} response.Response{data=dto.AuthResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Router /api/auth/oauth/{provider}/callback [get]
+func (h *OAuthHandler) Callback(c *gin.Context) {
+	var req dto.OAuthCallbackRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid callback parameters", err.Error())
+		return
+	}
+
+	stateCookie, _ := c.Cookie(oauthStateCookie)
+	c.SetCookie(oauthStateCookie, "", -1, "/api/auth/oauth", "", false, true)
+
+	result, err := h.usecase.CompleteLogin(c.Request.Context(), c.Param("provider"), &req, stateCookie)
+	if err != nil {
+		if errors.Is(err, oidc.ErrUnknownProvider) {
+			response.Error(c, http.StatusNotFound, err.Error(), nil)
+			return
+		}
+		response.Error(c, http.StatusUnauthorized, err.Error(), nil)
+		return
+	}
+
+	// Set authentication cookies
+	response.SetAuthCookies(c, result.AccessToken, result.RefreshToken, result.ExpiresIn)
+
+	response.Success(c, http.StatusOK, "Login successful", result)
+}
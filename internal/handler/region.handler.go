@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/amirullazmi0/kratify-backend/internal/dto"
+	"github.com/amirullazmi0/kratify-backend/internal/usecase"
+	"github.com/amirullazmi0/kratify-backend/pkg/response"
+	"github.com/amirullazmi0/kratify-backend/pkg/validator"
+	"github.com/gin-gonic/gin"
+)
+
+type RegionHandler struct {
+	usecase usecase.RegionUsecase
+}
+
+func NewRegionHandler(usecase usecase.RegionUsecase) *RegionHandler {
+	return &RegionHandler{usecase: usecase}
+}
+
+// GetProvinces godoc
+// @Summary List provinces
+// @Description List all provinces of Indonesia
+// @Tags regions
+// @Produce json
+// @Success 200 {object} response.Response{data=[]dto.RegionResponse}
+// @Router /api/regions/provinces [get]
+func (h *RegionHandler) GetProvinces(c *gin.Context) {
+	result, err := h.usecase.GetProvinces()
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to get provinces", err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Provinces retrieved successfully", result)
+}
+
+// GetRegencies godoc
+// @Summary List regencies
+// @Description List the regencies and cities of a province
+// @Tags regions
+// @Produce json
+// @Param code path string true "Province code"
+// @Success 200 {object} response.Response{data=[]dto.RegionResponse}
+// @Failure 404 {object} response.Response
+// @Router /api/regions/provinces/{code}/regencies [get]
+func (h *RegionHandler) GetRegencies(c *gin.Context) {
+	result, err := h.usecase.GetRegencies(c.Param("code"))
+	if err != nil {
+		response.Error(c, http.StatusNotFound, err.Error(), nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Regencies retrieved successfully", result)
+}
+
+// GetDistricts godoc
+// @Summary List districts
+// @Description List the districts (kecamatan) of a regency
+// @Tags regions
+// @Produce json
+// @Param code path string true "Regency code"
+// @Success 200 {object} response.Response{data=[]dto.RegionResponse}
+// @Failure 404 {object} response.Response
+// @Router /api/regions/regencies/{code}/districts [get]
+func (h *RegionHandler) GetDistricts(c *gin.Context) {
+	result, err := h.usecase.GetDistricts(c.Param("code"))
+	if err != nil {
+		response.Error(c, http.StatusNotFound, err.Error(), nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Districts retrieved successfully", result)
+}
+
+// GetVillages godoc
+// @Summary List villages
+// @Description List the villages (kelurahan/desa) of a district
+// @Tags regions
+// @Produce json
+// @Param code path string true "District code"
+// @Success 200 {object} response.Response{data=[]dto.RegionResponse}
+// @Failure 404 {object} response.Response
+// @Router /api/regions/districts/{code}/villages [get]
+func (h *RegionHandler) GetVillages(c *gin.Context) {
+	result, err := h.usecase.GetVillages(c.Param("code"))
+	if err != nil {
+		response.Error(c, http.StatusNotFound, err.Error(), nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Villages retrieved successfully", result)
+}
+
+// SearchRegions godoc
+// @Summary Search regions
+// @Description Address autocomplete: fuzzy, accent-insensitive search over region names and their ancestors
+// @Tags regions
+// @Produce json
+// @Param q query string true "Search text (e.g. senayan jakarta)"
+// @Param level query string false "Only return one level" Enums(province, regency, district, village)
+// @Param limit query int false "Maximum suggestions (1-50)" default(10)
+// @Success 200 {object} response.Response{data=[]dto.RegionSuggestionResponse}
+// @Failure 422 {object} response.Response
+// @Router /api/regions/search [get]
+func (h *RegionHandler) SearchRegions(c *gin.Context) {
+	var req dto.RegionSearchRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid query parameters", err.Error())
+		return
+	}
+
+	// Validate request
+	if err := validator.Validate(&req); err != nil {
+		response.ValidationError(c, validator.FormatValidationErrors(err))
+		return
+	}
+
+	result, err := h.usecase.SearchRegions(&req)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to search regions", err.Error())
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Regions retrieved successfully", result)
+}
+
+// LookupPostalCode godoc
+// @Summary Look up postal code
+// @Description List the candidate districts, and their villages, that use a postal code
+// @Tags regions
+// @Produce json
+// @Param code path string true "Postal code"
+// @Success 200 {object} response.Response{data=[]dto.PostalCodeCandidateResponse}
+// @Failure 404 {object} response.Response
+// @Router /api/regions/postal/{code} [get]
+func (h *RegionHandler) LookupPostalCode(c *gin.Context) {
+	result, err := h.usecase.LookupPostalCode(c.Param("code"))
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to look up postal code", err.Error())
+		return
+	}
+
+	if len(result) == 0 {
+		response.Error(c, http.StatusNotFound, "postal code not found", nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Postal code candidates retrieved successfully", result)
+}
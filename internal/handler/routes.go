@@ -13,7 +13,16 @@ func SetupRoutes(
 	userHandler *UserHandler,
 	addressHandler *AddressHandler,
 	attachmentHandler *AttachmentHandler,
+	apiKeyHandler *APIKeyHandler,
+	oauthHandler *OAuthHandler,
+	magicLinkHandler *MagicLinkHandler,
+	invitationHandler *InvitationHandler,
+	accountHandler *AccountHandler,
+	regionHandler *RegionHandler,
 	cfg *config.Config) {
+	// Accepts either a JWT or a personal API key (X-API-Key)
+	authenticate := middleware.Authenticate(&cfg.JWT, apiKeyHandler.usecase, accountHandler.usecase)
+
 	// API routes
 	api := router.Group("/api")
 	{
@@ -24,41 +33,129 @@ func SetupRoutes(
 			auth.GET("/verify-email", userHandler.VerifyEmail)
 			auth.POST("/login", userHandler.Login)
 			auth.POST("/refresh", userHandler.RefreshToken)
-			auth.POST("/logout", middleware.JWTAuth(&cfg.JWT), userHandler.Logout)
+			auth.POST("/logout", middleware.JWTAuth(&cfg.JWT), middleware.BlockImpersonation(), userHandler.Logout)
+
+			// Passwordless login
+			auth.POST("/magic-link", magicLinkHandler.RequestMagicLink)
+			auth.GET("/magic-link/verify", magicLinkHandler.VerifyMagicLink)
+
+			// Social login (OpenID Connect)
+			auth.GET("/oauth/providers", oauthHandler.GetProviders)
+			auth.GET("/oauth/:provider", oauthHandler.Login)
+			auth.GET("/oauth/:provider/callback", oauthHandler.Callback)
+
+			// Account reactivation
+			auth.POST("/reactivation", accountHandler.RequestReactivation)
+			auth.GET("/reactivate", accountHandler.Reactivate)
+
+			// Invitation acceptance
+			auth.GET("/invitations/accept", invitationHandler.PreviewInvitation)
+			auth.POST("/invitations/accept", invitationHandler.AcceptInvitation)
 		}
 
 		// User routes (protected)
 		users := api.Group("/users")
-		users.Use(middleware.JWTAuth(&cfg.JWT))
+		users.Use(authenticate)
 		{
 			users.GET("/profile", userHandler.GetProfile)
 			users.PUT("/profile", userHandler.UpdateProfile)
-			users.PUT("/change-password", userHandler.ChangePassword)
+			users.DELETE("/profile/avatar", userHandler.DeleteAvatar)
+			users.GET("/me/storage", attachmentHandler.GetStorageUsage)
+			users.PUT("/change-password", middleware.RequireSession(), middleware.BlockImpersonation(), userHandler.ChangePassword)
+
+			// Personal data export, deletion and deactivation (interactive sessions only)
+			me := users.Group("/me")
+			me.Use(middleware.RequireSession(), middleware.BlockImpersonation())
+			{
+				me.GET("/export", accountHandler.ExportData)
+				me.POST("/deletion", accountHandler.RequestDeletion)
+				me.DELETE("/deletion", accountHandler.CancelDeletion)
+				me.POST("/deactivate", accountHandler.Deactivate)
+			}
+
+			// API key management (interactive sessions only)
+			apiKeys := users.Group("/api-keys")
+			apiKeys.Use(middleware.RequireSession(), middleware.BlockImpersonation())
+			{
+				apiKeys.GET("", apiKeyHandler.GetAPIKeys)
+				apiKeys.POST("", apiKeyHandler.CreateAPIKey)
+				apiKeys.DELETE("/:id", apiKeyHandler.RevokeAPIKey)
+			}
 
 			// Admin only routes
 			users.GET("", middleware.RequireRole("ADMIN", "SUPERADMIN", "USER"), userHandler.GetAllUsers)
-			users.DELETE("/:id", middleware.RequireSuperAdmin(), userHandler.DeleteUser)
+			users.DELETE("/:id", middleware.RequireSession(), middleware.RequireSuperAdmin(), middleware.BlockImpersonation(), userHandler.DeleteUser)
+		}
+
+		// Admin routes (interactive sessions only)
+		admin := api.Group("/admin")
+		admin.Use(authenticate, middleware.RequireSession(), middleware.BlockImpersonation())
+		{
+			admin.POST("/users/:id/impersonate", middleware.RequireSuperAdmin(), userHandler.Impersonate)
+			admin.GET("/attachments/garbage", middleware.RequireSuperAdmin(), attachmentHandler.GetGarbageReport)
+
+			invitations := admin.Group("/invitations")
+			invitations.Use(middleware.RequireAdmin())
+			{
+				invitations.GET("", invitationHandler.GetInvitations)
+				invitations.POST("", invitationHandler.CreateInvitation)
+				invitations.POST("/:id/resend", invitationHandler.ResendInvitation)
+				invitations.DELETE("/:id", invitationHandler.RevokeInvitation)
+			}
 		}
 
 		// Address routes (protected)
 		addresses := api.Group("/addresses")
-		addresses.Use(middleware.JWTAuth(&cfg.JWT))
+		addresses.Use(authenticate)
 		{
 			addresses.GET("", addressHandler.GetAddressByAuth)
 			addresses.POST("", addressHandler.CreateAddress)
+			addresses.GET("/nearby", addressHandler.GetNearbyAddresses)
+			addresses.POST("/import", addressHandler.ImportAddresses)
+			addresses.GET("/export", addressHandler.ExportAddresses)
 			addresses.GET("/:id", addressHandler.GetAddressByID)
 			addresses.PUT("/:id", addressHandler.UpdateAddress)
 			addresses.DELETE("/:id", addressHandler.DeleteAddress)
+			addresses.PUT("/:id/primary", addressHandler.SetPrimaryAddress)
+			addresses.GET("/:id/versions", addressHandler.GetAddressHistory)
+			addresses.GET("/:id/versions/:version_id", addressHandler.GetAddressVersion)
+		}
+
+		// Region reference data (public)
+		regions := api.Group("/regions")
+		{
+			regions.GET("/provinces", regionHandler.GetProvinces)
+			regions.GET("/provinces/:code/regencies", regionHandler.GetRegencies)
+			regions.GET("/regencies/:code/districts", regionHandler.GetDistricts)
+			regions.GET("/districts/:code/villages", regionHandler.GetVillages)
+			regions.GET("/search", regionHandler.SearchRegions)
+			regions.GET("/postal/:code", regionHandler.LookupPostalCode)
 		}
 
 		// Attachment routes (protected)
 		attachments := api.Group("/attachments")
-		attachments.Use(middleware.JWTAuth(&cfg.JWT))
+		attachments.Use(authenticate)
 		{
 			attachments.POST("/image", attachmentHandler.UploadImage)
 			attachments.POST("/document", attachmentHandler.UploadDocument)
 			attachments.POST("/product-image", attachmentHandler.UploadProductImage)
 			attachments.POST("/profile-image", attachmentHandler.UploadProfileImage)
+			attachments.POST("/presign", attachmentHandler.PresignUpload)
+			attachments.GET("", attachmentHandler.GetAttachments)
+			attachments.GET("/:id", attachmentHandler.GetAttachment)
+			attachments.GET("/:id/download", attachmentHandler.DownloadAttachment)
+			attachments.DELETE("/:id", attachmentHandler.DeleteAttachment)
+			attachments.POST("/:id/complete", attachmentHandler.CompleteUpload)
+
+			// Resumable uploads (tus 1.0)
+			attachments.POST("/tus", attachmentHandler.CreateResumableUpload)
+			attachments.HEAD("/tus/:id", attachmentHandler.GetResumableUploadOffset)
+			attachments.PATCH("/tus/:id", attachmentHandler.WriteResumableUpload)
+			attachments.DELETE("/tus/:id", attachmentHandler.TerminateResumableUpload)
 		}
+
+		// tus capability discovery (public)
+		api.OPTIONS("/attachments/tus", attachmentHandler.TusOptions)
+		api.OPTIONS("/attachments/tus/:id", attachmentHandler.TusOptions)
 	}
 }
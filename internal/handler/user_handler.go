@@ -194,6 +194,28 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	response.Success(c, http.StatusOK, "Profile updated successfully", result)
 }
 
+// DeleteAvatar godoc
+// @Summary Delete avatar
+// @Description Delete the current user's profile image and fall back to the generated avatar with their initials
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=dto.UserResponse}
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /api/users/profile/avatar [delete]
+func (h *UserHandler) DeleteAvatar(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	result, err := h.userUsecase.DeleteAvatar(userID)
+	if err != nil {
+		response.Error(c, http.StatusNotFound, err.Error(), nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Avatar deleted successfully", result)
+}
+
 // ChangePassword godoc
 // @Summary Change user password
 // @Description Change current user password
@@ -309,3 +331,39 @@ func (h *UserHandler) Logout(c *gin.Context) {
 
 	response.Success(c, http.StatusOK, "Logged out successfully", nil)
 }
+
+// Impersonate godoc
+// @Summary Impersonate a user
+// @Description Issue a short-lived access token to act as another user for support; every request made with it is audited
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param request body dto.ImpersonateRequest true "Impersonate Request"
+// @Success 200 {object} response.Response{data=dto.ImpersonationResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Router /api/admin/users/{id}/impersonate [post]
+func (h *UserHandler) Impersonate(c *gin.Context) {
+	var req dto.ImpersonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	// Validate request
+	if err := validator.Validate(&req); err != nil {
+		response.ValidationError(c, validator.FormatValidationErrors(err))
+		return
+	}
+
+	result, err := h.userUsecase.Impersonate(c.GetString("user_id"), c.Param("id"), &req, requestMetadata(c))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Impersonation started", result)
+}
@@ -6,74 +6,204 @@ import (
 	"time"
 
 	"github.com/amirullazmi0/kratify-backend/config"
+	"github.com/amirullazmi0/kratify-backend/internal/model"
 	"github.com/amirullazmi0/kratify-backend/pkg/response"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// Authentication methods stored in the "auth_method" context key
+const (
+	AuthMethodJWT    = "jwt"
+	AuthMethodAPIKey = "api_key"
+)
+
+// APIKeyHeader is the header used by machine clients to send a personal API key
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyPrincipal is the identity a valid API key acts for
+type APIKeyPrincipal struct {
+	KeyID  string
+	UserID string
+	Email  string
+	Role   string
+	Scopes []string
+}
+
+// APIKeyValidator resolves a raw API key into the identity it acts for
+type APIKeyValidator interface {
+	ValidateAPIKey(rawKey string) (*APIKeyPrincipal, error)
+}
+
 type Claims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	UserID string       `json:"user_id"`
+	Email  string       `json:"email"`
+	Role   string       `json:"role"`
+	Act    *ActorClaims `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// ActorClaims identifies the admin acting on behalf of the token subject (RFC 8693 "act" claim)
+type ActorClaims struct {
+	UserID string `json:"sub"`
+	Email  string `json:"email"`
+}
+
+// SessionChecker rejects access tokens of users whose sessions have been revoked
+// (e.g. after the account was deactivated)
+type SessionChecker interface {
+	CheckSession(userID string, issuedAt time.Time) error
+}
+
 // JWTAuth middleware validates JWT token
 func JWTAuth(cfg *config.JWTConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var tokenString string
-
-		// Try to get token from cookie first
-		tokenString, err := c.Cookie("access_token")
-		
-		// If not found in cookie, check Authorization header
-		if err != nil || tokenString == "" {
-			authHeader := c.GetHeader("Authorization")
-			if authHeader == "" {
-				response.Error(c, http.StatusUnauthorized, "Authorization required", nil)
-				c.Abort()
+		if _, ok := authenticateJWT(c, cfg); !ok {
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// authenticateJWT validates the JWT token and sets the user context keys.
+// It aborts the request and returns false when the token is missing or invalid.
+func authenticateJWT(c *gin.Context, cfg *config.JWTConfig) (*Claims, bool) {
+	var tokenString string
+
+	// Try to get token from cookie first
+	tokenString, err := c.Cookie("access_token")
+
+	// If not found in cookie, check Authorization header
+	if err != nil || tokenString == "" {
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			response.Error(c, http.StatusUnauthorized, "Authorization required", nil)
+			c.Abort()
+			return nil, false
+		}
+
+		// Extract token from "Bearer <token>"
+		parts := strings.SplitN(authHeader, " ", 2)
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			response.Error(c, http.StatusUnauthorized, "Invalid authorization header format", nil)
+			c.Abort()
+			return nil, false
+		}
+
+		tokenString = parts[1]
+	}
+
+	// Parse and validate token
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		return []byte(cfg.Secret), nil
+	})
+
+	if err != nil || !token.Valid {
+		response.Error(c, http.StatusUnauthorized, "Invalid or expired token", nil)
+		c.Abort()
+		return nil, false
+	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "Invalid token claims", nil)
+		c.Abort()
+		return nil, false
+	}
+
+	// Set user info to context
+	c.Set("user_id", claims.UserID)
+	c.Set("user_email", claims.Email)
+	c.Set("user_role", claims.Role)
+	c.Set("auth_method", AuthMethodJWT)
+	if claims.Act != nil {
+		c.Set("impersonator_id", claims.Act.UserID)
+		c.Set("impersonator_email", claims.Act.Email)
+	}
+
+	return claims, true
+}
+
+// Authenticate middleware accepts either a personal API key (X-API-Key header)
+// or a JWT token, and sets the same user context keys as JWTAuth. Unlike JWTAuth
+// it also rejects tokens whose sessions have been revoked.
+func Authenticate(cfg *config.JWTConfig, apiKeys APIKeyValidator, sessions SessionChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rawKey := c.GetHeader(APIKeyHeader)
+		if rawKey == "" {
+			claims, ok := authenticateJWT(c, cfg)
+			if !ok {
 				return
 			}
 
-			// Extract token from "Bearer <token>"
-			parts := strings.SplitN(authHeader, " ", 2)
-			if len(parts) != 2 || parts[0] != "Bearer" {
-				response.Error(c, http.StatusUnauthorized, "Invalid authorization header format", nil)
+			var issuedAt time.Time
+			if claims.IssuedAt != nil {
+				issuedAt = claims.IssuedAt.Time
+			}
+			if err := sessions.CheckSession(claims.UserID, issuedAt); err != nil {
+				response.Error(c, http.StatusUnauthorized, err.Error(), nil)
 				c.Abort()
 				return
 			}
 
-			tokenString = parts[1]
+			c.Next()
+			return
 		}
 
-		// Parse and validate token
-		token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-			return []byte(cfg.Secret), nil
-		})
-
-		if err != nil || !token.Valid {
-			response.Error(c, http.StatusUnauthorized, "Invalid or expired token", nil)
+		principal, err := apiKeys.ValidateAPIKey(rawKey)
+		if err != nil {
+			response.Error(c, http.StatusUnauthorized, "Invalid or expired API key", nil)
 			c.Abort()
 			return
 		}
 
-		claims, ok := token.Claims.(*Claims)
-		if !ok {
-			response.Error(c, http.StatusUnauthorized, "Invalid token claims", nil)
+		// Safe methods need the read scope, everything else needs write
+		requiredScope := model.APIKeyScopeWrite
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			requiredScope = model.APIKeyScopeRead
+		}
+		if !hasScope(principal.Scopes, requiredScope) {
+			response.Error(c, http.StatusForbidden, "API key does not have the required scope: "+requiredScope, nil)
 			c.Abort()
 			return
 		}
 
 		// Set user info to context
-		c.Set("user_id", claims.UserID)
-		c.Set("user_email", claims.Email)
-		c.Set("user_role", claims.Role)
+		c.Set("user_id", principal.UserID)
+		c.Set("user_email", principal.Email)
+		c.Set("user_role", principal.Role)
+		c.Set("auth_method", AuthMethodAPIKey)
+		c.Set("api_key_id", principal.KeyID)
+
+		c.Next()
+	}
+}
+
+// RequireSession middleware rejects requests authenticated with an API key,
+// for endpoints that must only be reachable from an interactive login
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("auth_method") == AuthMethodAPIKey {
+			response.Error(c, http.StatusForbidden, "This endpoint cannot be accessed with an API key", nil)
+			c.Abort()
+			return
+		}
 
 		c.Next()
 	}
 }
 
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
 // GenerateToken generates a new JWT token
 func GenerateToken(userID string, email string, role string, cfg *config.JWTConfig) (string, error) {
 	claims := Claims{
@@ -90,6 +220,24 @@ func GenerateToken(userID string, email string, role string, cfg *config.JWTConf
 	return token.SignedString([]byte(cfg.Secret))
 }
 
+// GenerateImpersonationToken generates a short-lived access token for userID
+// carrying the acting admin in the "act" claim. No refresh token is issued for it.
+func GenerateImpersonationToken(userID string, email string, role string, actor ActorClaims, ttl time.Duration, cfg *config.JWTConfig) (string, error) {
+	claims := Claims{
+		UserID: userID,
+		Email:  email,
+		Role:   role,
+		Act:    &actor,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(cfg.Secret))
+}
+
 // GenerateRefreshToken generates a refresh token (longer expiry)
 func GenerateRefreshToken(userID string, email string, role string, cfg *config.JWTConfig) (string, error) {
 	claims := Claims{
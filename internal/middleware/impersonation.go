@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/amirullazmi0/kratify-backend/internal/model"
+	"github.com/amirullazmi0/kratify-backend/pkg/logger"
+	"github.com/amirullazmi0/kratify-backend/pkg/response"
+
+	"github.com/gin-contrib/requestid"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// AuditRecorder persists audit trail entries
+type AuditRecorder interface {
+	Create(entry *model.AuditLog) (string, error)
+}
+
+// BlockImpersonation middleware rejects sensitive requests made with an impersonation token
+func BlockImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("impersonator_id") != "" {
+			response.Error(c, http.StatusForbidden, "This action is not allowed while impersonating a user", nil)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// ImpersonationAudit middleware records every request made under impersonation in the audit trail
+func ImpersonationAudit(recorder AuditRecorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		impersonatorID := c.GetString("impersonator_id")
+		if impersonatorID == "" {
+			return
+		}
+
+		userID := c.GetString("user_id")
+		entry := &model.AuditLog{
+			ActorID:       &impersonatorID,
+			SubjectUserID: &userID,
+			Action:        model.AuditActionImpersonatedRequest,
+			Metadata: map[string]interface{}{
+				"method": c.Request.Method,
+				"path":   c.Request.URL.Path,
+				"status": c.Writer.Status(),
+			},
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+			RequestID: requestid.Get(c),
+		}
+
+		if _, err := recorder.Create(entry); err != nil {
+			logger.Error("Failed to write impersonation audit log",
+				zap.String("impersonator_id", impersonatorID),
+				zap.String("user_id", userID),
+				zap.Error(err),
+			)
+		}
+	}
+}
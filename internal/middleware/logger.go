@@ -5,6 +5,7 @@ import (
 
 	"github.com/amirullazmi0/kratify-backend/pkg/logger"
 
+	"github.com/gin-contrib/requestid"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
@@ -37,10 +38,22 @@ func Logger() gin.HandlerFunc {
 			zap.Duration("latency", latency),
 			zap.Int64("latency_ms", latency.Milliseconds()),
 			zap.String("user_agent", c.Request.UserAgent()),
-			zap.String("request_id", c.GetString("RequestID")),
+			zap.String("request_id", requestid.Get(c)),
 			zap.Int("response_size", c.Writer.Size()),
 		}
 
+		if userID := c.GetString("user_id"); userID != "" {
+			fields = append(fields, zap.String("user_id", userID))
+		}
+
+		// Tag requests made by an admin on behalf of a user
+		if impersonatorID := c.GetString("impersonator_id"); impersonatorID != "" {
+			fields = append(fields,
+				zap.Bool("impersonated", true),
+				zap.String("impersonator_id", impersonatorID),
+			)
+		}
+
 		if errorMsg != "" {
 			fields = append(fields, zap.String("error", errorMsg))
 		}
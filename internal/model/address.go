@@ -3,23 +3,32 @@ package model
 import "time"
 
 type Address struct {
-	ID            string     `json:"id"`
-	UserID        string     `json:"user_id"`
-	Label         string     `json:"label"`
-	RecipientName string     `json:"recipient_name"`
-	Phone         string     `json:"phone"`
-	Province      string     `json:"province"`
-	City          string     `json:"city"`
-	District      string     `json:"district"`
-	SubDistrict   string     `json:"sub_district"`
-	PostalCode    string     `json:"postal_code"`
-	FullAddress   string     `json:"full_address"`
-	IsPrimary     bool       `json:"is_primary"`
-	IsActive      bool       `json:"is_active"`
-	CreatedAt     time.Time  `json:"created_at"`
-	UpdatedAt     time.Time  `json:"updated_at"`
-	DeletedAt     *time.Time `json:"deleted_at,omitempty"`
-	CreatedBy     *string    `json:"created_by,omitempty"`
-	UpdatedBy     *string    `json:"updated_by,omitempty"`
-	DeletedBy     *string    `json:"deleted_by,omitempty"`
+	ID               string     `json:"id"`
+	UserID           string     `json:"user_id"`
+	Label            string     `json:"label"`
+	RecipientName    string     `json:"recipient_name"`
+	Phone            string     `json:"phone"`
+	Province         string     `json:"province"`
+	City             string     `json:"city"`
+	District         string     `json:"district"`
+	SubDistrict      string     `json:"sub_district"`
+	PostalCode       string     `json:"postal_code"`
+	FullAddress      string     `json:"full_address"`
+	ProvinceCode     *string    `json:"province_code,omitempty"`
+	RegencyCode      *string    `json:"regency_code,omitempty"`
+	DistrictCode     *string    `json:"district_code,omitempty"`
+	VillageCode      *string    `json:"village_code,omitempty"`
+	Latitude         *float64   `json:"latitude,omitempty"`
+	Longitude        *float64   `json:"longitude,omitempty"`
+	CoordinateSource *string    `json:"coordinate_source,omitempty"`
+	Version          int        `json:"version"`
+	CurrentVersionID *string    `json:"current_version_id,omitempty"`
+	IsPrimary        bool       `json:"is_primary"`
+	IsActive         bool       `json:"is_active"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+	DeletedAt        *time.Time `json:"deleted_at,omitempty"`
+	CreatedBy        *string    `json:"created_by,omitempty"`
+	UpdatedBy        *string    `json:"updated_by,omitempty"`
+	DeletedBy        *string    `json:"deleted_by,omitempty"`
 }
@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// AddressVersion is an immutable snapshot of an address. Records that must keep
+// the address as it was (e.g. shipments) reference a version instead of the address.
+type AddressVersion struct {
+	ID            string    `json:"id"`
+	AddressID     string    `json:"address_id"`
+	UserID        string    `json:"user_id"`
+	Version       int       `json:"version"`
+	Label         string    `json:"label"`
+	RecipientName string    `json:"recipient_name"`
+	Phone         string    `json:"phone"`
+	Province      string    `json:"province"`
+	City          string    `json:"city"`
+	District      string    `json:"district"`
+	SubDistrict   string    `json:"sub_district"`
+	PostalCode    string    `json:"postal_code"`
+	FullAddress   string    `json:"full_address"`
+	ProvinceCode  *string   `json:"province_code,omitempty"`
+	RegencyCode   *string   `json:"regency_code,omitempty"`
+	DistrictCode  *string   `json:"district_code,omitempty"`
+	VillageCode   *string   `json:"village_code,omitempty"`
+	Latitude      *float64  `json:"latitude,omitempty"`
+	Longitude     *float64  `json:"longitude,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	CreatedBy     *string   `json:"created_by,omitempty"`
+}
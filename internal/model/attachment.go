@@ -0,0 +1,95 @@
+package model
+
+import "time"
+
+// Attachment kinds, matching the AttachmentKind enum
+const (
+	AttachmentKindImage        = "IMAGE"
+	AttachmentKindDocument     = "DOCUMENT"
+	AttachmentKindProductImage = "PRODUCT_IMAGE"
+	AttachmentKindProfileImage = "PROFILE_IMAGE"
+)
+
+// Attachment statuses, matching the AttachmentStatus enum
+const (
+	// AttachmentStatusPendingUpload is a direct upload that has not been completed yet
+	AttachmentStatusPendingUpload = "PENDING_UPLOAD"
+	// AttachmentStatusPendingScan is a file that cannot be downloaded until a malware scan finds it clean
+	AttachmentStatusPendingScan = "PENDING_SCAN"
+	AttachmentStatusReady       = "READY"
+	// AttachmentStatusQuarantined is a file in which malware was found; it is moved out of reach
+	AttachmentStatusQuarantined = "QUARANTINED"
+)
+
+// Attachment visibilities, matching the AttachmentVisibility enum
+const (
+	AttachmentVisibilityPublic = "PUBLIC"
+	// AttachmentVisibilityPrivate files are only reachable through signed, expiring download URLs
+	AttachmentVisibilityPrivate = "PRIVATE"
+)
+
+// Subjects that can reference an attachment
+const (
+	AttachmentReferenceUser    = "user"
+	AttachmentReferenceProduct = "product"
+)
+
+// Attachment is a file uploaded by a user. The content is kept by the storage
+// provider under the key in Path; only its metadata is stored here.
+type Attachment struct {
+	ID               string              `json:"id"`
+	OwnerID          string              `json:"owner_id"`
+	Kind             string              `json:"kind"`
+	Status           string              `json:"status"`
+	Visibility       string              `json:"visibility"`
+	Provider         string              `json:"provider"`
+	ProviderFileID   string              `json:"provider_file_id"`
+	FileName         string              `json:"file_name"`
+	Path             string              `json:"path"`
+	URL              string              `json:"url"`
+	MimeType         string              `json:"mime_type"`
+	Size             int64               `json:"size"`
+	Checksum         string              `json:"checksum"`
+	Width            *int                `json:"width,omitempty"`
+	Height           *int                `json:"height,omitempty"`
+	Variants         []AttachmentVariant `json:"variants"`
+	UploadExpiresAt  *time.Time          `json:"upload_expires_at,omitempty"`
+	ScannedAt        *time.Time          `json:"scanned_at,omitempty"`
+	MalwareSignature *string             `json:"malware_signature,omitempty"`
+	CreatedAt        time.Time           `json:"created_at"`
+}
+
+// AttachmentVariant is a resized copy generated for an image attachment
+type AttachmentVariant struct {
+	Name     string `json:"name"`
+	Key      string `json:"key"`
+	URL      string `json:"url"`
+	MimeType string `json:"mime_type"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	Size     int64  `json:"size"`
+}
+
+// AttachmentReference records that a user or product uses an attachment. Attachments
+// of the kinds meant to be used this way are garbage collected once unreferenced.
+type AttachmentReference struct {
+	AttachmentID string    `json:"attachment_id"`
+	SubjectType  string    `json:"subject_type"`
+	SubjectID    string    `json:"subject_id"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// StorageQuota limits the attachments of a user; zero limits are not enforced
+type StorageQuota struct {
+	MaxBytes       int64
+	MaxFiles       int
+	UploadsPerHour int
+}
+
+// StorageUsage is what a user currently stores. Pending uploads count with their
+// declared size so that concurrent uploads cannot overrun a quota.
+type StorageUsage struct {
+	Bytes           int64
+	Files           int
+	UploadsLastHour int
+}
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// Audit trail actions
+const (
+	AuditActionImpersonationStart    = "impersonation.start"
+	AuditActionImpersonatedRequest   = "impersonation.request"
+	AuditActionDeletionRequested     = "account.deletion_requested"
+	AuditActionDeletionCancelled     = "account.deletion_cancelled"
+	AuditActionAccountErased         = "account.erased"
+	AuditActionAccountDeactivated    = "account.deactivated"
+	AuditActionAccountReactivated    = "account.reactivated"
+	AuditActionAttachmentQuarantined = "attachment.quarantined"
+)
+
+type AuditLog struct {
+	ID            string                 `json:"id"`
+	ActorID       *string                `json:"actor_id,omitempty"`
+	SubjectUserID *string                `json:"subject_user_id,omitempty"`
+	Action        string                 `json:"action"`
+	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+	IP            string                 `json:"ip,omitempty"`
+	UserAgent     string                 `json:"user_agent,omitempty"`
+	RequestID     string                 `json:"request_id,omitempty"`
+	CreatedAt     time.Time              `json:"created_at"`
+}
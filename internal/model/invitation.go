@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+// Invitation statuses (derived, not stored)
+const (
+	InvitationStatusPending  = "pending"
+	InvitationStatusAccepted = "accepted"
+	InvitationStatusRevoked  = "revoked"
+	InvitationStatusExpired  = "expired"
+)
+
+// Invitation is an admin-issued invite for a pending user account
+type Invitation struct {
+	ID         string     `json:"id"`
+	UserID     *string    `json:"user_id,omitempty"`
+	Email      string     `json:"email"`
+	Role       string     `json:"role"`
+	InvitedBy  *string    `json:"invited_by,omitempty"`
+	TokenHash  string     `json:"-"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	SentCount  int        `json:"sent_count"`
+	LastSentAt time.Time  `json:"last_sent_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// Status reports where the invitation is in its lifecycle
+func (i *Invitation) Status() string {
+	switch {
+	case i.AcceptedAt != nil:
+		return InvitationStatusAccepted
+	case i.RevokedAt != nil:
+		return InvitationStatusRevoked
+	case !i.ExpiresAt.After(time.Now()):
+		return InvitationStatusExpired
+	default:
+		return InvitationStatusPending
+	}
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// MagicLinkToken is a single-use passwordless sign-in token
+type MagicLinkToken struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"user_id"`
+	TokenHash string     `json:"-"`
+	NonceHash string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// Region is an Indonesian administrative region identified by its Kemendagri code
+type Region struct {
+	Code       string    `json:"code"`
+	ParentCode *string   `json:"parent_code,omitempty"`
+	Level      string    `json:"level"`
+	Name       string    `json:"name"`
+	PostalCode *string   `json:"postal_code,omitempty"`
+	FullName   string    `json:"full_name"`
+	Latitude   *float64  `json:"latitude,omitempty"`
+	Longitude  *float64  `json:"longitude,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
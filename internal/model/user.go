@@ -7,22 +7,29 @@ import (
 )
 
 type User struct {
-	ID                  string     `json:"id"`
-	Email               string     `json:"email"`
-	Password            string     `json:"-"`
-	Name                string     `json:"name"`
-	Role                string     `json:"role"`
-	IsActive            bool       `json:"is_active"`
-	RefreshToken        *string    `json:"-"`
-	TokenExpiry         *time.Time `json:"-"`
-	VerificationToken   *string    `json:"-"`
-	VerificationExpiry  *time.Time `json:"-"`
-	CreatedAt           time.Time  `json:"created_at"`
-	UpdatedAt           time.Time  `json:"updated_at"`
-	DeletedAt           *time.Time `json:"deleted_at,omitempty"`
-	CreatedBy           *string    `json:"created_by,omitempty"`
-	UpdatedBy           *string    `json:"updated_by,omitempty"`
-	DeletedBy           *string    `json:"deleted_by,omitempty"`
+	ID                    string     `json:"id"`
+	Email                 string     `json:"email"`
+	Password              string     `json:"-"`
+	Name                  string     `json:"name"`
+	Role                  string     `json:"role"`
+	IsActive              bool       `json:"is_active"`
+	RefreshToken          *string    `json:"-"`
+	TokenExpiry           *time.Time `json:"-"`
+	VerificationToken     *string    `json:"-"`
+	VerificationExpiry    *time.Time `json:"-"`
+	DeletionRequestedAt   *time.Time `json:"deletion_requested_at,omitempty"`
+	DeletionScheduledAt   *time.Time `json:"deletion_scheduled_at,omitempty"`
+	DeactivatedAt         *time.Time `json:"deactivated_at,omitempty"`
+	SessionsRevokedAt     *time.Time `json:"-"`
+	ReactivationTokenHash *string    `json:"-"`
+	ReactivationExpiry    *time.Time `json:"-"`
+	AvatarID              *string    `json:"avatar_id,omitempty"`
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+	DeletedAt             *time.Time `json:"deleted_at,omitempty"`
+	CreatedBy             *string    `json:"created_by,omitempty"`
+	UpdatedBy             *string    `json:"updated_by,omitempty"`
+	DeletedBy             *string    `json:"deleted_by,omitempty"`
 }
 
 // HashPassword hashes the user password
@@ -39,3 +46,8 @@ func (u *User) HashPassword() error {
 func (u *User) ComparePassword(password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
 }
+
+// IsDeactivated reports whether the user has deactivated their own account
+func (u *User) IsDeactivated() bool {
+	return u.DeactivatedAt != nil
+}
@@ -2,77 +2,71 @@ package repository
 
 import (
 	"database/sql"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/amirullazmi0/kratify-backend/internal/dto"
 	"github.com/amirullazmi0/kratify-backend/internal/model"
 	"github.com/amirullazmi0/kratify-backend/pkg/database"
+	"github.com/amirullazmi0/kratify-backend/pkg/geo"
+	"github.com/amirullazmi0/kratify-backend/pkg/logger"
+	"go.uber.org/zap"
 )
 
 type AddressRepository interface {
-	Create(userID string, address *dto.CreateAddressRequest) (model.Address, error)
+	Create(address *model.Address) (model.Address, error)
 	FindByID(id string) (*model.Address, error)
 	FindByUserID(userID string) ([]model.Address, error)
-	Update(userID string, address *dto.UpdateAddressRequest) (model.Address, error)
+	FindWithinRadius(userID string, center geo.Point, radiusKm float64, limit int) ([]model.Address, error)
+	Update(address *model.Address) (model.Address, error)
 	Delete(id string) error
+	CountByUserID(userID string) (int64, error)
+	ClearPrimary(userID string) error
+	SetPrimary(userID string, id string) error
+	PromoteMostRecent(userID string) error
+	LockUser(userID string) error
+	CreateVersion(addressID string, createdBy string) (*model.AddressVersion, error)
+	FindVersionByID(id string) (*model.AddressVersion, error)
+	FindVersionsByAddressID(addressID string) ([]model.AddressVersion, error)
+	FindVersionsByUserID(userID string) ([]model.AddressVersion, error)
+	Transaction(fn func(repo AddressRepository) error) error
 }
 
 type addressRepository struct {
-	db *sql.DB
+	db database.Executor
+	// conn starts transactions; it is nil for a repository bound to a transaction
+	conn  *sql.DB
+	earth *extensionProbe
 }
 
 func NewAddressRepository(db *sql.DB) AddressRepository {
-	return &addressRepository{db: db}
+	return &addressRepository{db: db, conn: db, earth: &extensionProbe{name: "earthdistance"}}
 }
 
-func (r *addressRepository) Create(userID string, address *dto.CreateAddressRequest) (model.Address, error) {
-	id, err := database.NewInsertBuilder("addresses").
-		Set("user_id", userID).
-		Set("label", address.Label).
-		Set("recipient_name", address.RecipientName).
-		Set("phone", address.Phone).
-		Set("province", address.Province).
-		Set("city", address.City).
-		Set("district", address.District).
-		Set("sub_district", address.SubDistrict).
-		Set("postal_code", address.PostalCode).
-		Set("full_address", address.FullAddress).
-		Set("is_primary", address.IsPrimary).
-		Execute(r.db)
-
-	if err != nil {
-		return model.Address{}, err
-	}
+// extensionProbe checks once whether a PostgreSQL extension is installed
+type extensionProbe struct {
+	name      string
+	once      sync.Once
+	available bool
+}
 
-	// Return created address
-	return model.Address{
-		ID:            id,
-		UserID:        userID,
-		Label:         address.Label,
-		RecipientName: address.RecipientName,
-		Phone:         address.Phone,
-		Province:      address.Province,
-		City:          address.City,
-		District:      address.District,
-		SubDistrict:   address.SubDistrict,
-		PostalCode:    address.PostalCode,
-		FullAddress:   address.FullAddress,
-		IsPrimary:     address.IsPrimary,
-		IsActive:      true,
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
-	}, nil
+func (p *extensionProbe) Available(db database.Executor) bool {
+	p.once.Do(func() {
+		available, err := database.HasExtension(db, p.name)
+		if err != nil {
+			logger.Warn("Failed to check database extension", zap.String("extension", p.name), zap.Error(err))
+		}
+		p.available = available
+	})
+	return p.available
 }
 
-func (r *addressRepository) FindByID(id string) (*model.Address, error) {
-	query, args := database.NewQueryBuilder("addresses").
-		Where("id = $1", id).
-		Where("deleted_at IS NULL").
-		Limit(1).
-		Build()
+var addressColumns = []string{"id", "user_id", "label", "recipient_name", "phone", "province", "city", "district", "sub_district", "postal_code", "full_address", "province_code", "regency_code", "district_code", "village_code", "latitude", "longitude", "coordinate_source", "version", "current_version_id", "is_primary", "is_active", "created_at", "updated_at", "deleted_at", "created_by", "updated_by", "deleted_by"}
 
+func scanAddress(scanner rowScanner) (*model.Address, error) {
 	var address model.Address
-	err := database.RawQueryRow(r.db, query, args...).Scan(
+	err := scanner.Scan(
 		&address.ID,
 		&address.UserID,
 		&address.Label,
@@ -84,6 +78,15 @@ func (r *addressRepository) FindByID(id string) (*model.Address, error) {
 		&address.SubDistrict,
 		&address.PostalCode,
 		&address.FullAddress,
+		&address.ProvinceCode,
+		&address.RegencyCode,
+		&address.DistrictCode,
+		&address.VillageCode,
+		&address.Latitude,
+		&address.Longitude,
+		&address.CoordinateSource,
+		&address.Version,
+		&address.CurrentVersionID,
 		&address.IsPrimary,
 		&address.IsActive,
 		&address.CreatedAt,
@@ -93,19 +96,79 @@ func (r *addressRepository) FindByID(id string) (*model.Address, error) {
 		&address.UpdatedBy,
 		&address.DeletedBy,
 	)
+	if err != nil {
+		return nil, err
+	}
+	return &address, nil
+}
 
-	if err == sql.ErrNoRows {
-		return nil, sql.ErrNoRows
+// Transaction runs fn with a repository bound to a single transaction.
+// Calls made on a repository that is already transactional join that transaction.
+func (r *addressRepository) Transaction(fn func(repo AddressRepository) error) error {
+	if r.conn == nil {
+		return fn(r)
 	}
+
+	return database.WithTransaction(r.conn, func(tx *sql.Tx) error {
+		return fn(&addressRepository{db: tx, earth: r.earth})
+	})
+}
+
+// LockUser serialises address changes of one user until the surrounding transaction ends
+func (r *addressRepository) LockUser(userID string) error {
+	var id string
+	return database.RawQueryRow(r.db, `SELECT id FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&id)
+}
+
+func (r *addressRepository) Create(address *model.Address) (model.Address, error) {
+	id, err := database.NewInsertBuilder("addresses").
+		Set("user_id", address.UserID).
+		Set("label", address.Label).
+		Set("recipient_name", address.RecipientName).
+		Set("phone", address.Phone).
+		Set("province", address.Province).
+		Set("city", address.City).
+		Set("district", address.District).
+		Set("sub_district", address.SubDistrict).
+		Set("postal_code", address.PostalCode).
+		Set("full_address", address.FullAddress).
+		Set("province_code", address.ProvinceCode).
+		Set("regency_code", address.RegencyCode).
+		Set("district_code", address.DistrictCode).
+		Set("village_code", address.VillageCode).
+		Set("latitude", address.Latitude).
+		Set("longitude", address.Longitude).
+		Set("coordinate_source", address.CoordinateSource).
+		Set("is_primary", address.IsPrimary).
+		Execute(r.db)
+
 	if err != nil {
-		return nil, err
+		return model.Address{}, err
 	}
 
-	return &address, nil
+	// Return created address
+	created := *address
+	created.ID = id
+	created.IsActive = true
+	created.CreatedAt = time.Now()
+	created.UpdatedAt = time.Now()
+	return created, nil
+}
+
+func (r *addressRepository) FindByID(id string) (*model.Address, error) {
+	query, args := database.NewQueryBuilder("addresses").
+		Select(addressColumns...).
+		Where("id = $1", id).
+		Where("deleted_at IS NULL").
+		Limit(1).
+		Build()
+
+	return scanAddress(database.RawQueryRow(r.db, query, args...))
 }
 
 func (r *addressRepository) FindByUserID(userID string) ([]model.Address, error) {
 	query, args := database.NewQueryBuilder("addresses").
+		Select(addressColumns...).
 		Where("user_id = $1", userID).
 		Where("deleted_at IS NULL").
 		OrderBy("is_primary DESC, created_at DESC").
@@ -115,81 +178,105 @@ func (r *addressRepository) FindByUserID(userID string) ([]model.Address, error)
 	if err != nil {
 		return nil, err
 	}
+	return scanAddresses(rows)
+}
+
+func scanAddresses(rows *sql.Rows) ([]model.Address, error) {
 	defer rows.Close()
 
 	var addresses []model.Address
 	for rows.Next() {
-		var address model.Address
-		err := rows.Scan(
-			&address.ID,
-			&address.UserID,
-			&address.Label,
-			&address.RecipientName,
-			&address.Phone,
-			&address.Province,
-			&address.City,
-			&address.District,
-			&address.SubDistrict,
-			&address.PostalCode,
-			&address.FullAddress,
-			&address.IsPrimary,
-			&address.IsActive,
-			&address.CreatedAt,
-			&address.UpdatedAt,
-			&address.DeletedAt,
-			&address.CreatedBy,
-			&address.UpdatedBy,
-			&address.DeletedBy,
-		)
+		address, err := scanAddress(rows)
 		if err != nil {
 			return nil, err
 		}
-		addresses = append(addresses, address)
+		addresses = append(addresses, *address)
 	}
 
-	return addresses, nil
+	return addresses, rows.Err()
 }
 
-func (r *addressRepository) Update(userID string, address *dto.UpdateAddressRequest) (model.Address, error) {
-	// Build update dynamically based on non-nil fields
-	builder := database.NewUpdateBuilder("addresses")
+// FindWithinRadius returns the user's addresses within radiusKm of center, nearest first.
+// It uses earthdistance when the extension is installed and Haversine otherwise.
+func (r *addressRepository) FindWithinRadius(userID string, center geo.Point, radiusKm float64, limit int) ([]model.Address, error) {
+	if r.earth.Available(r.db) {
+		query := `SELECT ` + strings.Join(addressColumns, ", ") + `
+			FROM addresses
+			WHERE user_id = $1 AND deleted_at IS NULL AND latitude IS NOT NULL
+				AND earth_box(ll_to_earth($2, $3), $4) @> ll_to_earth(latitude, longitude)
+				AND earth_distance(ll_to_earth($2, $3), ll_to_earth(latitude, longitude)) <= $4
+			ORDER BY earth_distance(ll_to_earth($2, $3), ll_to_earth(latitude, longitude))
+			LIMIT $5`
 
-	if address.Label != "" {
-		builder.Set("label", address.Label)
-	}
-	if address.RecipientName != "" {
-		builder.Set("recipient_name", address.RecipientName)
-	}
-	if address.Phone != "" {
-		builder.Set("phone", address.Phone)
-	}
-	if address.Province != "" {
-		builder.Set("province", address.Province)
-	}
-	if address.City != "" {
-		builder.Set("city", address.City)
-	}
-	if address.District != "" {
-		builder.Set("district", address.District)
+		rows, err := database.RawQuery(r.db, query, userID, center.Latitude, center.Longitude, radiusKm*1000, limit)
+		if err != nil {
+			return nil, err
+		}
+		return scanAddresses(rows)
 	}
-	if address.SubDistrict != "" {
-		builder.Set("sub_district", address.SubDistrict)
+
+	southWest, northEast := geo.BoundingBox(center, radiusKm)
+	query, args := database.NewQueryBuilder("addresses").
+		Select(addressColumns...).
+		Where("user_id = $1", userID).
+		Where("deleted_at IS NULL").
+		WhereBetween("latitude", southWest.Latitude, northEast.Latitude).
+		WhereBetween("longitude", southWest.Longitude, northEast.Longitude).
+		Build()
+
+	rows, err := database.RawQuery(r.db, query, args...)
+	if err != nil {
+		return nil, err
 	}
-	if address.PostalCode != "" {
-		builder.Set("postal_code", address.PostalCode)
+	candidates, err := scanAddresses(rows)
+	if err != nil {
+		return nil, err
 	}
-	if address.FullAddress != "" {
-		builder.Set("full_address", address.FullAddress)
+
+	// The box is only a pre-filter; keep the addresses inside the circle
+	var addresses []model.Address
+	distances := make(map[string]float64)
+	for _, address := range candidates {
+		distance := geo.Distance(center, geo.Point{Latitude: *address.Latitude, Longitude: *address.Longitude})
+		if distance <= radiusKm {
+			distances[address.ID] = distance
+			addresses = append(addresses, address)
+		}
 	}
-	if address.IsPrimary != nil {
-		builder.Set("is_primary", *address.IsPrimary)
+
+	sort.Slice(addresses, func(i, j int) bool {
+		return distances[addresses[i].ID] < distances[addresses[j].ID]
+	})
+	if len(addresses) > limit {
+		addresses = addresses[:limit]
 	}
 
-	builder.Set("updated_at", time.Now())
+	return addresses, nil
+}
 
-	_, err := builder.
+// Update writes the editable fields of the address; is_primary is managed
+// through SetPrimary/ClearPrimary so the single-primary invariant holds
+func (r *addressRepository) Update(address *model.Address) (model.Address, error) {
+	_, err := database.NewUpdateBuilder("addresses").
+		Set("label", address.Label).
+		Set("recipient_name", address.RecipientName).
+		Set("phone", address.Phone).
+		Set("province", address.Province).
+		Set("city", address.City).
+		Set("district", address.District).
+		Set("sub_district", address.SubDistrict).
+		Set("postal_code", address.PostalCode).
+		Set("full_address", address.FullAddress).
+		Set("province_code", address.ProvinceCode).
+		Set("regency_code", address.RegencyCode).
+		Set("district_code", address.DistrictCode).
+		Set("village_code", address.VillageCode).
+		Set("latitude", address.Latitude).
+		Set("longitude", address.Longitude).
+		Set("coordinate_source", address.CoordinateSource).
+		Set("updated_at", time.Now()).
 		Where("id = $1", address.ID).
-		Where("user_id = $2", userID).
+		Where("user_id = $1", address.UserID).
 		Execute(r.db)
 	if err != nil {
 		return model.Address{}, err
@@ -208,9 +295,175 @@ func (r *addressRepository) Delete(id string) error {
 	// Soft delete
 	_, err := database.NewUpdateBuilder("addresses").
 		Set("deleted_at", time.Now()).
+		Set("is_primary", false).
+		Set("updated_at", time.Now()).
+		Where("id = $1", id).
+		Execute(r.db)
+
+	return err
+}
+
+func (r *addressRepository) CountByUserID(userID string) (int64, error) {
+	return database.Count(r.db, "addresses", "user_id = $1 AND deleted_at IS NULL", userID)
+}
+
+func (r *addressRepository) ClearPrimary(userID string) error {
+	_, err := database.NewUpdateBuilder("addresses").
+		Set("is_primary", false).
+		Set("updated_at", time.Now()).
+		Where("user_id = $1", userID).
+		Where("is_primary = true").
+		Where("deleted_at IS NULL").
+		Execute(r.db)
+
+	return err
+}
+
+// SetPrimary marks one address as primary; callers clear the previous primary first
+func (r *addressRepository) SetPrimary(userID string, id string) error {
+	_, err := database.NewUpdateBuilder("addresses").
+		Set("is_primary", true).
 		Set("updated_at", time.Now()).
 		Where("id = $1", id).
+		Where("user_id = $1", userID).
+		Where("deleted_at IS NULL").
 		Execute(r.db)
 
 	return err
 }
+
+// PromoteMostRecent makes the most recently created address primary when the user has none
+func (r *addressRepository) PromoteMostRecent(userID string) error {
+	query := `UPDATE addresses SET is_primary = true, updated_at = $2
+		WHERE id = (
+			SELECT id FROM addresses
+			WHERE user_id = $1 AND deleted_at IS NULL
+			ORDER BY created_at DESC
+			LIMIT 1
+		) AND NOT EXISTS (
+			SELECT 1 FROM addresses
+			WHERE user_id = $1 AND is_primary = true AND deleted_at IS NULL
+		)`
+
+	_, err := database.RawExec(r.db, query, userID, time.Now())
+	return err
+}
+
+// addressSnapshotColumns are copied from addresses into address_versions
+var addressSnapshotColumns = []string{"label", "recipient_name", "phone", "province", "city", "district", "sub_district", "postal_code", "full_address", "province_code", "regency_code", "district_code", "village_code", "latitude", "longitude"}
+
+var addressVersionColumns = append([]string{"id", "address_id", "user_id", "version"}, append(addressSnapshotColumns, "created_at", "created_by")...)
+
+func scanAddressVersion(scanner rowScanner) (*model.AddressVersion, error) {
+	var version model.AddressVersion
+	err := scanner.Scan(
+		&version.ID,
+		&version.AddressID,
+		&version.UserID,
+		&version.Version,
+		&version.Label,
+		&version.RecipientName,
+		&version.Phone,
+		&version.Province,
+		&version.City,
+		&version.District,
+		&version.SubDistrict,
+		&version.PostalCode,
+		&version.FullAddress,
+		&version.ProvinceCode,
+		&version.RegencyCode,
+		&version.DistrictCode,
+		&version.VillageCode,
+		&version.Latitude,
+		&version.Longitude,
+		&version.CreatedAt,
+		&version.CreatedBy,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// CreateVersion snapshots the current state of the address as its next version
+// and makes it the current one. Callers hold the user lock, so version numbers do not race.
+func (r *addressRepository) CreateVersion(addressID string, createdBy string) (*model.AddressVersion, error) {
+	snapshot := strings.Join(addressSnapshotColumns, ", ")
+	query := `WITH next AS (
+			SELECT COALESCE(MAX(version), 0) + 1 AS version FROM address_versions WHERE address_id = $1
+		), inserted AS (
+			INSERT INTO address_versions (address_id, user_id, version, ` + snapshot + `, created_by)
+			SELECT a.id, a.user_id, next.version, ` + prefixColumns("a.", addressSnapshotColumns) + `, $2
+			FROM addresses a, next
+			WHERE a.id = $1
+			RETURNING ` + strings.Join(addressVersionColumns, ", ") + `
+		), updated AS (
+			UPDATE addresses SET version = inserted.version, current_version_id = inserted.id
+			FROM inserted WHERE addresses.id = inserted.address_id
+		)
+		SELECT ` + strings.Join(addressVersionColumns, ", ") + ` FROM inserted`
+
+	return scanAddressVersion(database.RawQueryRow(r.db, query, addressID, nullIfEmpty(createdBy)))
+}
+
+func (r *addressRepository) FindVersionByID(id string) (*model.AddressVersion, error) {
+	query, args := database.NewQueryBuilder("address_versions").
+		Select(addressVersionColumns...).
+		Where("id = $1", id).
+		Limit(1).
+		Build()
+
+	return scanAddressVersion(database.RawQueryRow(r.db, query, args...))
+}
+
+// FindVersionsByAddressID returns the history of an address, newest first
+func (r *addressRepository) FindVersionsByAddressID(addressID string) ([]model.AddressVersion, error) {
+	query, args := database.NewQueryBuilder("address_versions").
+		Select(addressVersionColumns...).
+		Where("address_id = $1", addressID).
+		OrderBy("version DESC").
+		Build()
+
+	rows, err := database.RawQuery(r.db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanAddressVersions(rows)
+}
+
+func (r *addressRepository) FindVersionsByUserID(userID string) ([]model.AddressVersion, error) {
+	query, args := database.NewQueryBuilder("address_versions").
+		Select(addressVersionColumns...).
+		Where("user_id = $1", userID).
+		OrderBy("address_id, version DESC").
+		Build()
+
+	rows, err := database.RawQuery(r.db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanAddressVersions(rows)
+}
+
+func scanAddressVersions(rows *sql.Rows) ([]model.AddressVersion, error) {
+	defer rows.Close()
+
+	var versions []model.AddressVersion
+	for rows.Next() {
+		version, err := scanAddressVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, *version)
+	}
+
+	return versions, rows.Err()
+}
+
+func prefixColumns(prefix string, columns []string) string {
+	prefixed := make([]string, len(columns))
+	for i, column := range columns {
+		prefixed[i] = prefix + column
+	}
+	return strings.Join(prefixed, ", ")
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/amirullazmi0/kratify-backend/internal/model"
+	"github.com/amirullazmi0/kratify-backend/pkg/database"
+	"github.com/lib/pq"
+)
+
+type APIKeyRepository interface {
+	Create(apiKey *model.APIKey) (string, error)
+	FindByHash(keyHash string) (*model.APIKey, error)
+	FindByUserID(userID string) ([]model.APIKey, error)
+	Revoke(userID string, id string) (int64, error)
+	TouchLastUsed(id string) error
+}
+
+type apiKeyRepository struct {
+	db *sql.DB
+}
+
+// NewAPIKeyRepository creates a new API key repository
+func NewAPIKeyRepository(db *sql.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+var apiKeyColumns = []string{"id", "user_id", "name", "prefix", "key_hash", "scopes", "expires_at", "last_used_at", "revoked_at", "created_at", "updated_at"}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAPIKey(scanner rowScanner) (*model.APIKey, error) {
+	var apiKey model.APIKey
+	err := scanner.Scan(
+		&apiKey.ID,
+		&apiKey.UserID,
+		&apiKey.Name,
+		&apiKey.Prefix,
+		&apiKey.KeyHash,
+		pq.Array(&apiKey.Scopes),
+		&apiKey.ExpiresAt,
+		&apiKey.LastUsedAt,
+		&apiKey.RevokedAt,
+		&apiKey.CreatedAt,
+		&apiKey.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &apiKey, nil
+}
+
+func (r *apiKeyRepository) Create(apiKey *model.APIKey) (string, error) {
+	return database.NewInsertBuilder("api_keys").
+		Set("user_id", apiKey.UserID).
+		Set("name", apiKey.Name).
+		Set("prefix", apiKey.Prefix).
+		Set("key_hash", apiKey.KeyHash).
+		Set("scopes", pq.Array(apiKey.Scopes)).
+		Set("expires_at", apiKey.ExpiresAt).
+		Execute(r.db)
+}
+
+func (r *apiKeyRepository) FindByHash(keyHash string) (*model.APIKey, error) {
+	query, args := database.NewQueryBuilder("api_keys").
+		Select(apiKeyColumns...).
+		Where("key_hash = $1", keyHash).
+		Limit(1).
+		Build()
+
+	return scanAPIKey(database.RawQueryRow(r.db, query, args...))
+}
+
+func (r *apiKeyRepository) FindByUserID(userID string) ([]model.APIKey, error) {
+	query, args := database.NewQueryBuilder("api_keys").
+		Select(apiKeyColumns...).
+		Where("user_id = $1", userID).
+		OrderBy("created_at DESC").
+		Build()
+
+	rows, err := database.RawQuery(r.db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var apiKeys []model.APIKey
+	for rows.Next() {
+		apiKey, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		apiKeys = append(apiKeys, *apiKey)
+	}
+
+	return apiKeys, rows.Err()
+}
+
+func (r *apiKeyRepository) Revoke(userID string, id string) (int64, error) {
+	return database.NewUpdateBuilder("api_keys").
+		Set("revoked_at", time.Now()).
+		Set("updated_at", time.Now()).
+		Where("id = $1", id).
+		Where("user_id = $1", userID).
+		Where("revoked_at IS NULL").
+		Execute(r.db)
+}
+
+func (r *apiKeyRepository) TouchLastUsed(id string) error {
+	_, err := database.NewUpdateBuilder("api_keys").
+		Set("last_used_at", time.Now()).
+		Where("id = $1", id).
+		Execute(r.db)
+
+	return err
+}
@@ -1,89 +1,804 @@
 package repository
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"path"
+	"strings"
 	"time"
 
-	"github.com/amirullazmi0/kratify-backend/internal/dto"
+	"github.com/amirullazmi0/kratify-backend/internal/model"
 	"github.com/amirullazmi0/kratify-backend/pkg/database"
-	"github.com/amirullazmi0/kratify-backend/pkg/imagekit"
+	"github.com/amirullazmi0/kratify-backend/pkg/logger"
+	"github.com/amirullazmi0/kratify-backend/pkg/storage"
+	"github.com/amirullazmi0/kratify-backend/pkg/upload"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrDirectUploadUnsupported is returned by Presign when the storage provider cannot accept direct uploads
+	ErrDirectUploadUnsupported = errors.New("the storage provider does not support direct uploads")
+	// ErrUploadAlreadyCompleted is returned by Finalize for an attachment that is no longer pending
+	ErrUploadAlreadyCompleted = errors.New("attachment upload is already completed")
+	// ErrSignedURLUnsupported is returned by SignedURL when the storage provider cannot sign download URLs
+	ErrSignedURLUnsupported = errors.New("the storage provider does not support signed download URLs")
+	// ErrScanAlreadyRecorded is returned by MarkClean and Quarantine for an attachment that no longer awaits a scan
+	ErrScanAlreadyRecorded = errors.New("attachment scan is already recorded")
+
+	// errNoDuplicate is returned by useDuplicate when the owner stores no identical content
+	errNoDuplicate = errors.New("no attachment with the same content")
+)
+
+var (
+	// quarantineFolder holds files in which malware was found; it is private so they cannot be fetched
+	quarantineFolder = path.Join(storage.PrivateFolder, "quarantine")
+	// stagingFolder holds files that are not known to be safe yet, direct uploads and
+	// files awaiting a malware scan; it is private so they cannot be fetched either
+	stagingFolder = path.Join(storage.PrivateFolder, "staging")
 )
 
 type AttachmentRepository interface {
-	UploadImage(file io.Reader, fileName string) (*dto.AttachmentResponse, error)
-	UploadDocument(file io.Reader, fileName string) (*dto.AttachmentResponse, error)
-	UploadProductImage(file io.Reader, fileName string) (*dto.AttachmentResponse, error)
-	UploadProfileImage(file io.Reader, fileName string, userID string) (*dto.AttachmentResponse, error)
+	Store(attachment *model.Attachment, file io.Reader, folder string, variants []VariantUpload, quota *model.StorageQuota) error
+	Presign(attachment *model.Attachment, folder string, maxSize int64, expiry time.Duration, quota *model.StorageQuota) (*storage.UploadTarget, error)
+	Open(attachment *model.Attachment, key string) (io.ReadCloser, *storage.Object, error)
+	SignedURL(attachment *model.Attachment, key string, expiry time.Duration) (string, error)
+	Finalize(attachment *model.Attachment, file io.Reader, folder string, variants []VariantUpload, quota *model.StorageQuota) error
+	Usage(ownerID string) (*model.StorageUsage, error)
+	RemoveReference(attachmentID string, subjectType string, subjectID string) error
+	SetReference(subjectType string, subjectID string, kind string, attachmentID string) error
+	FindUnreferenced(kinds []string, before time.Time, limit int) ([]model.Attachment, error)
+	FindExpiredUploads(now time.Time, limit int) ([]model.Attachment, error)
+	FindPendingScans(before time.Time, limit int) ([]model.Attachment, error)
+	MarkClean(attachment *model.Attachment) error
+	Quarantine(attachment *model.Attachment, signature string) error
+	FindByID(id string) (*model.Attachment, error)
+	FindByIDs(ids []string) ([]model.Attachment, error)
+	FindByOwnerID(ownerID string, kind string) ([]model.Attachment, error)
+	Delete(attachment *model.Attachment) error
+	DeleteByOwnerID(ownerID string) (int, error)
+}
+
+// VariantUpload is a generated copy stored next to an attachment
+type VariantUpload struct {
+	Variant model.AttachmentVariant
+	// Extension of the variant file, including the dot
+	Extension string
+	Content   []byte
 }
 
 type attachmentRepository struct {
-	db       *sql.DB
-	imageKit imagekit.ImageKitService
+	db      *sql.DB
+	storage storage.Provider
 }
 
-func NewAttachmentRepository(db *sql.DB, imageKit imagekit.ImageKitService) AttachmentRepository {
+func NewAttachmentRepository(db *sql.DB, storage storage.Provider) AttachmentRepository {
 	return &attachmentRepository{
-		db:       db,
-		imageKit: imageKit,
+		db:      db,
+		storage: storage,
 	}
 }
 
-func (r *attachmentRepository) UploadImage(file io.Reader, fileName string) (*dto.AttachmentResponse, error) {
-	resp, err := r.imageKit.UploadFile(context.Background(), file, fileName, "images")
+var attachmentColumns = []string{"id", "owner_id", "kind", "status", "visibility", "provider", "provider_file_id", "file_name", "path", "url", "mime_type", "size", "checksum", "width", "height", "variants", "upload_expires_at", "scanned_at", "malware_signature", "created_at"}
+
+func scanAttachment(scanner rowScanner) (*model.Attachment, error) {
+	var attachment model.Attachment
+	var variants []byte
+	err := scanner.Scan(
+		&attachment.ID,
+		&attachment.OwnerID,
+		&attachment.Kind,
+		&attachment.Status,
+		&attachment.Visibility,
+		&attachment.Provider,
+		&attachment.ProviderFileID,
+		&attachment.FileName,
+		&attachment.Path,
+		&attachment.URL,
+		&attachment.MimeType,
+		&attachment.Size,
+		&attachment.Checksum,
+		&attachment.Width,
+		&attachment.Height,
+		&variants,
+		&attachment.UploadExpiresAt,
+		&attachment.ScannedAt,
+		&attachment.MalwareSignature,
+		&attachment.CreatedAt,
+	)
 	if err != nil {
 		return nil, err
 	}
+	if err := json.Unmarshal(variants, &attachment.Variants); err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+func scanAttachments(rows *sql.Rows) ([]model.Attachment, error) {
+	attachments := []model.Attachment{}
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, *attachment)
+	}
 
-	return &dto.AttachmentResponse{
-		URL: resp.URL,
-	}, nil
+	return attachments, rows.Err()
 }
 
-func (r *attachmentRepository) UploadDocument(file io.Reader, fileName string) (*dto.AttachmentResponse, error) {
-	resp, err := r.imageKit.UploadFile(context.Background(), file, fileName, "documents")
+// Store uploads the file to folder, followed by its variants, and records them. The MIME
+// type, size and SHA-256 checksum are taken from the content; uploaded files are removed
+// again if the attachment cannot be recorded, e.g. because it does not fit the quota.
+// When the caller sets the checksum and the owner already stores that content, the
+// stored files are reused instead.
+func (r *attachmentRepository) Store(attachment *model.Attachment, file io.Reader, folder string, variants []VariantUpload, quota *model.StorageQuota) error {
+	if attachment.Checksum != "" {
+		var id string
+		err := r.lockOwner(attachment.OwnerID, func(tx database.Executor) error {
+			if err := r.useDuplicate(tx, attachment); err != nil {
+				return err
+			}
+			// The reused files are already counted
+			if err := r.checkQuota(tx, attachment.OwnerID, "", quota, 0); err != nil {
+				return err
+			}
+			var err error
+			id, err = r.insert(tx, attachment)
+			return err
+		})
+		if err == nil {
+			return r.reload(attachment, id)
+		}
+		if !errors.Is(err, errNoDuplicate) {
+			return err
+		}
+	}
+
+	uploaded, err := r.storeObjects(attachment, file, folder, variants)
+	if err != nil {
+		return err
+	}
+
+	var id string
+	err = r.withinQuota(attachment.OwnerID, "", quota, attachment.Size, func(tx database.Executor) error {
+		var err error
+		id, err = r.insert(tx, attachment)
+		return err
+	})
 	if err != nil {
+		r.removeObjects(uploaded)
+		return err
+	}
+
+	return r.reload(attachment, id)
+}
+
+// Presign records a pending direct upload to folder and returns where the client
+// sends the file. The record expires unless Finalize is called before the deadline;
+// until then it counts against the quota with the declared size. The file is staged
+// until Finalize has checked it.
+func (r *attachmentRepository) Presign(attachment *model.Attachment, folder string, maxSize int64, expiry time.Duration, quota *model.StorageQuota) (*storage.UploadTarget, error) {
+	presigner, ok := r.storage.(storage.Presigner)
+	if !ok {
+		return nil, ErrDirectUploadUnsupported
+	}
+
+	key, err := storage.NewKey(staged(objectFolder(attachment, folder)), attachment.FileName)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := presigner.PresignUpload(context.Background(), key, attachment.MimeType, maxSize, expiry)
+	if err != nil {
+		return nil, err
+	}
+
+	var id string
+	err = r.withinQuota(attachment.OwnerID, "", quota, attachment.Size, func(tx database.Executor) error {
+		if err := r.recordUpload(tx, attachment.OwnerID); err != nil {
+			return err
+		}
+		var err error
+		id, err = database.NewInsertBuilder("attachments").
+			Set("owner_id", attachment.OwnerID).
+			Set("kind", attachment.Kind).
+			Set("status", model.AttachmentStatusPendingUpload).
+			Set("visibility", visibility(attachment)).
+			Set("provider", r.storage.Name()).
+			Set("provider_file_id", key).
+			Set("file_name", attachment.FileName).
+			Set("path", key).
+			Set("url", r.storage.URL(key)).
+			Set("mime_type", attachment.MimeType).
+			Set("size", attachment.Size).
+			Set("checksum", "").
+			Set("upload_expires_at", target.ExpiresAt).
+			Execute(tx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.reload(attachment, id); err != nil {
 		return nil, err
 	}
+	return target, nil
+}
+
+// Open downloads a file of an attachment, the original or a variant; the caller must close the reader
+func (r *attachmentRepository) Open(attachment *model.Attachment, key string) (io.ReadCloser, *storage.Object, error) {
+	if err := r.checkProvider(attachment); err != nil {
+		return nil, nil, err
+	}
+
+	return r.storage.Get(context.Background(), key)
+}
+
+// SignedURL returns a short-lived download URL for a file of an attachment, the
+// original or a variant
+func (r *attachmentRepository) SignedURL(attachment *model.Attachment, key string, expiry time.Duration) (string, error) {
+	if err := r.checkProvider(attachment); err != nil {
+		return "", err
+	}
+
+	signer, ok := r.storage.(storage.Signer)
+	if !ok {
+		return "", ErrSignedURLUnsupported
+	}
+	return signer.SignedURL(context.Background(), key, expiry)
+}
+
+// Finalize marks a pending direct upload as ready. When file is set it is stored
+// (with its variants) in place of the uploaded file, which is then removed; otherwise
+// the uploaded file is kept and the caller sets its size, MIME type and checksum.
+// Content the owner already stores replaces the uploaded file like in Store. A kept
+// file leaves the staging folder unless it awaits a malware scan.
+// The quota is checked again with the actual size; the upload itself was already
+// counted against the hourly limit by Presign.
+func (r *attachmentRepository) Finalize(attachment *model.Attachment, file io.Reader, folder string, variants []VariantUpload, quota *model.StorageQuota) error {
+	if err := r.checkProvider(attachment); err != nil {
+		return err
+	}
+	if quota != nil {
+		sizeQuota := *quota
+		sizeQuota.UploadsPerHour = 0
+		quota = &sizeQuota
+	}
+
+	stagingKey := attachment.Path
+	if attachment.Checksum != "" {
+		err := r.lockOwner(attachment.OwnerID, func(tx database.Executor) error {
+			if err := r.useDuplicate(tx, attachment); err != nil {
+				return err
+			}
+			if err := r.checkQuota(tx, attachment.OwnerID, attachment.ID, quota, 0); err != nil {
+				return err
+			}
+			return r.complete(tx, attachment)
+		})
+		if err == nil {
+			r.removeObjects([]string{stagingKey})
+			return r.reload(attachment, attachment.ID)
+		}
+		if !errors.Is(err, errNoDuplicate) {
+			return err
+		}
+	}
+
+	var uploaded []string
+	if file != nil {
+		var err error
+		uploaded, err = r.storeObjects(attachment, file, folder, variants)
+		if err != nil {
+			return err
+		}
+	} else {
+		object, err := r.storage.Stat(context.Background(), stagingKey)
+		if err != nil {
+			return err
+		}
+		if attachment.Status != model.AttachmentStatusPendingScan && isStaged(stagingKey) {
+			object, err = r.copyObject(stagingKey, objectFolder(attachment, unstaged(stagingKey)))
+			if err != nil {
+				return err
+			}
+			uploaded = []string{object.Key}
+		}
+		attachment.ProviderFileID = object.ID
+		attachment.Path = object.Key
+		attachment.URL = object.URL
+		attachment.Variants = []model.AttachmentVariant{}
+	}
 
-	return &dto.AttachmentResponse{
-		URL: resp.URL,
-	}, nil
+	err := r.withinQuota(attachment.OwnerID, attachment.ID, quota, attachment.Size, func(tx database.Executor) error {
+		return r.complete(tx, attachment)
+	})
+	if err != nil {
+		r.removeObjects(uploaded)
+		return err
+	}
+
+	if attachment.Path != stagingKey {
+		r.removeObjects([]string{stagingKey})
+	}
+
+	return r.reload(attachment, attachment.ID)
 }
 
-func (r *attachmentRepository) UploadProductImage(file io.Reader, fileName string) (*dto.AttachmentResponse, error) {
-	resp, err := r.imageKit.UploadFile(context.Background(), file, fileName, "products")
+// FindExpiredUploads returns pending direct uploads whose deadline has passed
+func (r *attachmentRepository) FindExpiredUploads(now time.Time, limit int) ([]model.Attachment, error) {
+	query, args := database.NewQueryBuilder("attachments").
+		Select(attachmentColumns...).
+		Where("status = $1", model.AttachmentStatusPendingUpload).
+		Where("upload_expires_at < $2", now).
+		OrderBy("upload_expires_at ASC").
+		Limit(limit).
+		Build()
+
+	rows, err := database.RawQuery(r.db, query, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	return &dto.AttachmentResponse{
-		URL: resp.URL,
-	}, nil
+	return scanAttachments(rows)
 }
 
-func (r *attachmentRepository) UploadProfileImage(file io.Reader, fileName string, userID string) (*dto.AttachmentResponse, error) {
-	folder := fmt.Sprintf("profiles/%s", userID)
-	resp, err := r.imageKit.UploadFile(context.Background(), file, fileName, folder)
+// FindPendingScans returns attachments created before the given time that still await a malware scan
+func (r *attachmentRepository) FindPendingScans(before time.Time, limit int) ([]model.Attachment, error) {
+	query, args := database.NewQueryBuilder("attachments").
+		Select(attachmentColumns...).
+		Where("status = $1", model.AttachmentStatusPendingScan).
+		Where("created_at < $2", before).
+		OrderBy("created_at ASC").
+		Limit(limit).
+		Build()
 
+	rows, err := database.RawQuery(r.db, query, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	// Update user avatar
-	_, err = database.NewUpdateBuilder("users").
-		Set("avatar", resp.URL).
-		Set("updated_at", time.Now()).
-		Where("id = $1", userID).
+	return scanAttachments(rows)
+}
+
+// MarkClean makes an attachment awaiting a scan downloadable, moving its file out of
+// the staging folder to where its visibility puts it
+func (r *attachmentRepository) MarkClean(attachment *model.Attachment) error {
+	update := database.NewUpdateBuilder("attachments").
+		Set("status", model.AttachmentStatusReady).
+		Set("scanned_at", time.Now())
+
+	var released *storage.Object
+	if isStaged(attachment.Path) {
+		if err := r.checkProvider(attachment); err != nil {
+			return err
+		}
+		var err error
+		released, err = r.copyObject(attachment.Path, objectFolder(attachment, unstaged(attachment.Path)))
+		if err != nil {
+			return err
+		}
+		update = update.
+			Set("provider_file_id", released.ID).
+			Set("path", released.Key).
+			Set("url", released.URL)
+	}
+
+	affected, err := update.
+		Where("id = $1", attachment.ID).
+		Where("status = $1", model.AttachmentStatusPendingScan).
 		Execute(r.db)
+	if err == nil && affected == 0 {
+		err = ErrScanAlreadyRecorded
+	}
+	if err != nil {
+		if released != nil {
+			r.removeObjects([]string{released.Key})
+		}
+		return err
+	}
+
+	if released != nil {
+		r.removeObjects([]string{attachment.Path})
+	}
+	return r.reload(attachment, attachment.ID)
+}
 
+// Quarantine moves the file of an attachment awaiting a scan under the quarantine
+// folder, so its original URL stops working, and records the malware found
+func (r *attachmentRepository) Quarantine(attachment *model.Attachment, signature string) error {
+	if err := r.checkProvider(attachment); err != nil {
+		return err
+	}
+
+	quarantined, err := r.copyObject(attachment.Path, path.Join(quarantineFolder, unstaged(attachment.Path)))
+	if err != nil {
+		return err
+	}
+
+	affected, err := database.NewUpdateBuilder("attachments").
+		Set("status", model.AttachmentStatusQuarantined).
+		Set("provider_file_id", quarantined.ID).
+		Set("path", quarantined.Key).
+		Set("url", quarantined.URL).
+		Set("scanned_at", time.Now()).
+		Set("malware_signature", signature).
+		Where("id = $1", attachment.ID).
+		Where("status = $1", model.AttachmentStatusPendingScan).
+		Execute(r.db)
+	if err == nil && affected == 0 {
+		err = ErrScanAlreadyRecorded
+	}
+	if err != nil {
+		r.removeObjects([]string{quarantined.Key})
+		return err
+	}
+
+	r.removeObjects([]string{attachment.Path})
+	return r.reload(attachment, attachment.ID)
+}
+
+// insert records an uploaded attachment and returns its ID
+func (r *attachmentRepository) insert(db database.Executor, attachment *model.Attachment) (string, error) {
+	encodedVariants, err := json.Marshal(attachment.Variants)
+	if err != nil {
+		return "", err
+	}
+	if err := r.recordUpload(db, attachment.OwnerID); err != nil {
+		return "", err
+	}
+
+	return database.NewInsertBuilder("attachments").
+		Set("owner_id", attachment.OwnerID).
+		Set("kind", attachment.Kind).
+		Set("status", readyStatus(attachment)).
+		Set("visibility", visibility(attachment)).
+		Set("provider", attachment.Provider).
+		Set("provider_file_id", attachment.ProviderFileID).
+		Set("file_name", attachment.FileName).
+		Set("path", attachment.Path).
+		Set("url", attachment.URL).
+		Set("mime_type", attachment.MimeType).
+		Set("size", attachment.Size).
+		Set("checksum", attachment.Checksum).
+		Set("width", attachment.Width).
+		Set("height", attachment.Height).
+		Set("variants", string(encodedVariants)).
+		Execute(db)
+}
+
+// complete records the file of a pending direct upload
+func (r *attachmentRepository) complete(db database.Executor, attachment *model.Attachment) error {
+	encodedVariants, err := json.Marshal(attachment.Variants)
+	if err != nil {
+		return err
+	}
+
+	affected, err := database.NewUpdateBuilder("attachments").
+		Set("status", readyStatus(attachment)).
+		Set("provider_file_id", attachment.ProviderFileID).
+		Set("path", attachment.Path).
+		Set("url", attachment.URL).
+		Set("mime_type", attachment.MimeType).
+		Set("size", attachment.Size).
+		Set("checksum", attachment.Checksum).
+		Set("width", attachment.Width).
+		Set("height", attachment.Height).
+		Set("variants", string(encodedVariants)).
+		Set("upload_expires_at", nil).
+		Where("id = $1", attachment.ID).
+		Where("status = $1", model.AttachmentStatusPendingUpload).
+		Execute(db)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrUploadAlreadyCompleted
+	}
+	return nil
+}
+
+// useDuplicate points the attachment at the files of a ready attachment of the same
+// owner, kind and visibility whose content has the attachment's checksum. Only ready
+// files are shared: they are scanned or re-encoded, so the copy is ready as well.
+func (r *attachmentRepository) useDuplicate(db database.Executor, attachment *model.Attachment) error {
+	query, args := database.NewQueryBuilder("attachments").
+		Select(attachmentColumns...).
+		Where("owner_id = $1", attachment.OwnerID).
+		Where("checksum = $2", attachment.Checksum).
+		Where("kind = $3", attachment.Kind).
+		Where("visibility = $4", visibility(attachment)).
+		Where("provider = $5", r.storage.Name()).
+		Where("status = $6", model.AttachmentStatusReady).
+		OrderBy("created_at ASC").
+		Limit(1).
+		Build()
+
+	source, err := scanAttachment(database.RawQueryRow(db, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errNoDuplicate
+		}
+		return err
+	}
+
+	attachment.Status = model.AttachmentStatusReady
+	attachment.Provider = source.Provider
+	attachment.ProviderFileID = source.ProviderFileID
+	attachment.Path = source.Path
+	attachment.URL = source.URL
+	attachment.MimeType = source.MimeType
+	attachment.Size = source.Size
+	attachment.Width, attachment.Height = source.Width, source.Height
+	attachment.Variants = source.Variants
+	return nil
+}
+
+// shared reports whether another attachment uses the files of the attachment
+func (r *attachmentRepository) shared(db database.Executor, attachment *model.Attachment) (bool, error) {
+	var shared bool
+	err := database.RawQueryRow(db, `SELECT EXISTS (SELECT 1 FROM attachments WHERE path = $1 AND id <> $2)`, attachment.Path, attachment.ID).Scan(&shared)
+	return shared, err
+}
+
+// storeObjects uploads the file and its variants, filling in the attachment's storage
+// fields, and returns the keys of the uploaded files
+func (r *attachmentRepository) storeObjects(attachment *model.Attachment, file io.Reader, folder string, variants []VariantUpload) ([]string, error) {
+	head, err := io.ReadAll(io.LimitReader(file, 512))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	attachment.MimeType = upload.DetectContentType(head)
+
+	folder = objectFolder(attachment, folder)
+	if attachment.Status == model.AttachmentStatusPendingScan {
+		folder = staged(folder)
+	}
+	key, err := storage.NewKey(folder, attachment.FileName)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.New()
+	counter := &countingWriter{}
+	content := io.TeeReader(io.MultiReader(bytes.NewReader(head), file), io.MultiWriter(hash, counter))
+
+	object, err := r.storage.Put(context.Background(), key, content, -1, attachment.MimeType)
 	if err != nil {
 		return nil, err
 	}
 
-	return &dto.AttachmentResponse{
-		URL: resp.URL,
-	}, nil
+	attachment.Provider = r.storage.Name()
+	attachment.ProviderFileID = object.ID
+	attachment.Path = object.Key
+	attachment.URL = object.URL
+	attachment.Size = counter.n
+	attachment.Checksum = hex.EncodeToString(hash.Sum(nil))
+
+	uploaded := []string{object.Key}
+	attachment.Variants = []model.AttachmentVariant{}
+	for _, variant := range variants {
+		// Variants sit next to the original, e.g. images/3f9a1c2b-photo_thumbnail.webp
+		variantKey := strings.TrimSuffix(object.Key, path.Ext(object.Key)) + "_" + variant.Variant.Name + variant.Extension
+
+		stored, err := r.storage.Put(context.Background(), variantKey, bytes.NewReader(variant.Content), int64(len(variant.Content)), variant.Variant.MimeType)
+		if err != nil {
+			r.removeObjects(uploaded)
+			return nil, err
+		}
+		uploaded = append(uploaded, stored.Key)
+
+		variant.Variant.Key = stored.Key
+		variant.Variant.URL = stored.URL
+		variant.Variant.Size = int64(len(variant.Content))
+		attachment.Variants = append(attachment.Variants, variant.Variant)
+	}
+
+	return uploaded, nil
+}
+
+// reload replaces the attachment with the stored record
+func (r *attachmentRepository) reload(attachment *model.Attachment, id string) error {
+	stored, err := r.FindByID(id)
+	if err != nil {
+		return err
+	}
+	*attachment = *stored
+	return nil
+}
+
+// readyStatus is the status an uploaded attachment is recorded with: READY unless the
+// caller set another one, such as PENDING_SCAN
+func readyStatus(attachment *model.Attachment) string {
+	if attachment.Status == "" || attachment.Status == model.AttachmentStatusPendingUpload {
+		return model.AttachmentStatusReady
+	}
+	return attachment.Status
+}
+
+// visibility is the visibility an attachment is recorded with, public unless set
+func visibility(attachment *model.Attachment) string {
+	if attachment.Visibility == "" {
+		return model.AttachmentVisibilityPublic
+	}
+	return attachment.Visibility
+}
+
+// objectFolder places the files of private attachments under the storage's private folder
+func objectFolder(attachment *model.Attachment, folder string) string {
+	if attachment.Visibility == model.AttachmentVisibilityPrivate {
+		return path.Join(storage.PrivateFolder, folder)
+	}
+	return folder
+}
+
+// staged returns where a file at key is kept while it is not known to be safe
+func staged(key string) string {
+	return path.Join(stagingFolder, unstaged(key))
+}
+
+// isStaged reports whether key is in the staging folder
+func isStaged(key string) bool {
+	return strings.HasPrefix(key, stagingFolder+"/")
+}
+
+// unstaged returns key without the staging and private folders, e.g.
+// private/staging/documents/3f9a1c2b-report.pdf becomes documents/3f9a1c2b-report.pdf
+func unstaged(key string) string {
+	key = strings.TrimPrefix(key, stagingFolder+"/")
+	return strings.TrimPrefix(key, storage.PrivateFolder+"/")
+}
+
+// copyObject stores a copy of the file at from under to and returns the copy
+func (r *attachmentRepository) copyObject(from string, to string) (*storage.Object, error) {
+	content, object, err := r.storage.Get(context.Background(), from)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	return r.storage.Put(context.Background(), to, content, object.Size, object.ContentType)
+}
+
+// checkProvider refuses attachments stored with a provider other than the configured one
+func (r *attachmentRepository) checkProvider(attachment *model.Attachment) error {
+	if attachment.Provider != r.storage.Name() {
+		return fmt.Errorf("attachment is stored with the %q provider, which is not configured", attachment.Provider)
+	}
+	return nil
+}
+
+func (r *attachmentRepository) FindByID(id string) (*model.Attachment, error) {
+	query, args := database.NewQueryBuilder("attachments").
+		Select(attachmentColumns...).
+		Where("id = $1", id).
+		Limit(1).
+		Build()
+
+	return scanAttachment(database.RawQueryRow(r.db, query, args...))
+}
+
+// FindByIDs returns the attachments with the given IDs, in no particular order
+func (r *attachmentRepository) FindByIDs(ids []string) ([]model.Attachment, error) {
+	if len(ids) == 0 {
+		return []model.Attachment{}, nil
+	}
+
+	values := make([]interface{}, len(ids))
+	for i, id := range ids {
+		values[i] = id
+	}
+	query, args := database.NewQueryBuilder("attachments").
+		Select(attachmentColumns...).
+		WhereIn("id", values).
+		Build()
+
+	rows, err := database.RawQuery(r.db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAttachments(rows)
+}
+
+// FindByOwnerID lists a user's attachments, newest first, optionally of one kind
+func (r *attachmentRepository) FindByOwnerID(ownerID string, kind string) ([]model.Attachment, error) {
+	qb := database.NewQueryBuilder("attachments").
+		Select(attachmentColumns...).
+		Where("owner_id = $1", ownerID)
+	if kind != "" {
+		qb.Where("kind = $2", kind)
+	}
+	query, args := qb.OrderBy("created_at DESC").Build()
+
+	rows, err := database.RawQuery(r.db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAttachments(rows)
+}
+
+// Delete removes the remote files, then the record; files shared with another
+// attachment after deduplication are kept. The record is kept when a remote file
+// cannot be deleted, so the deletion can be retried; a file that is already gone
+// does not block it.
+func (r *attachmentRepository) Delete(attachment *model.Attachment) error {
+	if err := r.checkProvider(attachment); err != nil {
+		return err
+	}
+
+	return r.lockOwner(attachment.OwnerID, func(tx database.Executor) error {
+		shared, err := r.shared(tx, attachment)
+		if err != nil {
+			return err
+		}
+
+		if !shared {
+			keys := []string{attachment.Path}
+			for _, variant := range attachment.Variants {
+				keys = append(keys, variant.Key)
+			}
+			for _, key := range keys {
+				err := r.storage.Delete(context.Background(), key)
+				if err != nil && !errors.Is(err, storage.ErrNotFound) {
+					return err
+				}
+			}
+		}
+
+		_, err = database.NewDeleteBuilder("attachments").
+			Where("id = $1", attachment.ID).
+			HardDelete().
+			Execute(tx)
+		return err
+	})
+}
+
+// DeleteByOwnerID removes every file stored for a user and returns how many were deleted
+func (r *attachmentRepository) DeleteByOwnerID(ownerID string) (int, error) {
+	attachments, err := r.FindByOwnerID(ownerID, "")
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range attachments {
+		if err := r.Delete(&attachments[i]); err != nil {
+			return i, err
+		}
+	}
+
+	return len(attachments), nil
+}
+
+// removeObjects deletes uploaded files that could not be recorded
+func (r *attachmentRepository) removeObjects(keys []string) {
+	for _, key := range keys {
+		if err := r.storage.Delete(context.Background(), key); err != nil {
+			logger.Error("Failed to remove unrecorded upload", zap.String("key", key), zap.Error(err))
+		}
+	}
+}
+
+// countingWriter counts the bytes written to it
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
 }
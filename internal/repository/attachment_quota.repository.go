@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/amirullazmi0/kratify-backend/internal/model"
+	"github.com/amirullazmi0/kratify-backend/pkg/database"
+)
+
+// ErrQuotaExceeded is wrapped by every QuotaExceededError
+var ErrQuotaExceeded = errors.New("storage quota exceeded")
+
+// Limits a QuotaExceededError can report
+const (
+	QuotaLimitBytes          = "bytes"
+	QuotaLimitFiles          = "files"
+	QuotaLimitUploadsPerHour = "uploads_per_hour"
+)
+
+// QuotaExceededError tells which limit an upload would break; it is sent to the client as is
+type QuotaExceededError struct {
+	Limit     string `json:"limit"`
+	Used      int64  `json:"used"`
+	Max       int64  `json:"max"`
+	Requested int64  `json:"requested"`
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("%s: %s limit is %d, %d used, %d requested", ErrQuotaExceeded, e.Limit, e.Max, e.Used, e.Requested)
+}
+
+func (e *QuotaExceededError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+// CheckQuota returns a *QuotaExceededError when storing one more file of the given
+// size would break the quota. A nil quota is not enforced.
+func CheckQuota(quota *model.StorageQuota, usage *model.StorageUsage, bytes int64) error {
+	if quota == nil {
+		return nil
+	}
+
+	switch {
+	case quota.UploadsPerHour > 0 && usage.UploadsLastHour+1 > quota.UploadsPerHour:
+		return &QuotaExceededError{Limit: QuotaLimitUploadsPerHour, Used: int64(usage.UploadsLastHour), Max: int64(quota.UploadsPerHour), Requested: 1}
+	case quota.MaxFiles > 0 && usage.Files+1 > quota.MaxFiles:
+		return &QuotaExceededError{Limit: QuotaLimitFiles, Used: int64(usage.Files), Max: int64(quota.MaxFiles), Requested: 1}
+	case quota.MaxBytes > 0 && usage.Bytes+bytes > quota.MaxBytes:
+		return &QuotaExceededError{Limit: QuotaLimitBytes, Used: usage.Bytes, Max: quota.MaxBytes, Requested: bytes}
+	}
+	return nil
+}
+
+// Usage returns what a user stores, counting every attachment that is not deleted.
+// Files shared by deduplicated attachments count once towards the bytes.
+func (r *attachmentRepository) Usage(ownerID string) (*model.StorageUsage, error) {
+	return r.usage(r.db, ownerID, "")
+}
+
+// usage sums the attachments of a user, leaving out the one with exceptID
+func (r *attachmentRepository) usage(db database.Executor, ownerID string, exceptID string) (*model.StorageUsage, error) {
+	filter := `owner_id = $1`
+	args := []interface{}{ownerID, time.Now().Add(-time.Hour)}
+	if exceptID != "" {
+		filter += ` AND id <> $3`
+		args = append(args, exceptID)
+	}
+	// Uploads are counted from attachment_uploads, so deleting a file does not free up the hourly limit
+	query := `SELECT COALESCE(SUM(size) FILTER (WHERE first_of_path), 0), COUNT(*),
+			(SELECT COUNT(*) FROM attachment_uploads WHERE owner_id = $1 AND created_at > $2)
+		FROM (
+			SELECT size, ROW_NUMBER() OVER (PARTITION BY path ORDER BY created_at) = 1 AS first_of_path
+			FROM attachments WHERE ` + filter + `
+		) owned`
+
+	var usage model.StorageUsage
+	err := database.RawQueryRow(db, query, args...).Scan(&usage.Bytes, &usage.Files, &usage.UploadsLastHour)
+	if err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
+
+// withinQuota runs record once the owner's attachments plus one file of the given size
+// are found to fit the quota. The attachment with exceptID, one being completed, is not
+// counted. A nil quota is not enforced.
+func (r *attachmentRepository) withinQuota(ownerID string, exceptID string, quota *model.StorageQuota, bytes int64, record func(tx database.Executor) error) error {
+	return r.lockOwner(ownerID, func(tx database.Executor) error {
+		if err := r.checkQuota(tx, ownerID, exceptID, quota, bytes); err != nil {
+			return err
+		}
+		return record(tx)
+	})
+}
+
+// checkQuota compares the owner's usage plus one file of the given size with the quota
+func (r *attachmentRepository) checkQuota(db database.Executor, ownerID string, exceptID string, quota *model.StorageQuota, bytes int64) error {
+	if quota == nil {
+		return nil
+	}
+
+	usage, err := r.usage(db, ownerID, exceptID)
+	if err != nil {
+		return err
+	}
+	return CheckQuota(quota, usage, bytes)
+}
+
+// recordUpload counts an upload towards the owner's uploads per hour and prunes the
+// uploads that no longer count
+func (r *attachmentRepository) recordUpload(db database.Executor, ownerID string) error {
+	now := time.Now()
+	if _, err := database.RawExec(db, `DELETE FROM attachment_uploads WHERE owner_id = $1 AND created_at <= $2`, ownerID, now.Add(-time.Hour)); err != nil {
+		return err
+	}
+	_, err := database.RawExec(db, `INSERT INTO attachment_uploads (owner_id, created_at) VALUES ($1, $2)`, ownerID, now)
+	return err
+}
+
+// lockOwner runs fn in a transaction holding a lock on the owner's row, so quota checks,
+// deduplication and deletions of one user's attachments happen one after the other
+func (r *attachmentRepository) lockOwner(ownerID string, fn func(tx database.Executor) error) error {
+	return database.WithTransaction(r.db, func(tx *sql.Tx) error {
+		var id string
+		if err := database.RawQueryRow(tx, `SELECT id FROM users WHERE id = $1 FOR UPDATE`, ownerID).Scan(&id); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/amirullazmi0/kratify-backend/internal/model"
+	"github.com/amirullazmi0/kratify-backend/pkg/database"
+)
+
+// RemoveReference records that a user or product no longer uses an attachment
+func (r *attachmentRepository) RemoveReference(attachmentID string, subjectType string, subjectID string) error {
+	_, err := database.NewDeleteBuilder("attachment_references").
+		Where("attachment_id = $1", attachmentID).
+		Where("subject_type = $2", subjectType).
+		Where("subject_id = $3", subjectID).
+		HardDelete().
+		Execute(r.db)
+	return err
+}
+
+// SetReference makes the attachment the only one of its kind the subject uses, e.g.
+// a user's profile image, releasing the one it replaces
+func (r *attachmentRepository) SetReference(subjectType string, subjectID string, kind string, attachmentID string) error {
+	return database.WithTransaction(r.db, func(tx *sql.Tx) error {
+		_, err := database.RawExec(tx, `DELETE FROM attachment_references ar
+			USING attachments a
+			WHERE a.id = ar.attachment_id AND ar.subject_type = $1 AND ar.subject_id = $2 AND a.kind = $3 AND a.id <> $4`,
+			subjectType, subjectID, kind, attachmentID)
+		if err != nil {
+			return err
+		}
+
+		_, err = database.RawExec(tx, `INSERT INTO attachment_references (attachment_id, subject_type, subject_id)
+			VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`, attachmentID, subjectType, subjectID)
+		return err
+	})
+}
+
+// FindUnreferenced returns ready attachments of the given kinds created before the
+// given time that no user or product uses, oldest first
+func (r *attachmentRepository) FindUnreferenced(kinds []string, before time.Time, limit int) ([]model.Attachment, error) {
+	values := make([]interface{}, len(kinds))
+	for i, kind := range kinds {
+		values[i] = kind
+	}
+
+	query, args := database.NewQueryBuilder("attachments").
+		Select(attachmentColumns...).
+		Where("status = $1", model.AttachmentStatusReady).
+		Where("created_at < $2", before).
+		Where("NOT EXISTS (SELECT 1 FROM attachment_references ar WHERE ar.attachment_id = attachments.id)").
+		WhereIn("kind", values).
+		OrderBy("created_at ASC").
+		Limit(limit).
+		Build()
+
+	rows, err := database.RawQuery(r.db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAttachments(rows)
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/amirullazmi0/kratify-backend/internal/model"
+	"github.com/amirullazmi0/kratify-backend/pkg/database"
+)
+
+type AuditLogRepository interface {
+	Create(entry *model.AuditLog) (string, error)
+}
+
+type auditLogRepository struct {
+	db *sql.DB
+}
+
+// NewAuditLogRepository creates a new audit log repository
+func NewAuditLogRepository(db *sql.DB) AuditLogRepository {
+	return &auditLogRepository{db: db}
+}
+
+func (r *auditLogRepository) Create(entry *model.AuditLog) (string, error) {
+	var metadata interface{}
+	if entry.Metadata != nil {
+		encoded, err := json.Marshal(entry.Metadata)
+		if err != nil {
+			return "", err
+		}
+		metadata = string(encoded)
+	}
+
+	return database.NewInsertBuilder("audit_logs").
+		Set("actor_id", entry.ActorID).
+		Set("subject_user_id", entry.SubjectUserID).
+		Set("action", entry.Action).
+		Set("metadata", metadata).
+		Set("ip", nullIfEmpty(entry.IP)).
+		Set("user_agent", nullIfEmpty(entry.UserAgent)).
+		Set("request_id", nullIfEmpty(entry.RequestID)).
+		Execute(r.db)
+}
+
+func nullIfEmpty(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/amirullazmi0/kratify-backend/internal/model"
+	"github.com/amirullazmi0/kratify-backend/pkg/database"
+)
+
+type InvitationRepository interface {
+	Create(invitation *model.Invitation, user *model.User) (*model.Invitation, error)
+	FindByID(id string) (*model.Invitation, error)
+	FindPendingByTokenHash(tokenHash string) (*model.Invitation, error)
+	FindAll() ([]model.Invitation, error)
+	Renew(id string, tokenHash string, expiresAt time.Time) (*model.Invitation, error)
+	Revoke(id string) (int64, error)
+	DeleteExpiredUser(email string) (int64, error)
+	Accept(tokenHash string, name string, hashedPassword string) (string, error)
+}
+
+type invitationRepository struct {
+	db *sql.DB
+}
+
+// NewInvitationRepository creates a new invitation repository
+func NewInvitationRepository(db *sql.DB) InvitationRepository {
+	return &invitationRepository{db: db}
+}
+
+var invitationColumns = []string{"id", "user_id", "email", "role", "invited_by", "token_hash", "expires_at", "sent_count", "last_sent_at", "accepted_at", "revoked_at", "created_at", "updated_at"}
+
+const invitationReturning = `RETURNING id, user_id, email, role, invited_by, token_hash, expires_at, sent_count, last_sent_at, accepted_at, revoked_at, created_at, updated_at`
+
+func scanInvitation(scanner rowScanner) (*model.Invitation, error) {
+	var invitation model.Invitation
+	err := scanner.Scan(
+		&invitation.ID,
+		&invitation.UserID,
+		&invitation.Email,
+		&invitation.Role,
+		&invitation.InvitedBy,
+		&invitation.TokenHash,
+		&invitation.ExpiresAt,
+		&invitation.SentCount,
+		&invitation.LastSentAt,
+		&invitation.AcceptedAt,
+		&invitation.RevokedAt,
+		&invitation.CreatedAt,
+		&invitation.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// Create inserts the pending (inactive) user and its invitation in a single statement
+func (r *invitationRepository) Create(invitation *model.Invitation, user *model.User) (*model.Invitation, error) {
+	query := `WITH pending_user AS (
+			INSERT INTO users (email, password, name, role, is_active, created_by)
+			VALUES ($1, $2, $3, $4::"UserRole", false, $5)
+			RETURNING id
+		)
+		INSERT INTO invitations (user_id, email, role, invited_by, token_hash, expires_at)
+		SELECT pending_user.id, $1, $4::"UserRole", $5, $6, $7 FROM pending_user
+		` + invitationReturning
+
+	return scanInvitation(database.RawQueryRow(r.db, query,
+		user.Email,
+		user.Password,
+		user.Name,
+		invitation.Role,
+		invitation.InvitedBy,
+		invitation.TokenHash,
+		invitation.ExpiresAt,
+	))
+}
+
+func (r *invitationRepository) FindByID(id string) (*model.Invitation, error) {
+	query, args := database.NewQueryBuilder("invitations").
+		Select(invitationColumns...).
+		Where("id = $1", id).
+		Limit(1).
+		Build()
+
+	return scanInvitation(database.RawQueryRow(r.db, query, args...))
+}
+
+func (r *invitationRepository) FindPendingByTokenHash(tokenHash string) (*model.Invitation, error) {
+	query, args := database.NewQueryBuilder("invitations").
+		Select(invitationColumns...).
+		Where("token_hash = $1", tokenHash).
+		Where("accepted_at IS NULL").
+		Where("revoked_at IS NULL").
+		Where("expires_at > $2", time.Now()).
+		Limit(1).
+		Build()
+
+	return scanInvitation(database.RawQueryRow(r.db, query, args...))
+}
+
+func (r *invitationRepository) FindAll() ([]model.Invitation, error) {
+	query, args := database.NewQueryBuilder("invitations").
+		Select(invitationColumns...).
+		OrderBy("created_at DESC").
+		Build()
+
+	rows, err := database.RawQuery(r.db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []model.Invitation{}
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, *invitation)
+	}
+
+	return invitations, rows.Err()
+}
+
+// Renew rotates the token of an open (not accepted or revoked) invitation and extends its expiry.
+// It returns sql.ErrNoRows when the invitation is unknown, accepted or revoked, or its
+// pending user was removed.
+func (r *invitationRepository) Renew(id string, tokenHash string, expiresAt time.Time) (*model.Invitation, error) {
+	query := `UPDATE invitations
+		SET token_hash = $2, expires_at = $3, sent_count = sent_count + 1, last_sent_at = $4, updated_at = $4
+		WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL AND user_id IS NOT NULL
+		` + invitationReturning
+
+	return scanInvitation(database.RawQueryRow(r.db, query, id, tokenHash, expiresAt, time.Now()))
+}
+
+// Revoke marks an open invitation as revoked and removes the pending user it created,
+// freeing the email address for a new invitation or registration
+func (r *invitationRepository) Revoke(id string) (int64, error) {
+	query := `WITH open_invitation AS (
+			SELECT id, user_id FROM invitations
+			WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL
+			FOR UPDATE
+		), revoked AS (
+			UPDATE invitations SET revoked_at = $2, updated_at = $2
+			FROM open_invitation WHERE invitations.id = open_invitation.id
+			RETURNING invitations.id
+		), removed_user AS (
+			DELETE FROM users USING open_invitation
+			WHERE users.id = open_invitation.user_id AND users.is_active = false
+		)
+		SELECT COUNT(*) FROM revoked`
+
+	var affected int64
+	err := database.RawQueryRow(r.db, query, id, time.Now()).Scan(&affected)
+	return affected, err
+}
+
+// DeleteExpiredUser removes the pending user left by an invitation to the email that
+// expired without being accepted or revoked, so that the email can be invited again.
+// The invitation itself is kept and stays expired.
+func (r *invitationRepository) DeleteExpiredUser(email string) (int64, error) {
+	query := `DELETE FROM users USING invitations
+		WHERE users.email = $1 AND users.is_active = false
+			AND invitations.user_id = users.id
+			AND invitations.accepted_at IS NULL AND invitations.revoked_at IS NULL
+			AND invitations.expires_at <= $2`
+
+	result, err := database.RawExec(r.db, query, email, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Accept consumes a pending invitation, sets the invitee's name and password and activates the account.
+// It returns sql.ErrNoRows when the token is unknown, accepted, revoked or expired, or its pending
+// user no longer exists; the invitation is then left untouched.
+func (r *invitationRepository) Accept(tokenHash string, name string, hashedPassword string) (string, error) {
+	query := `WITH accepted AS (
+			UPDATE invitations SET accepted_at = $1, updated_at = $1
+			FROM users
+			WHERE invitations.token_hash = $2 AND invitations.accepted_at IS NULL
+				AND invitations.revoked_at IS NULL AND invitations.expires_at > $1
+				AND users.id = invitations.user_id AND users.deleted_at IS NULL AND users.is_active = false
+			RETURNING invitations.user_id
+		)
+		UPDATE users
+		SET name = COALESCE(NULLIF($3, ''), users.name), password = $4, is_active = true,
+			verification_token = NULL, verification_expiry = NULL, updated_at = $1
+		FROM accepted
+		WHERE users.id = accepted.user_id
+		RETURNING users.id`
+
+	var userID string
+	err := database.RawQueryRow(r.db, query, time.Now(), tokenHash, name, hashedPassword).Scan(&userID)
+	return userID, err
+}
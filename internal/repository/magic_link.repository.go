@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/amirullazmi0/kratify-backend/internal/model"
+	"github.com/amirullazmi0/kratify-backend/pkg/database"
+)
+
+type MagicLinkRepository interface {
+	Create(token *model.MagicLinkToken) (string, error)
+	Consume(tokenHash string, nonceHash string) (*model.MagicLinkToken, error)
+}
+
+type magicLinkRepository struct {
+	db *sql.DB
+}
+
+// NewMagicLinkRepository creates a new magic link token repository
+func NewMagicLinkRepository(db *sql.DB) MagicLinkRepository {
+	return &magicLinkRepository{db: db}
+}
+
+func (r *magicLinkRepository) Create(token *model.MagicLinkToken) (string, error) {
+	return database.NewInsertBuilder("magic_link_tokens").
+		Set("user_id", token.UserID).
+		Set("token_hash", token.TokenHash).
+		Set("nonce_hash", token.NonceHash).
+		Set("expires_at", token.ExpiresAt).
+		Execute(r.db)
+}
+
+// Consume atomically marks an unused, unexpired token as used and returns it.
+// It returns sql.ErrNoRows when the token is unknown, used, expired or bound to another nonce.
+func (r *magicLinkRepository) Consume(tokenHash string, nonceHash string) (*model.MagicLinkToken, error) {
+	query := `UPDATE magic_link_tokens SET used_at = $1
+		WHERE token_hash = $2 AND nonce_hash = $3 AND used_at IS NULL AND expires_at > $1
+		RETURNING id, user_id, token_hash, nonce_hash, expires_at, used_at, created_at`
+
+	var token model.MagicLinkToken
+	err := database.RawQueryRow(r.db, query, time.Now(), tokenHash, nonceHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.TokenHash,
+		&token.NonceHash,
+		&token.ExpiresAt,
+		&token.UsedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/amirullazmi0/kratify-backend/internal/model"
+	"github.com/amirullazmi0/kratify-backend/pkg/database"
+	"github.com/amirullazmi0/kratify-backend/pkg/regions"
+	"github.com/lib/pq"
+)
+
+type RegionRepository interface {
+	FindByCode(code string) (*model.Region, error)
+	FindByLevel(level string) ([]model.Region, error)
+	FindChildren(parentCode string) ([]model.Region, error)
+	FindWithAncestors(code string) ([]model.Region, error)
+	FindByCodes(codes []string) ([]model.Region, error)
+	FindByPostalCode(postalCode string) ([]model.Region, error)
+	Search(query string, levels []string, limit int) ([]model.Region, error)
+	Upsert(records []regions.Record) error
+}
+
+type regionRepository struct {
+	db *sql.DB
+}
+
+// NewRegionRepository creates a new region repository
+func NewRegionRepository(db *sql.DB) RegionRepository {
+	return &regionRepository{db: db}
+}
+
+var regionColumns = []string{"code", "parent_code", "level", "name", "postal_code", "full_name", "latitude", "longitude", "created_at", "updated_at"}
+
+func scanRegion(scanner rowScanner) (*model.Region, error) {
+	var region model.Region
+	err := scanner.Scan(
+		&region.Code,
+		&region.ParentCode,
+		&region.Level,
+		&region.Name,
+		&region.PostalCode,
+		&region.FullName,
+		&region.Latitude,
+		&region.Longitude,
+		&region.CreatedAt,
+		&region.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &region, nil
+}
+
+func scanRegions(rows *sql.Rows) ([]model.Region, error) {
+	defer rows.Close()
+
+	var result []model.Region
+	for rows.Next() {
+		region, err := scanRegion(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *region)
+	}
+
+	return result, rows.Err()
+}
+
+func (r *regionRepository) FindByCode(code string) (*model.Region, error) {
+	query, args := database.NewQueryBuilder("regions").
+		Select(regionColumns...).
+		Where("code = $1", code).
+		Limit(1).
+		Build()
+
+	return scanRegion(database.RawQueryRow(r.db, query, args...))
+}
+
+func (r *regionRepository) FindByLevel(level string) ([]model.Region, error) {
+	query, args := database.NewQueryBuilder("regions").
+		Select(regionColumns...).
+		Where("level = $1", level).
+		OrderBy("name ASC").
+		Build()
+
+	rows, err := database.RawQuery(r.db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanRegions(rows)
+}
+
+func (r *regionRepository) FindChildren(parentCode string) ([]model.Region, error) {
+	query, args := database.NewQueryBuilder("regions").
+		Select(regionColumns...).
+		Where("parent_code = $1", parentCode).
+		OrderBy("name ASC").
+		Build()
+
+	rows, err := database.RawQuery(r.db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanRegions(rows)
+}
+
+// FindWithAncestors returns the region and all of its ancestors, province first
+func (r *regionRepository) FindWithAncestors(code string) ([]model.Region, error) {
+	query := `WITH RECURSIVE lineage AS (
+			SELECT code, parent_code, level, name, postal_code, full_name, latitude, longitude, created_at, updated_at, 0 AS depth
+			FROM regions WHERE code = $1
+			UNION ALL
+			SELECT p.code, p.parent_code, p.level, p.name, p.postal_code, p.full_name, p.latitude, p.longitude, p.created_at, p.updated_at, l.depth + 1
+			FROM regions p JOIN lineage l ON p.code = l.parent_code
+		)
+		SELECT code, parent_code, level, name, postal_code, full_name, latitude, longitude, created_at, updated_at
+		FROM lineage ORDER BY depth DESC`
+
+	rows, err := database.RawQuery(r.db, query, code)
+	if err != nil {
+		return nil, err
+	}
+	return scanRegions(rows)
+}
+
+func (r *regionRepository) FindByCodes(codes []string) ([]model.Region, error) {
+	if len(codes) == 0 {
+		return nil, nil
+	}
+
+	values := make([]interface{}, len(codes))
+	for i, code := range codes {
+		values[i] = code
+	}
+
+	query, args := database.NewQueryBuilder("regions").
+		Select(regionColumns...).
+		WhereIn("code", values).
+		OrderBy("code ASC").
+		Build()
+
+	rows, err := database.RawQuery(r.db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanRegions(rows)
+}
+
+// FindByPostalCode returns the villages that use the postal code
+func (r *regionRepository) FindByPostalCode(postalCode string) ([]model.Region, error) {
+	query, args := database.NewQueryBuilder("regions").
+		Select(regionColumns...).
+		Where("postal_code = $1", postalCode).
+		OrderBy("code ASC").
+		Build()
+
+	rows, err := database.RawQuery(r.db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanRegions(rows)
+}
+
+// likeEscaper escapes the LIKE wildcards, so that "%" and "_" in a query match themselves
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Search matches the query against the full region names (region and ancestors),
+// ignoring case and accents. Substring matches rank first, followed by fuzzy
+// trigram matches, so both prefixes and typos find the region.
+func (r *regionRepository) Search(query string, levels []string, limit int) ([]model.Region, error) {
+	sqlQuery := `SELECT code, parent_code, level, name, postal_code, full_name, latitude, longitude, created_at, updated_at
+		FROM regions
+		WHERE level::TEXT = ANY($2)
+			AND (f_unaccent(lower(full_name)) LIKE '%' || f_unaccent(lower($4)) || '%' ESCAPE '\'
+				OR f_unaccent(lower($1)) <% f_unaccent(lower(full_name)))
+		ORDER BY
+			f_unaccent(lower(name)) LIKE f_unaccent(lower($4)) || '%' ESCAPE '\' DESC,
+			word_similarity(f_unaccent(lower($1)), f_unaccent(lower(full_name))) DESC,
+			length(full_name) ASC
+		LIMIT $3`
+
+	rows, err := database.RawQuery(r.db, sqlQuery, query, pq.Array(levels), limit, likeEscaper.Replace(query))
+	if err != nil {
+		return nil, err
+	}
+	return scanRegions(rows)
+}
+
+// Upsert inserts or updates the records in one transaction. Parents must come
+// before their children, as returned by regions.Load.
+func (r *regionRepository) Upsert(records []regions.Record) error {
+	return database.WithTransaction(r.db, func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare(`INSERT INTO regions (code, parent_code, level, name, postal_code, full_name, latitude, longitude)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (code) DO UPDATE SET
+				parent_code = EXCLUDED.parent_code,
+				level = EXCLUDED.level,
+				name = EXCLUDED.name,
+				postal_code = EXCLUDED.postal_code,
+				full_name = EXCLUDED.full_name,
+				latitude = EXCLUDED.latitude,
+				longitude = EXCLUDED.longitude,
+				updated_at = CURRENT_TIMESTAMP
+			WHERE (regions.parent_code, regions.level, regions.name, regions.postal_code, regions.full_name, regions.latitude, regions.longitude)
+				IS DISTINCT FROM (EXCLUDED.parent_code, EXCLUDED.level, EXCLUDED.name, EXCLUDED.postal_code, EXCLUDED.full_name, EXCLUDED.latitude, EXCLUDED.longitude)`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		for _, record := range records {
+			_, err := stmt.Exec(
+				record.Code,
+				nullIfEmpty(record.ParentCode),
+				record.Level,
+				record.Name,
+				nullIfEmpty(record.PostalCode),
+				record.FullName,
+				record.Latitude,
+				record.Longitude,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to upsert region %s: %w", record.Code, err)
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/amirullazmi0/kratify-backend/internal/model"
+	"github.com/amirullazmi0/kratify-backend/pkg/database"
+)
+
+type UserIdentityRepository interface {
+	Create(identity *model.UserIdentity) (string, error)
+	FindByProviderSubject(provider string, subject string) (*model.UserIdentity, error)
+	FindByUserID(userID string) ([]model.UserIdentity, error)
+}
+
+type userIdentityRepository struct {
+	db *sql.DB
+}
+
+// NewUserIdentityRepository creates a new user identity repository
+func NewUserIdentityRepository(db *sql.DB) UserIdentityRepository {
+	return &userIdentityRepository{db: db}
+}
+
+var userIdentityColumns = []string{"id", "user_id", "provider", "subject", "email", "created_at", "updated_at"}
+
+func scanUserIdentity(scanner rowScanner) (*model.UserIdentity, error) {
+	var identity model.UserIdentity
+	err := scanner.Scan(
+		&identity.ID,
+		&identity.UserID,
+		&identity.Provider,
+		&identity.Subject,
+		&identity.Email,
+		&identity.CreatedAt,
+		&identity.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *userIdentityRepository) Create(identity *model.UserIdentity) (string, error) {
+	return database.NewInsertBuilder("user_identities").
+		Set("user_id", identity.UserID).
+		Set("provider", identity.Provider).
+		Set("subject", identity.Subject).
+		Set("email", identity.Email).
+		Execute(r.db)
+}
+
+func (r *userIdentityRepository) FindByProviderSubject(provider string, subject string) (*model.UserIdentity, error) {
+	query, args := database.NewQueryBuilder("user_identities").
+		Select(userIdentityColumns...).
+		Where("provider = $1", provider).
+		Where("subject = $2", subject).
+		Limit(1).
+		Build()
+
+	return scanUserIdentity(database.RawQueryRow(r.db, query, args...))
+}
+
+func (r *userIdentityRepository) FindByUserID(userID string) ([]model.UserIdentity, error) {
+	query, args := database.NewQueryBuilder("user_identities").
+		Select(userIdentityColumns...).
+		Where("user_id = $1", userID).
+		OrderBy("created_at ASC").
+		Build()
+
+	rows, err := database.RawQuery(r.db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var identities []model.UserIdentity
+	for rows.Next() {
+		identity, err := scanUserIdentity(rows)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, *identity)
+	}
+
+	return identities, rows.Err()
+}
@@ -21,6 +21,14 @@ type UserRepository interface {
 	SaveVerificationToken(userID string, token string, expiresAt time.Time) error
 	VerifyEmail(userID string) error
 	Delete(id string) error
+	ScheduleDeletion(userID string, scheduledAt time.Time) error
+	CancelDeletion(userID string) (int64, error)
+	FindDueForErasure(now time.Time, limit int) ([]string, error)
+	Erase(id string) error
+	Deactivate(userID string, tokenHash string, reactivationExpiry time.Time) error
+	SaveReactivationToken(userID string, tokenHash string) error
+	Reactivate(tokenHash string) (string, error)
+	UpdateAvatar(userID string, attachmentID *string) error
 }
 
 type userRepository struct {
@@ -32,26 +40,11 @@ func NewUserRepository(db *sql.DB) UserRepository {
 	return &userRepository{db: db}
 }
 
-func (r *userRepository) Create(user *model.User) (string, error) {
-	id, err := database.NewInsertBuilder("users").
-		Set("email", user.Email).
-		Set("password", user.Password).
-		Set("name", user.Name).
-		Execute(r.db)
-
-	return id, err
-}
-
-func (r *userRepository) FindByID(id string) (*model.User, error) {
-	query, args := database.NewQueryBuilder("users").
-		Select("id", "email", "password", "name", "role", "refresh_token", "token_expiry", "verification_token", "verification_expiry", "is_active", "created_at", "updated_at", "deleted_at", "created_by", "updated_by", "deleted_by").
-		Where("id = $1", id).
-		Where("deleted_at IS NULL").
-		Limit(1).
-		Build()
+var userColumns = []string{"id", "email", "password", "name", "role", "refresh_token", "token_expiry", "verification_token", "verification_expiry", "is_active", "deletion_requested_at", "deletion_scheduled_at", "deactivated_at", "sessions_revoked_at", "reactivation_token_hash", "reactivation_expiry", "avatar_id", "created_at", "updated_at", "deleted_at", "created_by", "updated_by", "deleted_by"}
 
+func scanUser(scanner rowScanner) (*model.User, error) {
 	var user model.User
-	err := database.RawQueryRow(r.db, query, args...).Scan(
+	err := scanner.Scan(
 		&user.ID,
 		&user.Email,
 		&user.Password,
@@ -62,6 +55,13 @@ func (r *userRepository) FindByID(id string) (*model.User, error) {
 		&user.VerificationToken,
 		&user.VerificationExpiry,
 		&user.IsActive,
+		&user.DeletionRequestedAt,
+		&user.DeletionScheduledAt,
+		&user.DeactivatedAt,
+		&user.SessionsRevokedAt,
+		&user.ReactivationTokenHash,
+		&user.ReactivationExpiry,
+		&user.AvatarID,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.DeletedAt,
@@ -69,58 +69,48 @@ func (r *userRepository) FindByID(id string) (*model.User, error) {
 		&user.UpdatedBy,
 		&user.DeletedBy,
 	)
-
-	if err == sql.ErrNoRows {
-		return nil, sql.ErrNoRows
-	}
 	if err != nil {
 		return nil, err
 	}
-
 	return &user, nil
 }
 
-func (r *userRepository) FindByEmail(email string) (*model.User, error) {
+func (r *userRepository) Create(user *model.User) (string, error) {
+	id, err := database.NewInsertBuilder("users").
+		Set("email", user.Email).
+		Set("password", user.Password).
+		Set("name", user.Name).
+		Set("is_active", user.IsActive).
+		Execute(r.db)
+
+	return id, err
+}
+
+func (r *userRepository) FindByID(id string) (*model.User, error) {
 	query, args := database.NewQueryBuilder("users").
-		Select("id", "email", "password", "name", "role", "refresh_token", "token_expiry", "verification_token", "verification_expiry", "is_active", "created_at", "updated_at", "deleted_at", "created_by", "updated_by", "deleted_by").
-		Where("email = $1", email).
+		Select(userColumns...).
+		Where("id = $1", id).
 		Where("deleted_at IS NULL").
 		Limit(1).
 		Build()
 
-	var user model.User
-	err := database.RawQueryRow(r.db, query, args...).Scan(
-		&user.ID,
-		&user.Email,
-		&user.Password,
-		&user.Name,
-		&user.Role,
-		&user.RefreshToken,
-		&user.TokenExpiry,
-		&user.VerificationToken,
-		&user.VerificationExpiry,
-		&user.IsActive,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-		&user.DeletedAt,
-		&user.CreatedBy,
-		&user.UpdatedBy,
-		&user.DeletedBy,
-	)
+	return scanUser(database.RawQueryRow(r.db, query, args...))
+}
 
-	if err == sql.ErrNoRows {
-		return nil, sql.ErrNoRows
-	}
-	if err != nil {
-		return nil, err
-	}
+func (r *userRepository) FindByEmail(email string) (*model.User, error) {
+	query, args := database.NewQueryBuilder("users").
+		Select(userColumns...).
+		Where("email = $1", email).
+		Where("deleted_at IS NULL").
+		Limit(1).
+		Build()
 
-	return &user, nil
+	return scanUser(database.RawQueryRow(r.db, query, args...))
 }
 
 func (r *userRepository) FindAll() ([]model.User, error) {
 	query, args := database.NewQueryBuilder("users").
-		Select("id", "email", "password", "name", "role", "refresh_token", "token_expiry", "verification_token", "verification_expiry", "is_active", "created_at", "updated_at", "deleted_at", "created_by", "updated_by", "deleted_by").
+		Select(userColumns...).
 		Where("deleted_at IS NULL").
 		OrderBy("created_at DESC").
 		Build()
@@ -133,29 +123,11 @@ func (r *userRepository) FindAll() ([]model.User, error) {
 
 	var users []model.User
 	for rows.Next() {
-		var user model.User
-		err := rows.Scan(
-			&user.ID,
-			&user.Email,
-			&user.Password,
-			&user.Name,
-			&user.Role,
-			&user.RefreshToken,
-			&user.TokenExpiry,
-			&user.VerificationToken,
-			&user.VerificationExpiry,
-			&user.IsActive,
-			&user.CreatedAt,
-			&user.UpdatedAt,
-			&user.DeletedAt,
-			&user.CreatedBy,
-			&user.UpdatedBy,
-			&user.DeletedBy,
-		)
+		user, err := scanUser(rows)
 		if err != nil {
 			return nil, err
 		}
-		users = append(users, user)
+		users = append(users, *user)
 	}
 
 	return users, nil
@@ -172,11 +144,15 @@ func (r *userRepository) Update(user *model.User) error {
 	return err
 }
 
+// Delete soft-deletes the user. The email address can be registered again right away,
+// as it is only unique among users that are not deleted; the deleted record is kept.
 func (r *userRepository) Delete(id string) error {
-	// Soft delete
+	now := time.Now()
 	_, err := database.NewUpdateBuilder("users").
-		Set("deleted_at", time.Now()).
-		Set("updated_at", time.Now()).
+		Set("deleted_at", now).
+		Set("refresh_token", nil).
+		Set("token_expiry", nil).
+		Set("updated_at", now).
 		Where("id = $1", id).
 		Execute(r.db)
 
@@ -230,78 +206,137 @@ func (r *userRepository) VerifyEmail(userID string) error {
 
 func (r *userRepository) FindByVerificationToken(token string) (*model.User, error) {
 	query, args := database.NewQueryBuilder("users").
-		Select("id", "email", "password", "name", "role", "refresh_token", "token_expiry", "verification_token", "verification_expiry", "is_active", "created_at", "updated_at", "deleted_at", "created_by", "updated_by", "deleted_by").
+		Select(userColumns...).
 		Where("verification_token = $1", token).
 		Where("deleted_at IS NULL").
 		Where("verification_expiry > $2", time.Now()).
 		Limit(1).
 		Build()
 
-	var user model.User
-	err := r.db.QueryRow(query, args...).Scan(
-		&user.ID,
-		&user.Email,
-		&user.Password,
-		&user.Name,
-		&user.Role,
-		&user.RefreshToken,
-		&user.TokenExpiry,
-		&user.VerificationToken,
-		&user.VerificationExpiry,
-		&user.IsActive,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-		&user.DeletedAt,
-		&user.CreatedBy,
-		&user.UpdatedBy,
-		&user.DeletedBy,
-	)
-
-	if err == sql.ErrNoRows {
-		return nil, sql.ErrNoRows
-	}
-	if err != nil {
-		return nil, err
-	}
-
-	return &user, nil
+	return scanUser(r.db.QueryRow(query, args...))
 }
 
 func (r *userRepository) FindByRefreshToken(refreshToken string) (*model.User, error) {
 	query, args := database.NewQueryBuilder("users").
-		Select("id", "email", "password", "name", "role", "refresh_token", "token_expiry", "verification_token", "verification_expiry", "is_active", "created_at", "updated_at", "deleted_at", "created_by", "updated_by", "deleted_by").
+		Select(userColumns...).
 		Where("refresh_token = $1", refreshToken).
 		Where("deleted_at IS NULL").
 		Where("token_expiry > $2", time.Now()).
 		Limit(1).
 		Build()
 
-	var user model.User
-	err := r.db.QueryRow(query, args...).Scan(
-		&user.ID,
-		&user.Email,
-		&user.Password,
-		&user.Name,
-		&user.Role,
-		&user.RefreshToken,
-		&user.TokenExpiry,
-		&user.VerificationToken,
-		&user.VerificationExpiry,
-		&user.IsActive,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-		&user.DeletedAt,
-		&user.CreatedBy,
-		&user.UpdatedBy,
-		&user.DeletedBy,
-	)
+	return scanUser(r.db.QueryRow(query, args...))
+}
 
-	if err == sql.ErrNoRows {
-		return nil, sql.ErrNoRows
-	}
+func (r *userRepository) ScheduleDeletion(userID string, scheduledAt time.Time) error {
+	now := time.Now()
+	_, err := database.NewUpdateBuilder("users").
+		Set("deletion_requested_at", now).
+		Set("deletion_scheduled_at", scheduledAt).
+		Set("updated_at", now).
+		Where("id = $1", userID).
+		Execute(r.db)
+
+	return err
+}
+
+// CancelDeletion clears a pending deletion request; soft-deleted users cannot be restored this way
+func (r *userRepository) CancelDeletion(userID string) (int64, error) {
+	return database.NewUpdateBuilder("users").
+		Set("deletion_requested_at", nil).
+		Set("deletion_scheduled_at", nil).
+		Set("updated_at", time.Now()).
+		Where("id = $1", userID).
+		Where("deleted_at IS NULL").
+		Where("deletion_scheduled_at IS NOT NULL").
+		Execute(r.db)
+}
+
+// FindDueForErasure returns IDs of users whose deletion grace period has ended
+func (r *userRepository) FindDueForErasure(now time.Time, limit int) ([]string, error) {
+	query, args := database.NewQueryBuilder("users").
+		Select("id").
+		Where("deletion_scheduled_at <= $1", now).
+		OrderBy("deletion_scheduled_at ASC").
+		Limit(limit).
+		Build()
+
+	rows, err := database.RawQuery(r.db, query, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	return &user, nil
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// Erase permanently removes the user row; addresses, API keys, identities and
+// tokens are removed by ON DELETE CASCADE
+func (r *userRepository) Erase(id string) error {
+	_, err := database.NewDeleteBuilder("users").
+		Where("id = $1", id).
+		HardDelete().
+		Execute(r.db)
+
+	return err
+}
+
+// Deactivate marks the account deactivated, revokes its sessions and schedules erasure
+// for when the reactivation window closes (or earlier, if deletion was already requested)
+func (r *userRepository) Deactivate(userID string, tokenHash string, reactivationExpiry time.Time) error {
+	query := `UPDATE users
+		SET deactivated_at = $1, sessions_revoked_at = $1, refresh_token = NULL, token_expiry = NULL,
+			reactivation_token_hash = $2, reactivation_expiry = $3,
+			deletion_scheduled_at = LEAST(COALESCE(deletion_scheduled_at, $3), $3),
+			updated_at = $1
+		WHERE id = $4 AND deleted_at IS NULL`
+
+	_, err := database.RawExec(r.db, query, time.Now(), tokenHash, reactivationExpiry, userID)
+	return err
+}
+
+func (r *userRepository) SaveReactivationToken(userID string, tokenHash string) error {
+	_, err := database.NewUpdateBuilder("users").
+		Set("reactivation_token_hash", tokenHash).
+		Set("updated_at", time.Now()).
+		Where("id = $1", userID).
+		Execute(r.db)
+
+	return err
+}
+
+// Reactivate restores a deactivated account if the token is valid and the window is still open.
+// The erasure scheduled by deactivation is cancelled; an explicit deletion request is kept.
+// It returns sql.ErrNoRows when the token is unknown or expired.
+func (r *userRepository) Reactivate(tokenHash string) (string, error) {
+	query := `UPDATE users
+		SET deactivated_at = NULL, reactivation_token_hash = NULL, reactivation_expiry = NULL,
+			deletion_scheduled_at = CASE WHEN deletion_requested_at IS NULL THEN NULL ELSE deletion_scheduled_at END,
+			updated_at = $1
+		WHERE reactivation_token_hash = $2 AND reactivation_expiry > $1
+			AND deactivated_at IS NOT NULL AND deleted_at IS NULL
+		RETURNING id`
+
+	var userID string
+	err := database.RawQueryRow(r.db, query, time.Now(), tokenHash).Scan(&userID)
+	return userID, err
+}
+
+// UpdateAvatar sets the profile image shown for the user; nil restores the generated one
+func (r *userRepository) UpdateAvatar(userID string, attachmentID *string) error {
+	_, err := database.NewUpdateBuilder("users").
+		Set("avatar_id", attachmentID).
+		Set("updated_at", time.Now()).
+		Where("id = $1", userID).
+		Execute(r.db)
+	return err
 }
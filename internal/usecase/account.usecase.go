@@ -0,0 +1,389 @@
+package usecase
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/amirullazmi0/kratify-backend/config"
+	"github.com/amirullazmi0/kratify-backend/internal/dto"
+	"github.com/amirullazmi0/kratify-backend/internal/model"
+	"github.com/amirullazmi0/kratify-backend/internal/repository"
+	"github.com/amirullazmi0/kratify-backend/pkg/email"
+	"github.com/amirullazmi0/kratify-backend/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// defaultDeletionGraceDays applies when ACCOUNT_DELETION_GRACE_DAYS is not set
+const defaultDeletionGraceDays = 30
+
+// defaultReactivationDays applies when ACCOUNT_REACTIVATION_DAYS is not set
+const defaultReactivationDays = 30
+
+// erasureBatchSize caps how many accounts one purge run erases
+const erasureBatchSize = 100
+
+type AccountUsecase interface {
+	ExportData(userID string) ([]byte, error)
+	RequestDeletion(userID string, req *dto.AccountDeletionRequest, meta dto.RequestMetadata) (*dto.AccountDeletionResponse, error)
+	CancelDeletion(userID string, meta dto.RequestMetadata) error
+	PurgeDueAccounts(ctx context.Context) error
+	Deactivate(userID string, req *dto.DeactivateAccountRequest, meta dto.RequestMetadata) (*dto.DeactivateAccountResponse, error)
+	RequestReactivation(req *dto.ReactivationRequest) error
+	Reactivate(token string, meta dto.RequestMetadata) error
+	CheckSession(userID string, issuedAt time.Time) error
+}
+
+type accountUsecase struct {
+	userRepo         repository.UserRepository
+	addressRepo      repository.AddressRepository
+	attachmentRepo   repository.AttachmentRepository
+	apiKeyRepo       repository.APIKeyRepository
+	userIdentityRepo repository.UserIdentityRepository
+	auditLogRepo     repository.AuditLogRepository
+	emailService     *email.EmailService
+	accountCfg       *config.AccountConfig
+	appConfig        *config.AppConfig
+}
+
+// NewAccountUsecase creates a new account (data export and erasure) usecase
+func NewAccountUsecase(userRepo repository.UserRepository, addressRepo repository.AddressRepository, attachmentRepo repository.AttachmentRepository, apiKeyRepo repository.APIKeyRepository, userIdentityRepo repository.UserIdentityRepository, auditLogRepo repository.AuditLogRepository, emailService *email.EmailService, accountCfg *config.AccountConfig, appConfig *config.AppConfig) AccountUsecase {
+	return &accountUsecase{
+		userRepo:         userRepo,
+		addressRepo:      addressRepo,
+		attachmentRepo:   attachmentRepo,
+		apiKeyRepo:       apiKeyRepo,
+		userIdentityRepo: userIdentityRepo,
+		auditLogRepo:     auditLogRepo,
+		emailService:     emailService,
+		accountCfg:       accountCfg,
+		appConfig:        appConfig,
+	}
+}
+
+// ExportData builds a ZIP archive with the user's profile, addresses and their history, attachments metadata and sessions as JSON
+func (u *accountUsecase) ExportData(userID string) ([]byte, error) {
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+
+	addresses, err := u.addressRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	addressHistory, err := u.addressRepo.FindVersionsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	attachments, err := u.attachmentRepo.FindByOwnerID(userID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := u.exportSessions(user)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", user},
+		{"addresses.json", addresses},
+		{"address_history.json", addressHistory},
+		{"attachments.json", attachments},
+		{"sessions.json", sessions},
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, file := range files {
+		w, err := archive.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// RequestDeletion schedules the account for erasure after the grace period
+func (u *accountUsecase) RequestDeletion(userID string, req *dto.AccountDeletionRequest, meta dto.RequestMetadata) (*dto.AccountDeletionResponse, error) {
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+
+	if err := user.ComparePassword(req.Password); err != nil {
+		return nil, errors.New("invalid password")
+	}
+
+	if user.DeletionScheduledAt != nil {
+		return nil, errors.New("account deletion has already been requested")
+	}
+
+	graceDays := u.accountCfg.DeletionGraceDays
+	if graceDays <= 0 {
+		graceDays = defaultDeletionGraceDays
+	}
+	requestedAt := time.Now()
+	scheduledAt := requestedAt.AddDate(0, 0, graceDays)
+
+	if err := u.userRepo.ScheduleDeletion(userID, scheduledAt); err != nil {
+		return nil, err
+	}
+
+	u.audit(model.AuditActionDeletionRequested, &userID, userID, meta, map[string]interface{}{
+		"scheduled_at": scheduledAt.Format("2006-01-02T15:04:05Z07:00"),
+	})
+
+	return &dto.AccountDeletionResponse{
+		RequestedAt: requestedAt.Format("2006-01-02T15:04:05Z07:00"),
+		ScheduledAt: scheduledAt.Format("2006-01-02T15:04:05Z07:00"),
+	}, nil
+}
+
+func (u *accountUsecase) CancelDeletion(userID string, meta dto.RequestMetadata) error {
+	affected, err := u.userRepo.CancelDeletion(userID)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("no pending account deletion")
+	}
+
+	u.audit(model.AuditActionDeletionCancelled, &userID, userID, meta, nil)
+	return nil
+}
+
+// PurgeDueAccounts erases accounts whose grace period has ended: stored files are
+// deleted first, then the user row and everything that cascades from it.
+// An account whose files cannot be deleted is left for the next run.
+func (u *accountUsecase) PurgeDueAccounts(ctx context.Context) error {
+	ids, err := u.userRepo.FindDueForErasure(time.Now(), erasureBatchSize)
+	if err != nil {
+		return err
+	}
+
+	erased := 0
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			break
+		}
+
+		deletedFiles, err := u.attachmentRepo.DeleteByOwnerID(id)
+		if err != nil {
+			logger.Error("Failed to delete files of erased account", zap.String("user_id", id), zap.Error(err))
+			continue
+		}
+
+		if err := u.userRepo.Erase(id); err != nil {
+			logger.Error("Failed to erase account", zap.String("user_id", id), zap.Error(err))
+			continue
+		}
+
+		// Erasure is carried out by the system, so no actor is recorded
+		u.audit(model.AuditActionAccountErased, nil, id, dto.RequestMetadata{}, map[string]interface{}{
+			"deleted_files": deletedFiles,
+		})
+		erased++
+	}
+
+	if erased > 0 {
+		logger.Info("Erased accounts", zap.Int("count", erased))
+	}
+
+	return nil
+}
+
+// Deactivate signs the user out everywhere, blocks login and emails a reactivation link.
+// If the account is not reactivated within the window it becomes eligible for erasure.
+func (u *accountUsecase) Deactivate(userID string, req *dto.DeactivateAccountRequest, meta dto.RequestMetadata) (*dto.DeactivateAccountResponse, error) {
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+
+	if err := user.ComparePassword(req.Password); err != nil {
+		return nil, errors.New("invalid password")
+	}
+
+	if user.IsDeactivated() {
+		return nil, errors.New("account is already deactivated")
+	}
+
+	reactivationDays := u.accountCfg.ReactivationDays
+	if reactivationDays <= 0 {
+		reactivationDays = defaultReactivationDays
+	}
+	deactivatedAt := time.Now()
+	reactivatableUntil := deactivatedAt.AddDate(0, 0, reactivationDays)
+
+	token, err := email.GenerateVerificationToken()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.userRepo.Deactivate(userID, hashToken(token), reactivatableUntil); err != nil {
+		return nil, err
+	}
+
+	u.audit(model.AuditActionAccountDeactivated, &userID, userID, meta, map[string]interface{}{
+		"reactivatable_until": reactivatableUntil.Format("2006-01-02T15:04:05Z07:00"),
+	})
+
+	u.sendReactivationLink(user, token, reactivatableUntil)
+
+	return &dto.DeactivateAccountResponse{
+		DeactivatedAt:      deactivatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		ReactivatableUntil: reactivatableUntil.Format("2006-01-02T15:04:05Z07:00"),
+	}, nil
+}
+
+// RequestReactivation emails a new reactivation link (invalidating the previous one) while the window is open.
+// It succeeds silently for unknown or active accounts so the response does not reveal account state.
+func (u *accountUsecase) RequestReactivation(req *dto.ReactivationRequest) error {
+	user, err := u.userRepo.FindByEmail(req.Email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	if !user.IsDeactivated() || user.ReactivationExpiry == nil || !user.ReactivationExpiry.After(time.Now()) {
+		return nil
+	}
+
+	token, err := email.GenerateVerificationToken()
+	if err != nil {
+		return err
+	}
+
+	if err := u.userRepo.SaveReactivationToken(user.ID, hashToken(token)); err != nil {
+		return err
+	}
+
+	u.sendReactivationLink(user, token, *user.ReactivationExpiry)
+	return nil
+}
+
+func (u *accountUsecase) Reactivate(token string, meta dto.RequestMetadata) error {
+	if token == "" {
+		return errors.New("invalid or expired reactivation link")
+	}
+
+	userID, err := u.userRepo.Reactivate(hashToken(token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("invalid or expired reactivation link")
+		}
+		return err
+	}
+
+	u.audit(model.AuditActionAccountReactivated, &userID, userID, meta, nil)
+	return nil
+}
+
+// CheckSession rejects access tokens of deactivated users and tokens issued before sessions were revoked
+func (u *accountUsecase) CheckSession(userID string, issuedAt time.Time) error {
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("user not found")
+		}
+		return err
+	}
+
+	if user.IsDeactivated() {
+		return ErrAccountDeactivated
+	}
+	// JWT timestamps have second precision
+	if user.SessionsRevokedAt != nil && issuedAt.Before(user.SessionsRevokedAt.Truncate(time.Second)) {
+		return errors.New("session has been revoked, please log in again")
+	}
+
+	return nil
+}
+
+func (u *accountUsecase) sendReactivationLink(user *model.User, token string, validUntil time.Time) {
+	// Send reactivation email in background (goroutine)
+	go func() {
+		link := fmt.Sprintf("%s/auth/reactivate?token=%s", u.appConfig.APIBaseURL(), url.QueryEscape(token))
+		if err := u.emailService.SendReactivationEmail(user.Email, user.Name, link, validUntil); err != nil {
+			logger.Error("Failed to send reactivation email", zap.String("user_id", user.ID), zap.Error(err))
+		}
+	}()
+}
+
+func (u *accountUsecase) exportSessions(user *model.User) (*dto.AccountSessionsExport, error) {
+	apiKeys, err := u.apiKeyRepo.FindByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	identities, err := u.userIdentityRepo.FindByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := &dto.AccountSessionsExport{
+		APIKeys:          []dto.APIKeyResponse{},
+		LinkedIdentities: []dto.LinkedIdentityExport{},
+	}
+	if user.RefreshToken != nil {
+		sessions.RefreshTokenExpiresAt = formatOptionalTime(user.TokenExpiry)
+	}
+	for i := range apiKeys {
+		sessions.APIKeys = append(sessions.APIKeys, toAPIKeyResponse(&apiKeys[i]))
+	}
+	for _, identity := range identities {
+		sessions.LinkedIdentities = append(sessions.LinkedIdentities, dto.LinkedIdentityExport{
+			Provider:  identity.Provider,
+			Subject:   identity.Subject,
+			Email:     identity.Email,
+			CreatedAt: identity.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		})
+	}
+
+	return sessions, nil
+}
+
+func (u *accountUsecase) audit(action string, actorID *string, userID string, meta dto.RequestMetadata, metadata map[string]interface{}) {
+	if _, err := u.auditLogRepo.Create(&model.AuditLog{
+		ActorID:       actorID,
+		SubjectUserID: &userID,
+		Action:        action,
+		Metadata:      metadata,
+		IP:            meta.IP,
+		UserAgent:     meta.UserAgent,
+		RequestID:     meta.RequestID,
+	}); err != nil {
+		logger.Error("Failed to write audit log", zap.String("action", action), zap.String("user_id", userID), zap.Error(err))
+	}
+}
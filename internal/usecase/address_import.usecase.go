@@ -0,0 +1,244 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/amirullazmi0/kratify-backend/internal/dto"
+	"github.com/amirullazmi0/kratify-backend/internal/model"
+	"github.com/amirullazmi0/kratify-backend/internal/repository"
+	"github.com/amirullazmi0/kratify-backend/pkg/spreadsheet"
+	"github.com/amirullazmi0/kratify-backend/pkg/validator"
+)
+
+// MaxAddressImportRows caps the number of rows in one import file
+const MaxAddressImportRows = 1000
+
+// addressFileColumns is the column layout of address import and export files.
+// The province, city, district and sub_district names are informational: imports
+// take them from the region codes.
+var addressFileColumns = []string{
+	"label", "recipient_name", "phone",
+	"province_code", "regency_code", "district_code", "village_code",
+	"postal_code", "full_address", "is_primary", "latitude", "longitude",
+	"province", "city", "district", "sub_district",
+}
+
+// addressFileRequiredColumns must be present in an import file header; village_code may be
+// replaced by suggestion_id
+var addressFileRequiredColumns = []string{"label", "recipient_name", "phone", "village_code", "full_address"}
+
+// ImportAddresses validates every row of a CSV or XLSX file with the CreateAddress rules
+// and, unless dryRun is set, saves all rows in one transaction when none has errors
+func (u *addressUsecase) ImportAddresses(userID string, format string, r io.Reader, dryRun bool) (*dto.AddressImportResponse, error) {
+	table, err := spreadsheet.Read(format, r)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, column := range addressFileRequiredColumns {
+		if column == "village_code" && table.Column("suggestion_id") >= 0 {
+			continue
+		}
+		if table.Column(column) < 0 {
+			return nil, fmt.Errorf("missing column %q", column)
+		}
+	}
+	if len(table.Rows) == 0 {
+		return nil, errors.New("file has no address rows")
+	}
+	if len(table.Rows) > MaxAddressImportRows {
+		return nil, fmt.Errorf("file has %d rows, the maximum is %d", len(table.Rows), MaxAddressImportRows)
+	}
+
+	report := &dto.AddressImportResponse{
+		DryRun:    dryRun,
+		TotalRows: len(table.Rows),
+		Errors:    []dto.AddressImportRowError{},
+	}
+
+	type importRow struct {
+		line    int
+		address model.Address
+		request dto.CreateAddressRequest
+	}
+	var rows []importRow
+	primaryRow := 0
+	labels := make(map[string]int)
+
+	for i, values := range table.Rows {
+		rowNumber := table.Lines[i]
+		req, rowErrors := parseAddressRow(table, values)
+
+		if len(rowErrors) == 0 {
+			if err := validator.Validate(&req); err != nil {
+				rowErrors = validator.FormatValidationErrors(err)
+			}
+		}
+
+		if len(rowErrors) == 0 {
+			if first, ok := labels[strings.ToLower(req.Label)]; ok {
+				rowErrors = map[string]string{"label": fmt.Sprintf("label is already used in row %d", first)}
+			} else if req.IsPrimary && primaryRow != 0 {
+				rowErrors = map[string]string{"is_primary": fmt.Sprintf("only one row can be primary, row %d already is", primaryRow)}
+			}
+		}
+
+		var address model.Address
+		if len(rowErrors) == 0 {
+			var err error
+			address, err = u.newAddress(userID, &req)
+			if err != nil {
+				rowErrors = map[string]string{"region": err.Error()}
+			} else if (req.Latitude == nil) != (req.Longitude == nil) {
+				rowErrors = map[string]string{"latitude": "latitude and longitude must be sent together"}
+			}
+		}
+
+		if len(rowErrors) > 0 {
+			report.Errors = append(report.Errors, dto.AddressImportRowError{Row: rowNumber, Errors: rowErrors})
+			continue
+		}
+
+		labels[strings.ToLower(req.Label)] = rowNumber
+		if req.IsPrimary {
+			primaryRow = rowNumber
+		}
+		rows = append(rows, importRow{line: rowNumber, address: address, request: req})
+	}
+
+	report.ValidRows = len(rows)
+	if dryRun || len(report.Errors) > 0 {
+		return report, nil
+	}
+
+	// Rows without coordinates are placed at their region's centroid; geocoding up to
+	// MaxAddressImportRows rows remotely would outlast the request
+	for i := range rows {
+		if err := u.locate(u.centroids, &rows[i].address, rows[i].request.Latitude, rows[i].request.Longitude); err != nil {
+			return nil, err
+		}
+	}
+
+	err = u.addressRepo.Transaction(func(repo repository.AddressRepository) error {
+		if err := repo.LockUser(userID); err != nil {
+			return err
+		}
+
+		for i := range rows {
+			if err := insertAddress(repo, &rows[i].address); err != nil {
+				return fmt.Errorf("row %d: %w", rows[i].line, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report.Imported = len(rows)
+	return report, nil
+}
+
+// ExportAddresses writes the user's addresses in the import file layout
+func (u *addressUsecase) ExportAddresses(userID string, format string) ([]byte, error) {
+	addresses, err := u.addressRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	table := &spreadsheet.Table{Header: addressFileColumns}
+	for _, address := range addresses {
+		table.Rows = append(table.Rows, []string{
+			address.Label,
+			address.RecipientName,
+			address.Phone,
+			stringValue(address.ProvinceCode),
+			stringValue(address.RegencyCode),
+			stringValue(address.DistrictCode),
+			stringValue(address.VillageCode),
+			address.PostalCode,
+			address.FullAddress,
+			strconv.FormatBool(address.IsPrimary),
+			formatCoordinate(address.Latitude),
+			formatCoordinate(address.Longitude),
+			address.Province,
+			address.City,
+			address.District,
+			address.SubDistrict,
+		})
+	}
+
+	return spreadsheet.Write(format, table)
+}
+
+// parseAddressRow maps a file row onto a create request, reporting values that cannot be parsed
+func parseAddressRow(table *spreadsheet.Table, values []string) (dto.CreateAddressRequest, map[string]string) {
+	value := func(column string) string {
+		if i := table.Column(column); i >= 0 {
+			return values[i]
+		}
+		return ""
+	}
+
+	req := dto.CreateAddressRequest{
+		Label:         value("label"),
+		RecipientName: value("recipient_name"),
+		Phone:         value("phone"),
+		SuggestionID:  value("suggestion_id"),
+		ProvinceCode:  value("province_code"),
+		RegencyCode:   value("regency_code"),
+		DistrictCode:  value("district_code"),
+		VillageCode:   value("village_code"),
+		PostalCode:    value("postal_code"),
+		FullAddress:   value("full_address"),
+	}
+
+	rowErrors := make(map[string]string)
+
+	switch strings.ToLower(value("is_primary")) {
+	case "", "false", "0", "no", "tidak":
+	case "true", "1", "yes", "ya":
+		req.IsPrimary = true
+	default:
+		rowErrors["is_primary"] = "is_primary must be true or false"
+	}
+
+	for _, coordinate := range []struct {
+		column string
+		target **float64
+	}{
+		{"latitude", &req.Latitude},
+		{"longitude", &req.Longitude},
+	} {
+		raw := value(coordinate.column)
+		if raw == "" {
+			continue
+		}
+		parsed, err := strconv.ParseFloat(strings.ReplaceAll(raw, ",", "."), 64)
+		if err != nil {
+			rowErrors[coordinate.column] = coordinate.column + " must be a number"
+			continue
+		}
+		*coordinate.target = &parsed
+	}
+
+	return req, rowErrors
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func formatCoordinate(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*value, 'f', -1, 64)
+}
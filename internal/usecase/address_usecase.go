@@ -1,25 +1,58 @@
 package usecase
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
 	"github.com/amirullazmi0/kratify-backend/config"
 	"github.com/amirullazmi0/kratify-backend/internal/dto"
+	"github.com/amirullazmi0/kratify-backend/internal/model"
 	"github.com/amirullazmi0/kratify-backend/internal/repository"
+	"github.com/amirullazmi0/kratify-backend/pkg/geo"
+	"github.com/amirullazmi0/kratify-backend/pkg/logger"
+	"github.com/amirullazmi0/kratify-backend/pkg/regions"
+	"go.uber.org/zap"
 )
 
 type AddressUsecase interface {
 	GetAddressByAuth(userID string) ([]dto.AddressResponse, error)
-	GetAddressById(id string) (dto.AddressResponse, error)
+	GetAddressById(userID string, id string) (dto.AddressResponse, error)
 	CreateAddress(userID string, body *dto.CreateAddressRequest) (dto.AddressResponse, error)
 	UpdateAddress(userID string, body *dto.UpdateAddressRequest) (dto.AddressResponse, error)
 	DeleteAddress(userID string, addressID string) error
+	SetPrimaryAddress(userID string, addressID string) (dto.AddressResponse, error)
+	GetNearbyAddresses(userID string, req *dto.NearbyAddressRequest) ([]dto.AddressResponse, error)
+	GetAddressHistory(userID string, addressID string) ([]dto.AddressVersionResponse, error)
+	GetAddressVersion(userID string, addressID string, versionID string) (dto.AddressVersionResponse, error)
+	ImportAddresses(userID string, format string, r io.Reader, dryRun bool) (*dto.AddressImportResponse, error)
+	ExportAddresses(userID string, format string) ([]byte, error)
 }
 
+// geocodeTimeout bounds how long saving an address waits for the geocoder
+const geocodeTimeout = 5 * time.Second
+
 type addressUsecase struct {
 	addressRepo repository.AddressRepository
+	regionRepo  repository.RegionRepository
+	geocoder    geo.Geocoder
+	// centroids only reads the region table; imports use it so that a large file
+	// neither waits on nor floods a remote geocoder
+	centroids geo.Geocoder
 }
 
-func NewAddressUsecase(addressRepo repository.AddressRepository, wtCfg *config.JWTConfig) AddressUsecase {
-	return &addressUsecase{addressRepo: addressRepo}
+func NewAddressUsecase(addressRepo repository.AddressRepository, regionRepo repository.RegionRepository, geocoder geo.Geocoder, wtCfg *config.JWTConfig) AddressUsecase {
+	return &addressUsecase{
+		addressRepo: addressRepo,
+		regionRepo:  regionRepo,
+		geocoder:    geocoder,
+		centroids:   &regionCentroidGeocoder{regionRepo: regionRepo},
+	}
 }
 
 func (u *addressUsecase) GetAddressByAuth(userID string) ([]dto.AddressResponse, error) {
@@ -29,115 +62,475 @@ func (u *addressUsecase) GetAddressByAuth(userID string) ([]dto.AddressResponse,
 		return nil, err
 	}
 
-	for _, a := range addresses {
-		address = append(address, dto.AddressResponse{
-			ID:            a.ID,
-			UserID:        a.UserID,
-			Label:         a.Label,
-			RecipientName: a.RecipientName,
-			Phone:         a.Phone,
-			Province:      a.Province,
-			City:          a.City,
-			District:      a.District,
-			SubDistrict:   a.SubDistrict,
-			PostalCode:    a.PostalCode,
-			FullAddress:   a.FullAddress,
-			IsPrimary:     a.IsPrimary,
-			IsActive:      a.IsActive,
-			CreatedAt:     a.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-			UpdatedAt:     a.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		})
+	for i := range addresses {
+		address = append(address, toAddressResponse(&addresses[i]))
 	}
 
 	return address, nil
 }
 
-func (u *addressUsecase) GetAddressById(id string) (dto.AddressResponse, error) {
-	address, err := u.addressRepo.FindByID(id)
+func (u *addressUsecase) GetAddressById(userID string, id string) (dto.AddressResponse, error) {
+	address, err := findOwnedAddress(u.addressRepo, userID, id)
 	if err != nil {
 		return dto.AddressResponse{}, err
 	}
 
-	return dto.AddressResponse{
-		ID:            address.ID,
-		UserID:        address.UserID,
-		Label:         address.Label,
-		RecipientName: address.RecipientName,
-		Phone:         address.Phone,
-		Province:      address.Province,
-		City:          address.City,
-		District:      address.District,
-		SubDistrict:   address.SubDistrict,
-		PostalCode:    address.PostalCode,
-		FullAddress:   address.FullAddress,
-		IsPrimary:     address.IsPrimary,
-		IsActive:      address.IsActive,
-		CreatedAt:     address.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		UpdatedAt:     address.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
-	}, nil
+	return toAddressResponse(address), nil
 }
 
+// CreateAddress adds an address; the user's first address always becomes primary,
+// and a new primary replaces the old one in the same transaction
 func (u *addressUsecase) CreateAddress(userID string, body *dto.CreateAddressRequest) (dto.AddressResponse, error) {
-	address, err := u.addressRepo.Create(userID, body)
+	address, err := u.newAddress(userID, body)
 	if err != nil {
 		return dto.AddressResponse{}, err
 	}
+	if err := u.locate(u.geocoder, &address, body.Latitude, body.Longitude); err != nil {
+		return dto.AddressResponse{}, err
+	}
 
-	return dto.AddressResponse{
-		ID:            address.ID,
-		UserID:        address.UserID,
-		Label:         address.Label,
-		RecipientName: address.RecipientName,
-		Phone:         address.Phone,
-		Province:      address.Province,
-		City:          address.City,
-		District:      address.District,
-		SubDistrict:   address.SubDistrict,
-		PostalCode:    address.PostalCode,
-		FullAddress:   address.FullAddress,
-		IsPrimary:     address.IsPrimary,
-		IsActive:      address.IsActive,
-		CreatedAt:     address.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		UpdatedAt:     address.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
-	}, nil
+	err = u.addressRepo.Transaction(func(repo repository.AddressRepository) error {
+		if err := repo.LockUser(userID); err != nil {
+			return err
+		}
+
+		return insertAddress(repo, &address)
+	})
+	if err != nil {
+		return dto.AddressResponse{}, err
+	}
+
+	return toAddressResponse(&address), nil
+}
+
+// newAddress builds an address from the request, resolving its region codes
+// (or village suggestion) into the denormalised region names
+func (u *addressUsecase) newAddress(userID string, body *dto.CreateAddressRequest) (model.Address, error) {
+	address := model.Address{
+		UserID:        userID,
+		Label:         body.Label,
+		RecipientName: body.RecipientName,
+		Phone:         body.Phone,
+		PostalCode:    body.PostalCode,
+		FullAddress:   body.FullAddress,
+		IsPrimary:     body.IsPrimary,
+	}
+
+	provinceCode, regencyCode, districtCode, villageCode := body.ProvinceCode, body.RegencyCode, body.DistrictCode, body.VillageCode
+	if body.SuggestionID != "" {
+		// A village suggestion carries its whole hierarchy in the code
+		lineage := regions.Lineage(body.SuggestionID)
+		if len(lineage) != 4 {
+			return model.Address{}, errors.New("suggestion_id must be a village suggestion")
+		}
+		if villageCode == "" {
+			provinceCode, regencyCode, districtCode, villageCode = lineage[0], lineage[1], lineage[2], lineage[3]
+		} else if villageCode != body.SuggestionID {
+			return model.Address{}, errors.New("suggestion_id does not match village_code")
+		}
+	}
+
+	if err := u.applyRegions(&address, provinceCode, regencyCode, districtCode, villageCode); err != nil {
+		return model.Address{}, err
+	}
+
+	return address, nil
+}
+
+// insertAddress saves a new address and its first version. The user's first address
+// always becomes primary. Callers hold the user lock.
+func insertAddress(repo repository.AddressRepository, address *model.Address) error {
+	count, err := repo.CountByUserID(address.UserID)
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		address.IsPrimary = true
+	} else if address.IsPrimary {
+		if err := repo.ClearPrimary(address.UserID); err != nil {
+			return err
+		}
+	}
+
+	created, err := repo.Create(address)
+	if err != nil {
+		return err
+	}
+
+	version, err := repo.CreateVersion(created.ID, address.UserID)
+	if err != nil {
+		return err
+	}
+	created.Version, created.CurrentVersionID = version.Version, &version.ID
+
+	*address = created
+	return nil
 }
 
 func (u *addressUsecase) UpdateAddress(userID string, body *dto.UpdateAddressRequest) (dto.AddressResponse, error) {
-	address, err := u.addressRepo.Update(userID, body)
+	// Coordinates follow the location unless new ones are sent. They are resolved before
+	// taking the user lock; the geocoder may call a remote service.
+	var located *model.Address
+	if body.Latitude != nil || body.Longitude != nil || body.VillageCode != "" || body.FullAddress != "" {
+		current, err := findOwnedAddress(u.addressRepo, userID, body.ID)
+		if err != nil {
+			return dto.AddressResponse{}, err
+		}
+		if err := u.applyAddressChanges(current, body); err != nil {
+			return dto.AddressResponse{}, err
+		}
+		if err := u.locate(u.geocoder, current, body.Latitude, body.Longitude); err != nil {
+			return dto.AddressResponse{}, err
+		}
+		located = current
+	}
+
+	var address model.Address
+	err := u.addressRepo.Transaction(func(repo repository.AddressRepository) error {
+		if err := repo.LockUser(userID); err != nil {
+			return err
+		}
+
+		existing, err := findOwnedAddress(repo, userID, body.ID)
+		if err != nil {
+			return err
+		}
+
+		if body.IsPrimary != nil {
+			if *body.IsPrimary && !existing.IsPrimary {
+				if err := setPrimary(repo, userID, existing.ID); err != nil {
+					return err
+				}
+			} else if !*body.IsPrimary && existing.IsPrimary {
+				return errors.New("cannot unset the primary address; set another address as primary instead")
+			}
+		}
+
+		if err := u.applyAddressChanges(existing, body); err != nil {
+			return err
+		}
+
+		if located != nil {
+			// Another update may have moved the address since it was geocoded
+			if body.Latitude == nil && geocodeQuery(existing) != geocodeQuery(located) {
+				return errors.New("address was changed by another request; please retry")
+			}
+			existing.Latitude, existing.Longitude, existing.CoordinateSource = located.Latitude, located.Longitude, located.CoordinateSource
+		}
+
+		if _, err := repo.Update(existing); err != nil {
+			return err
+		}
+
+		// Every change is kept as a new immutable version
+		if _, err := repo.CreateVersion(existing.ID, userID); err != nil {
+			return err
+		}
+
+		updated, err := repo.FindByID(existing.ID)
+		if err != nil {
+			return err
+		}
+		address = *updated
+		return nil
+	})
 	if err != nil {
 		return dto.AddressResponse{}, err
 	}
 
-	return dto.AddressResponse{
-		ID:            address.ID,
-		UserID:        address.UserID,
-		Label:         address.Label,
-		RecipientName: address.RecipientName,
-		Phone:         address.Phone,
-		Province:      address.Province,
-		City:          address.City,
-		District:      address.District,
-		SubDistrict:   address.SubDistrict,
-		PostalCode:    address.PostalCode,
-		FullAddress:   address.FullAddress,
-		IsPrimary:     address.IsPrimary,
-		IsActive:      address.IsActive,
-		CreatedAt:     address.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		UpdatedAt:     address.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
-	}, nil
+	return toAddressResponse(&address), nil
+}
+
+// applyAddressChanges copies the fields sent in an update onto the address
+func (u *addressUsecase) applyAddressChanges(address *model.Address, body *dto.UpdateAddressRequest) error {
+	if body.Label != "" {
+		address.Label = body.Label
+	}
+	if body.RecipientName != "" {
+		address.RecipientName = body.RecipientName
+	}
+	if body.Phone != "" {
+		address.Phone = body.Phone
+	}
+	if body.FullAddress != "" {
+		address.FullAddress = body.FullAddress
+	}
+	if body.VillageCode != "" {
+		address.PostalCode = body.PostalCode
+		return u.applyRegions(address, body.ProvinceCode, body.RegencyCode, body.DistrictCode, body.VillageCode)
+	}
+	if body.PostalCode != "" {
+		address.PostalCode = body.PostalCode
+	}
+	return nil
 }
 
+// DeleteAddress removes an address and, if it was the primary one, promotes the most recent remaining address
 func (u *addressUsecase) DeleteAddress(userID string, addressID string) error {
-	// Verify address belongs to user
-	address, err := u.addressRepo.FindByID(addressID)
+	return u.addressRepo.Transaction(func(repo repository.AddressRepository) error {
+		if err := repo.LockUser(userID); err != nil {
+			return err
+		}
+
+		// Verify address belongs to user
+		address, err := findOwnedAddress(repo, userID, addressID)
+		if err != nil {
+			return err
+		}
+
+		if err := repo.Delete(address.ID); err != nil {
+			return err
+		}
+
+		if address.IsPrimary {
+			return repo.PromoteMostRecent(userID)
+		}
+		return nil
+	})
+}
+
+func (u *addressUsecase) SetPrimaryAddress(userID string, addressID string) (dto.AddressResponse, error) {
+	var address *model.Address
+	err := u.addressRepo.Transaction(func(repo repository.AddressRepository) error {
+		if err := repo.LockUser(userID); err != nil {
+			return err
+		}
+
+		existing, err := findOwnedAddress(repo, userID, addressID)
+		if err != nil {
+			return err
+		}
+
+		if !existing.IsPrimary {
+			if err := setPrimary(repo, userID, existing.ID); err != nil {
+				return err
+			}
+		}
+
+		address, err = repo.FindByID(existing.ID)
+		return err
+	})
+	if err != nil {
+		return dto.AddressResponse{}, err
+	}
+
+	return toAddressResponse(address), nil
+}
+
+// applyRegions validates that the region codes form one province > regency > district > village
+// chain and copies their names onto the address. The postal code defaults to the village's.
+func (u *addressUsecase) applyRegions(address *model.Address, provinceCode, regencyCode, districtCode, villageCode string) error {
+	lineage, err := u.regionRepo.FindWithAncestors(villageCode)
+	if err != nil {
+		return err
+	}
+	if len(lineage) != 4 || lineage[3].Level != regions.LevelVillage {
+		return errors.New("village not found")
+	}
+
+	expected := []string{provinceCode, regencyCode, districtCode, villageCode}
+	for i, region := range lineage {
+		if region.Code != expected[i] {
+			return fmt.Errorf("%s_code %s does not match village %s", strings.ToLower(region.Level), expected[i], villageCode)
+		}
+	}
+
+	province, regency, district, village := lineage[0], lineage[1], lineage[2], lineage[3]
+	address.Province = province.Name
+	address.City = regency.Name
+	address.District = district.Name
+	address.SubDistrict = village.Name
+	address.ProvinceCode = &province.Code
+	address.RegencyCode = &regency.Code
+	address.DistrictCode = &district.Code
+	address.VillageCode = &village.Code
+
+	if address.PostalCode == "" && village.PostalCode != nil {
+		address.PostalCode = *village.PostalCode
+	}
+	if address.PostalCode == "" {
+		return errors.New("postal_code is required for this village")
+	}
+
+	return nil
+}
+
+func (u *addressUsecase) GetNearbyAddresses(userID string, req *dto.NearbyAddressRequest) ([]dto.AddressResponse, error) {
+	center := geo.Point{Latitude: *req.Latitude, Longitude: *req.Longitude}
+
+	addresses, err := u.addressRepo.FindWithinRadius(userID, center, req.RadiusKm, req.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []dto.AddressResponse{}
+	for i := range addresses {
+		response := toAddressResponse(&addresses[i])
+		distance := math.Round(geo.Distance(center, geo.Point{Latitude: *addresses[i].Latitude, Longitude: *addresses[i].Longitude})*1000) / 1000
+		response.DistanceKm = &distance
+		result = append(result, response)
+	}
+
+	return result, nil
+}
+
+// GetAddressHistory lists every version of the user's address, newest first.
+// The history stays available after the address is deleted.
+func (u *addressUsecase) GetAddressHistory(userID string, addressID string) ([]dto.AddressVersionResponse, error) {
+	versions, err := u.addressRepo.FindVersionsByAddressID(addressID)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 || versions[0].UserID != userID {
+		return nil, errors.New("address not found")
+	}
+
+	result := []dto.AddressVersionResponse{}
+	for i := range versions {
+		result = append(result, toAddressVersionResponse(&versions[i]))
+	}
+
+	return result, nil
+}
+
+// GetAddressVersion returns one pinned version of the user's address
+func (u *addressUsecase) GetAddressVersion(userID string, addressID string, versionID string) (dto.AddressVersionResponse, error) {
+	version, err := u.addressRepo.FindVersionByID(versionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return dto.AddressVersionResponse{}, errors.New("address version not found")
+		}
+		return dto.AddressVersionResponse{}, err
+	}
+
+	if version.AddressID != addressID || version.UserID != userID {
+		return dto.AddressVersionResponse{}, errors.New("address version not found")
+	}
+
+	return toAddressVersionResponse(version), nil
+}
+
+// locate sets the address coordinates to the ones sent by the client or, when none
+// are sent, to the geocoder's result. An address the geocoder cannot place keeps no coordinates.
+func (u *addressUsecase) locate(geocoder geo.Geocoder, address *model.Address, latitude, longitude *float64) error {
+	if (latitude == nil) != (longitude == nil) {
+		return errors.New("latitude and longitude must be sent together")
+	}
+
+	if latitude != nil {
+		source := geo.SourceManual
+		address.Latitude, address.Longitude, address.CoordinateSource = latitude, longitude, &source
+		return nil
+	}
+
+	address.Latitude, address.Longitude, address.CoordinateSource = nil, nil, nil
+
+	ctx, cancel := context.WithTimeout(context.Background(), geocodeTimeout)
+	defer cancel()
+
+	result, err := geocoder.Geocode(ctx, geocodeQuery(address))
 	if err != nil {
+		if !errors.Is(err, geo.ErrNoMatch) {
+			logger.Warn("Failed to geocode address", zap.String("address_id", address.ID), zap.Error(err))
+		}
+		return nil
+	}
+
+	address.Latitude, address.Longitude = &result.Point.Latitude, &result.Point.Longitude
+	address.CoordinateSource = &result.Source
+	return nil
+}
+
+// geocodeQuery describes the address's location to the geocoder
+func geocodeQuery(address *model.Address) geo.Query {
+	query := geo.Query{
+		Text:        strings.Join([]string{address.FullAddress, address.SubDistrict, address.District, address.City, address.Province, address.PostalCode}, ", "),
+		CountryCode: "ID",
+	}
+	if address.VillageCode != nil {
+		query.RegionCode = *address.VillageCode
+	}
+	return query
+}
+
+// setPrimary unsets the current primary before marking the new one, so the
+// partial unique index on (user_id) WHERE is_primary is never violated
+func setPrimary(repo repository.AddressRepository, userID string, addressID string) error {
+	if err := repo.ClearPrimary(userID); err != nil {
 		return err
 	}
+	return repo.SetPrimary(userID, addressID)
+}
+
+// findOwnedAddress returns the address if it exists and belongs to the user
+func findOwnedAddress(repo repository.AddressRepository, userID string, addressID string) (*model.Address, error) {
+	address, err := repo.FindByID(addressID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("address not found")
+		}
+		return nil, err
+	}
 
 	// Check ownership
 	if address.UserID != userID {
-		return err
+		return nil, errors.New("address not found")
 	}
 
-	return u.addressRepo.Delete(addressID)
+	return address, nil
+}
+
+func toAddressResponse(address *model.Address) dto.AddressResponse {
+	return dto.AddressResponse{
+		ID:               address.ID,
+		UserID:           address.UserID,
+		Label:            address.Label,
+		RecipientName:    address.RecipientName,
+		Phone:            address.Phone,
+		Province:         address.Province,
+		City:             address.City,
+		District:         address.District,
+		SubDistrict:      address.SubDistrict,
+		PostalCode:       address.PostalCode,
+		FullAddress:      address.FullAddress,
+		ProvinceCode:     address.ProvinceCode,
+		RegencyCode:      address.RegencyCode,
+		DistrictCode:     address.DistrictCode,
+		VillageCode:      address.VillageCode,
+		Latitude:         address.Latitude,
+		Longitude:        address.Longitude,
+		CoordinateSource: address.CoordinateSource,
+		Version:          address.Version,
+		VersionID:        address.CurrentVersionID,
+		IsPrimary:        address.IsPrimary,
+		IsActive:         address.IsActive,
+		CreatedAt:        address.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:        address.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+func toAddressVersionResponse(version *model.AddressVersion) dto.AddressVersionResponse {
+	return dto.AddressVersionResponse{
+		ID:            version.ID,
+		AddressID:     version.AddressID,
+		Version:       version.Version,
+		Label:         version.Label,
+		RecipientName: version.RecipientName,
+		Phone:         version.Phone,
+		Province:      version.Province,
+		City:          version.City,
+		District:      version.District,
+		SubDistrict:   version.SubDistrict,
+		PostalCode:    version.PostalCode,
+		FullAddress:   version.FullAddress,
+		ProvinceCode:  version.ProvinceCode,
+		RegencyCode:   version.RegencyCode,
+		DistrictCode:  version.DistrictCode,
+		VillageCode:   version.VillageCode,
+		Latitude:      version.Latitude,
+		Longitude:     version.Longitude,
+		CreatedAt:     version.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		CreatedBy:     version.CreatedBy,
+	}
 }
@@ -1,41 +1,653 @@
 package usecase
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"io"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
 
+	"github.com/amirullazmi0/kratify-backend/config"
 	"github.com/amirullazmi0/kratify-backend/internal/dto"
+	"github.com/amirullazmi0/kratify-backend/internal/model"
 	"github.com/amirullazmi0/kratify-backend/internal/repository"
+	"github.com/amirullazmi0/kratify-backend/pkg/imageproc"
+	"github.com/amirullazmi0/kratify-backend/pkg/logger"
+	"github.com/amirullazmi0/kratify-backend/pkg/scanner"
+	"github.com/amirullazmi0/kratify-backend/pkg/storage"
+	"github.com/amirullazmi0/kratify-backend/pkg/tus"
+	"github.com/amirullazmi0/kratify-backend/pkg/upload"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrAttachmentNotFound is returned for missing attachments and those of other users
+	ErrAttachmentNotFound = errors.New("attachment not found")
+	// ErrUploadNotPending is returned when completing an attachment that is not awaiting its file
+	ErrUploadNotPending = errors.New("attachment is not awaiting an upload")
+	// ErrUploadExpired is returned when completing a direct upload after its deadline
+	ErrUploadExpired = errors.New("upload has expired, request a new upload URL")
+	// ErrUploadMismatch is returned when completing a direct upload whose stored file is not the one the upload URL was issued for
+	ErrUploadMismatch = errors.New("stored file does not match the issued upload")
+	// ErrUploadMissing is returned when completing a direct upload whose file was never sent
+	ErrUploadMissing = errors.New("file has not been uploaded yet")
+	// ErrInvalidVisibility is returned for visibilities other than PUBLIC and PRIVATE
+	ErrInvalidVisibility = errors.New("visibility must be PUBLIC or PRIVATE")
+)
+
+const (
+	// presignExpiry is how long a direct upload URL stays valid
+	presignExpiry = 15 * time.Minute
+	// uploadClockSkew is how far the storage clock may lag behind when checking that a
+	// direct upload was sent after its URL was issued
+	uploadClockSkew = time.Minute
+	// expiredUploadBatchSize caps the expired uploads removed per cleanup run
+	expiredUploadBatchSize = 100
+	// maxImagePixels caps images that are decoded for processing, which takes about
+	// four bytes per pixel for every copy
+	maxImagePixels = 40_000_000
+	// maxConcurrentImageProcessing caps the images decoded at the same time
+	maxConcurrentImageProcessing = 4
 )
 
+// Image types accepted for image attachments
+var imageTypes = []string{"image/jpeg", "image/png", "image/webp"}
+var imageExtensions = []string{".jpg", ".jpeg", ".png", ".webp"}
+
+// attachmentPolicies declares the files accepted for each attachment kind
+var attachmentPolicies = map[string]upload.Policy{
+	model.AttachmentKindImage: {
+		AllowedTypes:      append(imageTypes, "image/gif"),
+		AllowedExtensions: append(imageExtensions, ".gif"),
+		MaxSize:           10 << 20,
+		MaxWidth:          8000,
+		MaxHeight:         8000,
+		MaxPixels:         maxImagePixels,
+	},
+	model.AttachmentKindDocument: {
+		AllowedTypes:      []string{"application/pdf", "image/jpeg", "image/png"},
+		AllowedExtensions: []string{".pdf", ".jpg", ".jpeg", ".png"},
+		MaxSize:           20 << 20,
+		MaxWidth:          10000,
+		MaxHeight:         10000,
+	},
+	model.AttachmentKindProductImage: {
+		AllowedTypes:      imageTypes,
+		AllowedExtensions: imageExtensions,
+		MaxSize:           10 << 20,
+		MaxWidth:          6000,
+		MaxHeight:         6000,
+		MaxPixels:         maxImagePixels,
+	},
+	model.AttachmentKindProfileImage: {
+		AllowedTypes:      imageTypes,
+		AllowedExtensions: imageExtensions,
+		MaxSize:           5 << 20,
+		MaxWidth:          4096,
+		MaxHeight:         4096,
+		MaxPixels:         maxImagePixels,
+	},
+}
+
+// imageKinds are the attachment kinds whose images are cleaned and resized
+var imageKinds = map[string]bool{
+	model.AttachmentKindImage:        true,
+	model.AttachmentKindProductImage: true,
+	model.AttachmentKindProfileImage: true,
+}
+
+// defaultImageVariants apply when IMAGE_VARIANTS is not set
+var defaultImageVariants = []imageproc.Variant{
+	{Name: "thumbnail", MaxSize: 200, Format: imageproc.FormatWebP},
+	{Name: "medium", MaxSize: 800, Format: imageproc.FormatJPEG},
+	{Name: "large", MaxSize: 1600, Format: imageproc.FormatJPEG},
+}
+
 type AttachmentUsecase interface {
-	UploadImage(file io.Reader, fileName string) (*dto.AttachmentResponse, error)
-	UploadDocument(file io.Reader, fileName string) (*dto.AttachmentResponse, error)
-	UploadProductImage(file io.Reader, fileName string) (*dto.AttachmentResponse, error)
-	UploadProfileImage(file io.Reader, fileName string, userID string) (*dto.AttachmentResponse, error)
+	UploadImage(file io.ReadSeeker, fileName string, userID string, visibility string) (*dto.AttachmentResponse, error)
+	UploadDocument(file io.ReadSeeker, fileName string, userID string, visibility string) (*dto.AttachmentResponse, error)
+	UploadProductImage(file io.ReadSeeker, fileName string, userID string, visibility string) (*dto.AttachmentResponse, error)
+	UploadProfileImage(file io.ReadSeeker, fileName string, userID string, visibility string) (*dto.AttachmentResponse, error)
+	GetAttachments(userID string, req *dto.AttachmentListRequest) ([]dto.AttachmentResponse, error)
+	GetAttachment(userID string, id string) (*dto.AttachmentResponse, error)
+	DownloadAttachment(userID string, id string, variant string) (*AttachmentDownload, error)
+	DeleteAttachment(userID string, id string) error
+	PresignUpload(userID string, req *dto.PresignAttachmentRequest) (*dto.PresignAttachmentResponse, error)
+	CompleteUpload(userID string, id string) (*dto.AttachmentResponse, error)
+	PurgeExpiredUploads(ctx context.Context) error
+	CreateResumableUpload(userID string, length int64, metadata map[string]string) (*tus.Upload, error)
+	GetResumableUpload(userID string, id string) (*tus.Upload, error)
+	WriteResumableChunk(userID string, id string, offset int64, chunk io.Reader) (*tus.Upload, *dto.AttachmentResponse, error)
+	TerminateResumableUpload(userID string, id string) error
+	PurgeExpiredResumableUploads(ctx context.Context) error
+	ScanPendingAttachments(ctx context.Context) error
+	GetStorageUsage(userID string) (*dto.StorageUsageResponse, error)
+	CollectGarbage(ctx context.Context, dryRun bool) (*dto.AttachmentGCReport, error)
+	PurgeUnreferencedAttachments(ctx context.Context) error
+	MaxUploadSize() int64
+	Policy(kind string) upload.Policy
 }
 
 type attachmentUsecase struct {
-	repo repository.AttachmentRepository
+	repo             repository.AttachmentRepository
+	userRepo         repository.UserRepository
+	auditLogRepo     repository.AuditLogRepository
+	scanner          scanner.Scanner
+	resumableUploads *tus.Store
+	resumableExpiry  time.Duration
+	imageOptions     imageproc.Options
+	imageProcessing  chan struct{}
+	quotas           map[string]model.StorageQuota
+	gcGracePeriod    time.Duration
+	gcDryRun         bool
 }
 
-func NewAttachmentUsecase(repo repository.AttachmentRepository) AttachmentUsecase {
+// NewAttachmentUsecase creates the attachment usecase; uploads are not scanned for malware when fileScanner is nil
+func NewAttachmentUsecase(repo repository.AttachmentRepository, userRepo repository.UserRepository, auditLogRepo repository.AuditLogRepository, fileScanner scanner.Scanner, resumableUploads *tus.Store, storageCfg *config.StorageConfig, imageCfg *config.ImageConfig) AttachmentUsecase {
+	resumableExpiry := defaultResumableExpiry
+	if storageCfg.Tus.ExpiryHours > 0 {
+		resumableExpiry = time.Duration(storageCfg.Tus.ExpiryHours) * time.Hour
+	}
+
+	gcGracePeriod := defaultGCGracePeriod
+	if storageCfg.GC.GraceHours > 0 {
+		gcGracePeriod = time.Duration(storageCfg.GC.GraceHours) * time.Hour
+	}
+
+	imageOptions := imageproc.Options{
+		Variants:    defaultImageVariants,
+		JPEGQuality: imageCfg.JPEGQuality,
+	}
+	if len(imageCfg.Variants) > 0 {
+		imageOptions.Variants = nil
+		for _, variant := range imageCfg.Variants {
+			imageOptions.Variants = append(imageOptions.Variants, imageproc.Variant{
+				Name:    variant.Name,
+				MaxSize: variant.MaxSize,
+				Format:  variant.Format,
+			})
+		}
+	}
+
 	return &attachmentUsecase{
-		repo: repo,
+		repo:             repo,
+		userRepo:         userRepo,
+		auditLogRepo:     auditLogRepo,
+		scanner:          fileScanner,
+		resumableUploads: resumableUploads,
+		resumableExpiry:  resumableExpiry,
+		imageOptions:     imageOptions,
+		imageProcessing:  make(chan struct{}, maxConcurrentImageProcessing),
+		quotas:           storageQuotas(storageCfg.Quotas),
+		gcGracePeriod:    gcGracePeriod,
+		gcDryRun:         storageCfg.GC.DryRun,
 	}
 }
 
-func (u *attachmentUsecase) UploadImage(file io.Reader, fileName string) (*dto.AttachmentResponse, error) {
-	return u.repo.UploadImage(file, fileName)
+func (u *attachmentUsecase) UploadImage(file io.ReadSeeker, fileName string, userID string, visibility string) (*dto.AttachmentResponse, error) {
+	return u.upload(file, fileName, userID, model.AttachmentKindImage, visibility)
 }
 
-func (u *attachmentUsecase) UploadDocument(file io.Reader, fileName string) (*dto.AttachmentResponse, error) {
-	return u.repo.UploadDocument(file, fileName)
+func (u *attachmentUsecase) UploadDocument(file io.ReadSeeker, fileName string, userID string, visibility string) (*dto.AttachmentResponse, error) {
+	return u.upload(file, fileName, userID, model.AttachmentKindDocument, visibility)
+}
+
+func (u *attachmentUsecase) UploadProductImage(file io.ReadSeeker, fileName string, userID string, visibility string) (*dto.AttachmentResponse, error) {
+	return u.upload(file, fileName, userID, model.AttachmentKindProductImage, visibility)
+}
+
+func (u *attachmentUsecase) UploadProfileImage(file io.ReadSeeker, fileName string, userID string, visibility string) (*dto.AttachmentResponse, error) {
+	return u.upload(file, fileName, userID, model.AttachmentKindProfileImage, visibility)
+}
+
+// GetAttachments lists the user's attachments, newest first
+func (u *attachmentUsecase) GetAttachments(userID string, req *dto.AttachmentListRequest) ([]dto.AttachmentResponse, error) {
+	attachments, err := u.repo.FindByOwnerID(userID, req.Kind)
+	if err != nil {
+		return nil, err
+	}
+
+	response := []dto.AttachmentResponse{}
+	for _, attachment := range attachments {
+		response = append(response, toAttachmentResponse(&attachment))
+	}
+
+	return response, nil
+}
+
+func (u *attachmentUsecase) GetAttachment(userID string, id string) (*dto.AttachmentResponse, error) {
+	attachment, err := u.findOwnedAttachment(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	response := toAttachmentResponse(attachment)
+	return &response, nil
+}
+
+// DeleteAttachment removes the stored file and its record
+func (u *attachmentUsecase) DeleteAttachment(userID string, id string) error {
+	attachment, err := u.findOwnedAttachment(userID, id)
+	if err != nil {
+		return err
+	}
+
+	return u.repo.Delete(attachment)
 }
 
-func (u *attachmentUsecase) UploadProductImage(file io.Reader, fileName string) (*dto.AttachmentResponse, error) {
-	return u.repo.UploadProductImage(file, fileName)
+// Policy returns the upload policy of an attachment kind
+func (u *attachmentUsecase) Policy(kind string) upload.Policy {
+	return attachmentPolicies[kind]
 }
 
-func (u *attachmentUsecase) UploadProfileImage(file io.Reader, fileName string, userID string) (*dto.AttachmentResponse, error) {
-	return u.repo.UploadProfileImage(file, fileName, userID)
+// PresignUpload checks what the client declares against the policy of the kind and
+// returns a short-lived target the file can be sent to directly, bypassing the API.
+// The attachment stays pending until CompleteUpload is called.
+func (u *attachmentUsecase) PresignUpload(userID string, req *dto.PresignAttachmentRequest) (*dto.PresignAttachmentResponse, error) {
+	policy := u.Policy(req.Kind)
+	if err := policy.CheckDeclared(req.FileName, req.ContentType, req.Size); err != nil {
+		return nil, err
+	}
+	visibility, err := attachmentVisibility(req.Kind, req.Visibility)
+	if err != nil {
+		return nil, err
+	}
+	quota, err := u.quota(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	attachment := &model.Attachment{
+		OwnerID:    userID,
+		Kind:       req.Kind,
+		Visibility: visibility,
+		FileName:   req.FileName,
+		MimeType:   req.ContentType,
+		Size:       req.Size,
+	}
+
+	target, err := u.repo.Presign(attachment, attachmentFolder(req.Kind, userID), policy.MaxSize, presignExpiry, quota)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.PresignAttachmentResponse{
+		AttachmentID: attachment.ID,
+		Method:       target.Method,
+		URL:          target.URL,
+		Headers:      target.Headers,
+		Fields:       target.Fields,
+		FileField:    target.FileField,
+		ExpiresAt:    target.ExpiresAt.Format("2006-01-02T15:04:05Z07:00"),
+	}, nil
+}
+
+// CompleteUpload verifies a directly uploaded file the same way as an upload through
+// the API and registers it. A file that breaks the policy is deleted with its attachment.
+func (u *attachmentUsecase) CompleteUpload(userID string, id string) (*dto.AttachmentResponse, error) {
+	attachment, err := u.findOwnedAttachment(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if attachment.Status != model.AttachmentStatusPendingUpload {
+		return nil, ErrUploadNotPending
+	}
+	if attachment.UploadExpiresAt != nil && time.Now().After(*attachment.UploadExpiresAt) {
+		return nil, ErrUploadExpired
+	}
+	quota, err := u.quota(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := u.download(attachment)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	prepared, err := u.prepare(attachment, file)
+	if err != nil {
+		var violation *upload.Violation
+		if errors.As(err, &violation) {
+			if deleteErr := u.repo.Delete(attachment); deleteErr != nil {
+				logger.Error("Failed to delete rejected upload", zap.String("attachment_id", attachment.ID), zap.Error(deleteErr))
+			}
+		}
+		return nil, err
+	}
+
+	folder := attachmentFolder(attachment.Kind, userID)
+	if prepared.processed {
+		err = u.repo.Finalize(attachment, prepared.content, folder, prepared.variants, quota)
+	} else {
+		// The uploaded file is kept as it is, so only its details are recorded
+		attachment.MimeType = prepared.file.ContentType
+		attachment.Size = prepared.file.Size
+		if u.needsScan(prepared) {
+			attachment.Status = model.AttachmentStatusPendingScan
+		}
+		err = u.repo.Finalize(attachment, nil, folder, nil, quota)
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrUploadAlreadyCompleted) {
+			return nil, ErrUploadNotPending
+		}
+		return nil, err
+	}
+	if attachment.Status == model.AttachmentStatusPendingScan {
+		u.scanInBackground(attachment)
+	}
+	u.reference(attachment)
+
+	response := toAttachmentResponse(attachment)
+	return &response, nil
+}
+
+// PurgeExpiredUploads deletes direct uploads that were not completed in time,
+// including any file the client sent
+func (u *attachmentUsecase) PurgeExpiredUploads(ctx context.Context) error {
+	attachments, err := u.repo.FindExpiredUploads(time.Now(), expiredUploadBatchSize)
+	if err != nil {
+		return err
+	}
+
+	purged := 0
+	for i := range attachments {
+		if ctx.Err() != nil {
+			break
+		}
+
+		if err := u.repo.Delete(&attachments[i]); err != nil {
+			logger.Error("Failed to delete expired upload", zap.String("attachment_id", attachments[i].ID), zap.Error(err))
+			continue
+		}
+		purged++
+	}
+
+	if purged > 0 {
+		logger.Info("Deleted expired uploads", zap.Int("count", purged))
+	}
+
+	return nil
+}
+
+// upload checks the file against the policy of its kind and the user's quota before storing it
+func (u *attachmentUsecase) upload(file io.ReadSeeker, fileName string, userID string, kind string, visibility string) (*dto.AttachmentResponse, error) {
+	visibility, err := attachmentVisibility(kind, visibility)
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	quota, err := u.checkQuota(userID, size)
+	if err != nil {
+		return nil, err
+	}
+
+	attachment := &model.Attachment{
+		OwnerID:    userID,
+		Kind:       kind,
+		Visibility: visibility,
+		FileName:   fileName,
+	}
+
+	prepared, err := u.prepare(attachment, file)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.needsScan(prepared) {
+		attachment.Status = model.AttachmentStatusPendingScan
+	}
+
+	if err := u.repo.Store(attachment, prepared.content, attachmentFolder(kind, userID), prepared.variants, quota); err != nil {
+		return nil, err
+	}
+	if attachment.Status == model.AttachmentStatusPendingScan {
+		u.scanInBackground(attachment)
+	}
+	u.reference(attachment)
+
+	response := toAttachmentResponse(attachment)
+	return &response, nil
+}
+
+// preparedUpload is a checked file ready to be stored
+type preparedUpload struct {
+	file    *upload.File
+	content io.ReadSeeker
+	// processed is set when content is a re-encoded image rather than the file itself
+	processed bool
+	variants  []repository.VariantUpload
+}
+
+// prepare checks the file against the policy of the attachment's kind and sets its
+// dimensions and the checksum of the content to store, by which identical content is
+// deduplicated. Images are re-encoded upright without metadata, with resized variants.
+func (u *attachmentUsecase) prepare(attachment *model.Attachment, file io.ReadSeeker) (*preparedUpload, error) {
+	checked, err := u.Policy(attachment.Kind).Check(attachment.FileName, file)
+	if err != nil {
+		return nil, err
+	}
+
+	prepared := &preparedUpload{file: checked, content: file}
+	if checked.Width > 0 {
+		attachment.Width, attachment.Height = &checked.Width, &checked.Height
+	}
+
+	if !imageKinds[attachment.Kind] || !imageproc.Supported(checked.ContentType) {
+		attachment.Checksum, err = checksum(file)
+		if err != nil {
+			return nil, err
+		}
+		return prepared, nil
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	u.imageProcessing <- struct{}{}
+	processed, err := imageproc.Process(data, u.imageOptions)
+	<-u.imageProcessing
+	if err != nil {
+		return nil, err
+	}
+	// Re-encoding can make a file larger than the one that was checked
+	if err := u.Policy(attachment.Kind).CheckSize(int64(len(processed.Original.Data))); err != nil {
+		return nil, err
+	}
+	if ext := path.Ext(attachment.FileName); !strings.EqualFold(ext, processed.Original.Extension) &&
+		!(strings.EqualFold(ext, ".jpeg") && processed.Original.Extension == ".jpg") {
+		attachment.FileName = strings.TrimSuffix(attachment.FileName, ext) + processed.Original.Extension
+	}
+
+	prepared.content = bytes.NewReader(processed.Original.Data)
+	prepared.processed = true
+	attachment.Width, attachment.Height = &processed.Original.Width, &processed.Original.Height
+	for _, variant := range processed.Variants {
+		prepared.variants = append(prepared.variants, repository.VariantUpload{
+			Variant: model.AttachmentVariant{
+				Name:     variant.Name,
+				MimeType: variant.ContentType,
+				Width:    variant.Width,
+				Height:   variant.Height,
+			},
+			Extension: variant.Extension,
+			Content:   variant.Data,
+		})
+	}
+
+	attachment.Checksum, err = checksum(prepared.content)
+	if err != nil {
+		return nil, err
+	}
+	return prepared, nil
+}
+
+// download copies the stored file of an attachment to a temporary file, reading at
+// most one byte more than the policy allows so that oversized files are still rejected
+func (u *attachmentUsecase) download(attachment *model.Attachment) (*os.File, error) {
+	reader, object, err := u.repo.Open(attachment, attachment.Path)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, ErrUploadMissing
+		}
+		return nil, err
+	}
+	defer reader.Close()
+
+	// The file must be stored under the issued key and not predate the upload URL,
+	// otherwise it is not the one the client was allowed to send
+	if object.Key != attachment.Path || (!object.ModifiedAt.IsZero() && object.ModifiedAt.Before(attachment.CreatedAt.Add(-uploadClockSkew))) {
+		return nil, ErrUploadMismatch
+	}
+
+	file, err := os.CreateTemp("", "attachment-*")
+	if err != nil {
+		return nil, err
+	}
+
+	var content io.Reader = reader
+	if maxSize := u.Policy(attachment.Kind).MaxSize; maxSize > 0 {
+		content = io.LimitReader(reader, maxSize+1)
+	}
+	if _, err := io.Copy(file, content); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// findOwnedAttachment returns the attachment when it belongs to the user
+func (u *attachmentUsecase) findOwnedAttachment(userID string, id string) (*model.Attachment, error) {
+	attachment, err := u.repo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAttachmentNotFound
+		}
+		return nil, err
+	}
+
+	if attachment.OwnerID != userID {
+		return nil, ErrAttachmentNotFound
+	}
+
+	return attachment, nil
+}
+
+// toAttachmentResponse maps an attachment to its response. URLs are only included
+// once the file is ready, so unscanned and quarantined files are never handed out.
+// Private files are linked to the download endpoint instead of the storage URL.
+func toAttachmentResponse(attachment *model.Attachment) dto.AttachmentResponse {
+	response := dto.AttachmentResponse{
+		ID:         attachment.ID,
+		Kind:       attachment.Kind,
+		FileName:   attachment.FileName,
+		MimeType:   attachment.MimeType,
+		Size:       attachment.Size,
+		Checksum:   attachment.Checksum,
+		Status:     attachment.Status,
+		Visibility: attachment.Visibility,
+		Width:      attachment.Width,
+		Height:     attachment.Height,
+		Variants:   []dto.AttachmentVariantResponse{},
+		CreatedAt:  attachment.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if attachment.Status != model.AttachmentStatusReady {
+		return response
+	}
+
+	private := attachment.Visibility == model.AttachmentVisibilityPrivate
+	response.URL = attachment.URL
+	if private {
+		response.URL = downloadPath(attachment.ID)
+	}
+	for _, variant := range attachment.Variants {
+		variantURL := variant.URL
+		if private {
+			variantURL = downloadPath(attachment.ID) + "?variant=" + url.QueryEscape(variant.Name)
+		}
+		response.Variants = append(response.Variants, dto.AttachmentVariantResponse{
+			Name:     variant.Name,
+			URL:      variantURL,
+			MimeType: variant.MimeType,
+			Width:    variant.Width,
+			Height:   variant.Height,
+			Size:     variant.Size,
+		})
+	}
+
+	return response
+}
+
+// attachmentVisibility returns the requested visibility, or the default of the kind:
+// documents such as invoices and IDs are private, images public
+func attachmentVisibility(kind string, requested string) (string, error) {
+	switch requested {
+	case model.AttachmentVisibilityPublic, model.AttachmentVisibilityPrivate:
+		return requested, nil
+	case "":
+		if kind == model.AttachmentKindDocument {
+			return model.AttachmentVisibilityPrivate, nil
+		}
+		return model.AttachmentVisibilityPublic, nil
+	}
+	return "", ErrInvalidVisibility
+}
+
+// attachmentFolder returns the storage folder of an attachment kind
+func attachmentFolder(kind string, userID string) string {
+	switch kind {
+	case model.AttachmentKindDocument:
+		return "documents"
+	case model.AttachmentKindProductImage:
+		return "products"
+	case model.AttachmentKindProfileImage:
+		return profileFolder(userID)
+	}
+	return "images"
+}
+
+func profileFolder(userID string) string {
+	return fmt.Sprintf("profiles/%s", userID)
+}
+
+// checksum returns the hex SHA-256 of the file, then rewinds it
+func checksum(file io.ReadSeeker) (string, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
 }
@@ -0,0 +1,88 @@
+package usecase
+
+import (
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/amirullazmi0/kratify-backend/internal/model"
+	"github.com/amirullazmi0/kratify-backend/internal/repository"
+)
+
+var (
+	// ErrAttachmentNotReady is returned when downloading a file that is not uploaded, not scanned yet or quarantined
+	ErrAttachmentNotReady = errors.New("attachment is not available for download")
+	// ErrVariantNotFound is returned when downloading a variant the attachment does not have
+	ErrVariantNotFound = errors.New("variant not found")
+)
+
+// downloadURLExpiry is how long a signed download URL stays valid
+const downloadURLExpiry = 5 * time.Minute
+
+// AttachmentDownload is either a signed URL to redirect to or the file content to stream
+type AttachmentDownload struct {
+	RedirectURL string
+	// Content is set when the storage provider cannot sign URLs; the caller must close it
+	Content     io.ReadCloser
+	ContentType string
+	Size        int64
+	FileName    string
+}
+
+// DownloadAttachment gives the owner access to a ready file, the original or a named
+// variant, through a short-lived signed URL, or its content when the provider cannot sign
+func (u *attachmentUsecase) DownloadAttachment(userID string, id string, variant string) (*AttachmentDownload, error) {
+	attachment, err := u.findOwnedAttachment(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if attachment.Status != model.AttachmentStatusReady {
+		return nil, ErrAttachmentNotReady
+	}
+
+	key := attachment.Path
+	download := &AttachmentDownload{
+		ContentType: attachment.MimeType,
+		Size:        attachment.Size,
+		FileName:    attachment.FileName,
+	}
+	if variant != "" {
+		found := false
+		for _, v := range attachment.Variants {
+			if v.Name == variant {
+				key, found = v.Key, true
+				download.ContentType, download.Size = v.MimeType, v.Size
+				download.FileName = strings.TrimSuffix(attachment.FileName, path.Ext(attachment.FileName)) + "_" + v.Name + path.Ext(v.Key)
+				break
+			}
+		}
+		if !found {
+			return nil, ErrVariantNotFound
+		}
+	}
+
+	signedURL, err := u.repo.SignedURL(attachment, key, downloadURLExpiry)
+	if err == nil {
+		download.RedirectURL = signedURL
+		return download, nil
+	}
+	if !errors.Is(err, repository.ErrSignedURLUnsupported) {
+		return nil, err
+	}
+
+	content, object, err := u.repo.Open(attachment, key)
+	if err != nil {
+		return nil, err
+	}
+	download.Content = content
+	download.Size = object.Size
+
+	return download, nil
+}
+
+// downloadPath is the route private files are reached through
+func downloadPath(id string) string {
+	return "/api/attachments/" + id + "/download"
+}
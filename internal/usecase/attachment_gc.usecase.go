@@ -0,0 +1,113 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/amirullazmi0/kratify-backend/internal/dto"
+	"github.com/amirullazmi0/kratify-backend/internal/model"
+	"github.com/amirullazmi0/kratify-backend/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultGCGracePeriod applies when STORAGE_GC_GRACE_HOURS is not set
+	defaultGCGracePeriod = 7 * 24 * time.Hour
+	// gcBatchSize caps the attachments deleted, or reported, per garbage collection run
+	gcBatchSize = 100
+)
+
+// referencedKinds are the attachment kinds that only exist to be used by a user; they
+// are deleted once nothing references them. Product images are not among them: no
+// product records its references yet, so they are never collected.
+var referencedKinds = []string{model.AttachmentKindProfileImage}
+
+// CollectGarbage deletes ready profile images that no user references and that are
+// older than the grace period. With dryRun nothing is deleted and the report lists
+// what would be.
+func (u *attachmentUsecase) CollectGarbage(ctx context.Context, dryRun bool) (*dto.AttachmentGCReport, error) {
+	before := time.Now().Add(-u.gcGracePeriod)
+	attachments, err := u.repo.FindUnreferenced(referencedKinds, before, gcBatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &dto.AttachmentGCReport{
+		DryRun:        dryRun,
+		CreatedBefore: before.Format("2006-01-02T15:04:05Z07:00"),
+		Attachments:   []dto.AttachmentGCEntry{},
+	}
+	for i := range attachments {
+		if ctx.Err() != nil {
+			break
+		}
+
+		attachment := &attachments[i]
+		if !dryRun {
+			if err := u.repo.Delete(attachment); err != nil {
+				logger.Error("Failed to delete unreferenced attachment", zap.String("attachment_id", attachment.ID), zap.Error(err))
+				report.Failed++
+				continue
+			}
+		}
+
+		report.Attachments = append(report.Attachments, dto.AttachmentGCEntry{
+			ID:        attachment.ID,
+			OwnerID:   attachment.OwnerID,
+			Kind:      attachment.Kind,
+			FileName:  attachment.FileName,
+			Size:      attachment.Size,
+			CreatedAt: attachment.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		})
+		report.Count++
+		report.Bytes += attachment.Size
+	}
+
+	return report, nil
+}
+
+// PurgeUnreferencedAttachments runs the garbage collection, as a dry run when
+// STORAGE_GC_DRY_RUN is set, and logs its report
+func (u *attachmentUsecase) PurgeUnreferencedAttachments(ctx context.Context) error {
+	report, err := u.CollectGarbage(ctx, u.gcDryRun)
+	if err != nil {
+		return err
+	}
+
+	if report.DryRun {
+		for _, entry := range report.Attachments {
+			logger.Info("Unreferenced attachment would be deleted",
+				zap.String("attachment_id", entry.ID),
+				zap.String("owner_id", entry.OwnerID),
+				zap.String("kind", entry.Kind),
+				zap.Int64("size", entry.Size),
+				zap.String("created_at", entry.CreatedAt))
+		}
+	}
+	if report.Count > 0 || report.Failed > 0 {
+		logger.Info("Collected unreferenced attachments",
+			zap.Bool("dry_run", report.DryRun),
+			zap.Int("count", report.Count),
+			zap.Int64("bytes", report.Bytes),
+			zap.Int("failed", report.Failed))
+	}
+
+	return nil
+}
+
+// reference makes a newly uploaded profile image the owner's avatar, releasing the
+// previous one to the garbage collection
+func (u *attachmentUsecase) reference(attachment *model.Attachment) {
+	if attachment.Kind != model.AttachmentKindProfileImage {
+		return
+	}
+
+	if err := u.repo.SetReference(model.AttachmentReferenceUser, attachment.OwnerID, attachment.Kind, attachment.ID); err != nil {
+		logger.Error("Failed to reference profile image", zap.String("attachment_id", attachment.ID), zap.String("user_id", attachment.OwnerID), zap.Error(err))
+		return
+	}
+	if err := u.userRepo.UpdateAvatar(attachment.OwnerID, &attachment.ID); err != nil {
+		logger.Error("Failed to update avatar", zap.String("attachment_id", attachment.ID), zap.String("user_id", attachment.OwnerID), zap.Error(err))
+	}
+}
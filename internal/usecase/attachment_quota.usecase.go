@@ -0,0 +1,103 @@
+package usecase
+
+import (
+	"github.com/amirullazmi0/kratify-backend/config"
+	"github.com/amirullazmi0/kratify-backend/internal/dto"
+	"github.com/amirullazmi0/kratify-backend/internal/model"
+	"github.com/amirullazmi0/kratify-backend/internal/repository"
+)
+
+// defaultStorageQuotas apply to the roles and limits not set with STORAGE_QUOTA_<ROLE>_*;
+// roles missing here are not limited
+var defaultStorageQuotas = map[string]model.StorageQuota{
+	"USER":  {MaxBytes: 1 << 30, MaxFiles: 1000, UploadsPerHour: 100},
+	"ADMIN": {MaxBytes: 5 << 30, MaxFiles: 5000, UploadsPerHour: 500},
+}
+
+// storageQuotas merges the configured quotas into the defaults
+func storageQuotas(configured map[string]config.QuotaConfig) map[string]model.StorageQuota {
+	quotas := map[string]model.StorageQuota{}
+	for role, quota := range defaultStorageQuotas {
+		quotas[role] = quota
+	}
+
+	for role, cfg := range configured {
+		quota := quotas[role]
+		quota.MaxBytes = quotaLimit(quota.MaxBytes, cfg.MaxMB<<20)
+		quota.MaxFiles = int(quotaLimit(int64(quota.MaxFiles), int64(cfg.MaxFiles)))
+		quota.UploadsPerHour = int(quotaLimit(int64(quota.UploadsPerHour), int64(cfg.UploadsPerHour)))
+		quotas[role] = quota
+	}
+
+	return quotas
+}
+
+// quotaLimit keeps the default for an unset limit and lifts it for a negative one
+func quotaLimit(defaultLimit int64, configured int64) int64 {
+	switch {
+	case configured < 0:
+		return 0
+	case configured == 0:
+		return defaultLimit
+	}
+	return configured
+}
+
+// GetStorageUsage shows what the user stores against the quota of their role
+func (u *attachmentUsecase) GetStorageUsage(userID string) (*dto.StorageUsageResponse, error) {
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	usage, err := u.repo.Usage(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	quota := u.quotas[user.Role]
+	return &dto.StorageUsageResponse{
+		Role:           user.Role,
+		Bytes:          storageLimit(usage.Bytes, quota.MaxBytes),
+		Files:          storageLimit(int64(usage.Files), int64(quota.MaxFiles)),
+		UploadsPerHour: storageLimit(int64(usage.UploadsLastHour), int64(quota.UploadsPerHour)),
+	}, nil
+}
+
+// quota returns the storage quota of the user's role
+func (u *attachmentUsecase) quota(userID string) (*model.StorageQuota, error) {
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	quota := u.quotas[user.Role]
+	return &quota, nil
+}
+
+// checkQuota rejects an upload of the given size early, before the file is processed
+// and stored. The repository checks again when the attachment is recorded.
+func (u *attachmentUsecase) checkQuota(userID string, size int64) (*model.StorageQuota, error) {
+	quota, err := u.quota(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	usage, err := u.repo.Usage(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := repository.CheckQuota(quota, usage, size); err != nil {
+		return nil, err
+	}
+
+	return quota, nil
+}
+
+func storageLimit(used int64, limit int64) dto.StorageLimitResponse {
+	response := dto.StorageLimitResponse{Used: used}
+	if limit > 0 {
+		remaining := max(limit-used, 0)
+		response.Limit, response.Remaining = &limit, &remaining
+	}
+	return response
+}
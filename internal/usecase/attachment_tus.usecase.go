@@ -0,0 +1,206 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/amirullazmi0/kratify-backend/internal/dto"
+	"github.com/amirullazmi0/kratify-backend/internal/model"
+	"github.com/amirullazmi0/kratify-backend/pkg/logger"
+	"github.com/amirullazmi0/kratify-backend/pkg/tus"
+	"github.com/amirullazmi0/kratify-backend/pkg/upload"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrResumableUploadNotFound is returned for unknown resumable uploads and those of other users
+	ErrResumableUploadNotFound = errors.New("upload not found")
+	// ErrInvalidUploadMetadata is returned when a resumable upload lacks a file name or has an unknown kind
+	ErrInvalidUploadMetadata = errors.New("upload metadata must contain a filename and a valid kind")
+)
+
+// defaultResumableExpiry applies when STORAGE_TUS_EXPIRY_HOURS is not set
+const defaultResumableExpiry = 24 * time.Hour
+
+// CreateResumableUpload stages an upload of length bytes. The metadata holds the
+// "filename", optionally the "filetype", the attachment "kind" (DOCUMENT by default) and
+// its "visibility", which are checked with the user's quota before any bytes are accepted.
+func (u *attachmentUsecase) CreateResumableUpload(userID string, length int64, metadata map[string]string) (*tus.Upload, error) {
+	if metadata["kind"] == "" {
+		metadata["kind"] = model.AttachmentKindDocument
+	}
+	policy, ok := attachmentPolicies[metadata["kind"]]
+	if !ok || metadata["filename"] == "" {
+		return nil, ErrInvalidUploadMetadata
+	}
+
+	// The type is checked again from the content once the upload is complete
+	if metadata["filetype"] == "" {
+		policy.AllowedTypes = nil
+	}
+	if err := policy.CheckDeclared(metadata["filename"], metadata["filetype"], length); err != nil {
+		return nil, err
+	}
+	if _, err := attachmentVisibility(metadata["kind"], metadata["visibility"]); err != nil {
+		return nil, err
+	}
+	if _, err := u.checkQuota(userID, length); err != nil {
+		return nil, err
+	}
+
+	return u.resumableUploads.Create(userID, length, metadata, time.Now().Add(u.resumableExpiry))
+}
+
+// GetResumableUpload returns the state of one of the user's resumable uploads
+func (u *attachmentUsecase) GetResumableUpload(userID string, id string) (*tus.Upload, error) {
+	return u.findOwnedResumableUpload(userID, id)
+}
+
+// WriteResumableChunk appends a chunk to one of the user's resumable uploads. When the
+// last byte arrives the file is checked and stored like an upload through the API and
+// the attachment is returned; a file that breaks the policy is discarded.
+func (u *attachmentUsecase) WriteResumableChunk(userID string, id string, offset int64, chunk io.Reader) (*tus.Upload, *dto.AttachmentResponse, error) {
+	unlock, err := u.resumableUploads.Lock(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer unlock()
+
+	staged, err := u.findOwnedResumableUpload(userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if staged.AttachmentID != "" {
+		return staged, nil, tus.ErrOffsetMismatch
+	}
+
+	if err := u.resumableUploads.WriteChunk(staged, offset, chunk); err != nil {
+		if errors.Is(err, tus.ErrExceedsLength) {
+			u.discardResumableUpload(staged)
+		}
+		return staged, nil, err
+	}
+	if !staged.Done() {
+		return staged, nil, nil
+	}
+
+	attachment, err := u.completeResumableUpload(staged)
+	if err != nil {
+		var violation *upload.Violation
+		if errors.As(err, &violation) {
+			u.discardResumableUpload(staged)
+		}
+		return staged, nil, err
+	}
+
+	return staged, attachment, nil
+}
+
+// TerminateResumableUpload discards one of the user's resumable uploads
+func (u *attachmentUsecase) TerminateResumableUpload(userID string, id string) error {
+	unlock, err := u.resumableUploads.Lock(id)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	staged, err := u.findOwnedResumableUpload(userID, id)
+	if err != nil {
+		return err
+	}
+
+	return u.resumableUploads.Delete(staged.ID)
+}
+
+// PurgeExpiredResumableUploads deletes resumable uploads whose expiry has passed.
+// Uploads that are being written to are left for the next run.
+func (u *attachmentUsecase) PurgeExpiredResumableUploads(ctx context.Context) error {
+	expired, err := u.resumableUploads.Expired(time.Now())
+	if err != nil {
+		return err
+	}
+
+	purged := 0
+	for _, staged := range expired {
+		if ctx.Err() != nil {
+			break
+		}
+
+		unlock, err := u.resumableUploads.Lock(staged.ID)
+		if err != nil {
+			continue
+		}
+		err = u.resumableUploads.Delete(staged.ID)
+		unlock()
+		if err != nil {
+			logger.Error("Failed to delete expired resumable upload", zap.String("upload_id", staged.ID), zap.Error(err))
+			continue
+		}
+		purged++
+	}
+
+	if purged > 0 {
+		logger.Info("Deleted expired resumable uploads", zap.Int("count", purged))
+	}
+
+	return nil
+}
+
+// MaxUploadSize returns the largest file any upload policy accepts
+func (u *attachmentUsecase) MaxUploadSize() int64 {
+	var maxSize int64
+	for _, policy := range attachmentPolicies {
+		maxSize = max(maxSize, policy.MaxSize)
+	}
+	return maxSize
+}
+
+// completeResumableUpload stores the staged bytes as an attachment and records it
+func (u *attachmentUsecase) completeResumableUpload(staged *tus.Upload) (*dto.AttachmentResponse, error) {
+	file, err := u.resumableUploads.Open(staged.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	attachment, err := u.upload(file, staged.Metadata["filename"], staged.OwnerID, staged.Metadata["kind"], staged.Metadata["visibility"])
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.resumableUploads.Complete(staged, attachment.ID); err != nil {
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+// findOwnedResumableUpload returns the upload when it belongs to the user and has not expired
+func (u *attachmentUsecase) findOwnedResumableUpload(userID string, id string) (*tus.Upload, error) {
+	staged, err := u.resumableUploads.Get(id)
+	if err != nil {
+		if errors.Is(err, tus.ErrNotFound) {
+			return nil, ErrResumableUploadNotFound
+		}
+		return nil, err
+	}
+
+	if staged.OwnerID != userID {
+		return nil, ErrResumableUploadNotFound
+	}
+	if time.Now().After(staged.ExpiresAt) {
+		return nil, ErrUploadExpired
+	}
+
+	return staged, nil
+}
+
+// discardResumableUpload deletes an upload that can never be completed
+func (u *attachmentUsecase) discardResumableUpload(staged *tus.Upload) {
+	if err := u.resumableUploads.Delete(staged.ID); err != nil {
+		logger.Error("Failed to delete rejected resumable upload", zap.String("upload_id", staged.ID), zap.Error(err))
+	}
+}
@@ -0,0 +1,155 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amirullazmi0/kratify-backend/config"
+	"github.com/amirullazmi0/kratify-backend/internal/model"
+	"github.com/amirullazmi0/kratify-backend/pkg/tus"
+	"github.com/amirullazmi0/kratify-backend/pkg/upload"
+)
+
+// testPDF is detected as application/pdf, which documents accept without processing
+const testPDF = "%PDF-1.4\n1 0 obj << >> endobj\ntrailer << >>\n%%EOF\n"
+
+// newTestResumableUsecase returns an attachment usecase with resumable uploads staged in
+// a temporary directory and two users, "owner" and "other"
+func newTestResumableUsecase(t *testing.T) (*attachmentUsecase, *fakeAttachmentRepository, *tus.Store) {
+	t.Helper()
+
+	store, err := tus.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	repo := newFakeAttachmentRepository()
+	users := newFakeUserRepository(&model.User{ID: "owner", Role: "USER"}, &model.User{ID: "other", Role: "USER"})
+
+	usecase := NewAttachmentUsecase(repo, users, nil, nil, store, &config.StorageConfig{}, &config.ImageConfig{})
+	return usecase.(*attachmentUsecase), repo, store
+}
+
+func documentMetadata() map[string]string {
+	return map[string]string{"filename": "invoice.pdf", "filetype": "application/pdf", "kind": model.AttachmentKindDocument}
+}
+
+func TestResumableUploadCompletesAsAttachment(t *testing.T) {
+	usecase, repo, _ := newTestResumableUsecase(t)
+
+	staged, err := usecase.CreateResumableUpload("owner", int64(len(testPDF)), documentMetadata())
+	if err != nil {
+		t.Fatalf("CreateResumableUpload() error = %v", err)
+	}
+
+	split := len(testPDF) / 2
+	written, attachment, err := usecase.WriteResumableChunk("owner", staged.ID, 0, strings.NewReader(testPDF[:split]))
+	if err != nil || attachment != nil || written.Offset != int64(split) {
+		t.Fatalf("first chunk = offset %d, attachment %v, error %v", written.Offset, attachment, err)
+	}
+
+	written, attachment, err = usecase.WriteResumableChunk("owner", staged.ID, int64(split), strings.NewReader(testPDF[split:]))
+	if err != nil {
+		t.Fatalf("last chunk error = %v", err)
+	}
+	if attachment == nil || attachment.FileName != "invoice.pdf" || attachment.Kind != model.AttachmentKindDocument {
+		t.Fatalf("last chunk attachment = %+v", attachment)
+	}
+	if content, ok := repo.content(attachment.ID); !ok || string(content) != testPDF {
+		t.Errorf("stored content = %q", content)
+	}
+
+	// The upload reports the attachment, and a retried last chunk does not store it twice
+	state, err := usecase.GetResumableUpload("owner", staged.ID)
+	if err != nil || state.AttachmentID != attachment.ID || !state.Done() {
+		t.Errorf("GetResumableUpload() = %+v, %v", state, err)
+	}
+	if _, _, err := usecase.WriteResumableChunk("owner", staged.ID, int64(split), strings.NewReader(testPDF[split:])); !errors.Is(err, tus.ErrOffsetMismatch) {
+		t.Errorf("chunk after completion error = %v, want ErrOffsetMismatch", err)
+	}
+	if len(repo.attachments) != 1 {
+		t.Errorf("stored %d attachments, want 1", len(repo.attachments))
+	}
+}
+
+func TestResumableUploadBelongsToItsOwner(t *testing.T) {
+	usecase, repo, _ := newTestResumableUsecase(t)
+
+	staged, err := usecase.CreateResumableUpload("owner", int64(len(testPDF)), documentMetadata())
+	if err != nil {
+		t.Fatalf("CreateResumableUpload() error = %v", err)
+	}
+
+	if _, err := usecase.GetResumableUpload("other", staged.ID); !errors.Is(err, ErrResumableUploadNotFound) {
+		t.Errorf("GetResumableUpload() by another user error = %v, want ErrResumableUploadNotFound", err)
+	}
+	if _, _, err := usecase.WriteResumableChunk("other", staged.ID, 0, strings.NewReader(testPDF)); !errors.Is(err, ErrResumableUploadNotFound) {
+		t.Errorf("WriteResumableChunk() by another user error = %v, want ErrResumableUploadNotFound", err)
+	}
+	if err := usecase.TerminateResumableUpload("other", staged.ID); !errors.Is(err, ErrResumableUploadNotFound) {
+		t.Errorf("TerminateResumableUpload() by another user error = %v, want ErrResumableUploadNotFound", err)
+	}
+
+	// Nothing the other user sent was written
+	_, attachment, err := usecase.WriteResumableChunk("owner", staged.ID, 0, strings.NewReader(testPDF))
+	if err != nil || attachment == nil {
+		t.Fatalf("WriteResumableChunk() by the owner = %v, %v", attachment, err)
+	}
+	if stored := repo.attachments[attachment.ID]; stored.OwnerID != "owner" {
+		t.Errorf("attachment owner = %q, want owner", stored.OwnerID)
+	}
+}
+
+func TestResumableUploadExpires(t *testing.T) {
+	usecase, _, store := newTestResumableUsecase(t)
+
+	expired, err := store.Create("owner", int64(len(testPDF)), documentMetadata(), time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	open, err := usecase.CreateResumableUpload("owner", int64(len(testPDF)), documentMetadata())
+	if err != nil {
+		t.Fatalf("CreateResumableUpload() error = %v", err)
+	}
+
+	if _, err := usecase.GetResumableUpload("owner", expired.ID); !errors.Is(err, ErrUploadExpired) {
+		t.Errorf("GetResumableUpload() error = %v, want ErrUploadExpired", err)
+	}
+	if _, _, err := usecase.WriteResumableChunk("owner", expired.ID, 0, strings.NewReader(testPDF)); !errors.Is(err, ErrUploadExpired) {
+		t.Errorf("WriteResumableChunk() error = %v, want ErrUploadExpired", err)
+	}
+
+	if err := usecase.PurgeExpiredResumableUploads(context.Background()); err != nil {
+		t.Fatalf("PurgeExpiredResumableUploads() error = %v", err)
+	}
+	if _, err := usecase.GetResumableUpload("owner", expired.ID); !errors.Is(err, ErrResumableUploadNotFound) {
+		t.Errorf("GetResumableUpload() after purge error = %v, want ErrResumableUploadNotFound", err)
+	}
+	if _, err := usecase.GetResumableUpload("owner", open.ID); err != nil {
+		t.Errorf("GetResumableUpload() of an open upload after purge error = %v", err)
+	}
+}
+
+func TestResumableUploadRejectedFileIsDiscarded(t *testing.T) {
+	usecase, repo, _ := newTestResumableUsecase(t)
+
+	// Declared as a PDF, but the content is plain text
+	content := "not a pdf at all"
+	staged, err := usecase.CreateResumableUpload("owner", int64(len(content)), documentMetadata())
+	if err != nil {
+		t.Fatalf("CreateResumableUpload() error = %v", err)
+	}
+
+	_, _, err = usecase.WriteResumableChunk("owner", staged.ID, 0, strings.NewReader(content))
+	if !errors.Is(err, upload.ErrUnsupportedType) {
+		t.Fatalf("WriteResumableChunk() error = %v, want ErrUnsupportedType", err)
+	}
+	if _, err := usecase.GetResumableUpload("owner", staged.ID); !errors.Is(err, ErrResumableUploadNotFound) {
+		t.Errorf("GetResumableUpload() of a rejected upload error = %v, want ErrResumableUploadNotFound", err)
+	}
+	if len(repo.attachments) != 0 {
+		t.Errorf("stored %d attachments, want 0", len(repo.attachments))
+	}
+}
@@ -0,0 +1,187 @@
+package usecase
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/amirullazmi0/kratify-backend/internal/model"
+	"github.com/amirullazmi0/kratify-backend/internal/repository"
+)
+
+// fakeUserRepository keeps users in memory. Methods a test does not need panic
+// through the embedded nil interface.
+type fakeUserRepository struct {
+	repository.UserRepository
+
+	mu    sync.Mutex
+	users map[string]*model.User
+}
+
+func newFakeUserRepository(users ...*model.User) *fakeUserRepository {
+	r := &fakeUserRepository{users: map[string]*model.User{}}
+	for _, user := range users {
+		r.users[user.ID] = user
+	}
+	return r
+}
+
+func (r *fakeUserRepository) Create(user *model.User) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.users {
+		if existing.Email == user.Email {
+			return "", fmt.Errorf("duplicate email %s", user.Email)
+		}
+	}
+	id := fmt.Sprintf("user-%d", len(r.users)+1)
+	created := *user
+	created.ID = id
+	r.users[id] = &created
+	return id, nil
+}
+
+func (r *fakeUserRepository) FindByID(id string) (*model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *fakeUserRepository) FindByEmail(email string) (*model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range r.users {
+		if user.Email == email {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *fakeUserRepository) SaveRefreshToken(userID string, refreshToken string, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	user.RefreshToken = &refreshToken
+	user.TokenExpiry = &expiresAt
+	return nil
+}
+
+func (r *fakeUserRepository) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.users)
+}
+
+// fakeUserIdentityRepository keeps external identities in memory
+type fakeUserIdentityRepository struct {
+	mu         sync.Mutex
+	identities []model.UserIdentity
+}
+
+func (r *fakeUserIdentityRepository) Create(identity *model.UserIdentity) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	identity.ID = fmt.Sprintf("identity-%d", len(r.identities)+1)
+	r.identities = append(r.identities, *identity)
+	return identity.ID, nil
+}
+
+func (r *fakeUserIdentityRepository) FindByProviderSubject(provider string, subject string) (*model.UserIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, identity := range r.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			copied := identity
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *fakeUserIdentityRepository) FindByUserID(userID string) ([]model.UserIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var identities []model.UserIdentity
+	for _, identity := range r.identities {
+		if identity.UserID == userID {
+			identities = append(identities, identity)
+		}
+	}
+	return identities, nil
+}
+
+// fakeAttachmentRepository keeps stored attachments and their content in memory.
+// Methods a test does not need panic through the embedded nil interface.
+type fakeAttachmentRepository struct {
+	repository.AttachmentRepository
+
+	mu          sync.Mutex
+	attachments map[string]*model.Attachment
+	contents    map[string][]byte
+}
+
+func newFakeAttachmentRepository() *fakeAttachmentRepository {
+	return &fakeAttachmentRepository{attachments: map[string]*model.Attachment{}, contents: map[string][]byte{}}
+}
+
+func (r *fakeAttachmentRepository) Store(attachment *model.Attachment, file io.Reader, folder string, variants []repository.VariantUpload, quota *model.StorageQuota) error {
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attachment.ID = fmt.Sprintf("attachment-%d", len(r.attachments)+1)
+	attachment.Size = int64(len(content))
+	attachment.CreatedAt = time.Now()
+	if attachment.Status == "" {
+		attachment.Status = model.AttachmentStatusReady
+	}
+	stored := *attachment
+	r.attachments[attachment.ID] = &stored
+	r.contents[attachment.ID] = content
+	return nil
+}
+
+func (r *fakeAttachmentRepository) Usage(ownerID string) (*model.StorageUsage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	usage := &model.StorageUsage{}
+	for _, attachment := range r.attachments {
+		if attachment.OwnerID == ownerID {
+			usage.Bytes += attachment.Size
+			usage.Files++
+		}
+	}
+	return usage, nil
+}
+
+func (r *fakeAttachmentRepository) content(id string) ([]byte, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	content, ok := r.contents[id]
+	return content, ok
+}
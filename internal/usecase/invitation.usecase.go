@@ -0,0 +1,319 @@
+package usecase
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/amirullazmi0/kratify-backend/config"
+	"github.com/amirullazmi0/kratify-backend/internal/dto"
+	"github.com/amirullazmi0/kratify-backend/internal/model"
+	"github.com/amirullazmi0/kratify-backend/internal/repository"
+	"github.com/amirullazmi0/kratify-backend/pkg/email"
+	"github.com/amirullazmi0/kratify-backend/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// ErrInvitationRoleForbidden is returned when the inviter may not manage invitations for the role
+var ErrInvitationRoleForbidden = errors.New("you are not allowed to manage invitations for this role")
+
+// InvitationTTL is how long an invitation link stays valid; resending issues a new link
+const InvitationTTL = 7 * 24 * time.Hour
+
+type InvitationUsecase interface {
+	CreateInvitation(inviterID string, req *dto.CreateInvitationRequest) (*dto.InvitationResponse, error)
+	GetInvitations(status string) ([]dto.InvitationResponse, error)
+	ResendInvitation(inviterID string, id string) (*dto.InvitationResponse, error)
+	RevokeInvitation(inviterID string, id string) error
+	PreviewInvitation(token string) (*dto.InvitationPreviewResponse, error)
+	AcceptInvitation(req *dto.AcceptInvitationRequest) (*dto.AuthResponse, error)
+}
+
+type invitationUsecase struct {
+	invitationRepo repository.InvitationRepository
+	userRepo       repository.UserRepository
+	jwtCfg         *config.JWTConfig
+	emailService   *email.EmailService
+	appConfig      *config.AppConfig
+}
+
+// NewInvitationUsecase creates a new invitation usecase
+func NewInvitationUsecase(invitationRepo repository.InvitationRepository, userRepo repository.UserRepository, jwtCfg *config.JWTConfig, emailService *email.EmailService, appConfig *config.AppConfig) InvitationUsecase {
+	return &invitationUsecase{
+		invitationRepo: invitationRepo,
+		userRepo:       userRepo,
+		jwtCfg:         jwtCfg,
+		emailService:   emailService,
+		appConfig:      appConfig,
+	}
+}
+
+func (u *invitationUsecase) CreateInvitation(inviterID string, req *dto.CreateInvitationRequest) (*dto.InvitationResponse, error) {
+	inviter, err := u.findInviter(inviterID)
+	if err != nil {
+		return nil, err
+	}
+	if !canAssignRole(inviter.Role, req.Role) {
+		return nil, errors.New("you are not allowed to invite users with this role")
+	}
+
+	// An invitation that expired unanswered leaves its inactive pending user behind
+	if removed, err := u.invitationRepo.DeleteExpiredUser(req.Email); err != nil {
+		return nil, err
+	} else if removed > 0 {
+		logger.Info("Removed pending user of an expired invitation", zap.String("email", req.Email))
+	}
+
+	existingUser, _ := u.userRepo.FindByEmail(req.Email)
+	if existingUser != nil {
+		return nil, errors.New("email already registered")
+	}
+
+	name := req.Name
+	if name == "" {
+		name = strings.Split(req.Email, "@")[0]
+	}
+
+	// The pending user gets an unusable random password until the invitee sets their own
+	placeholder, err := email.GenerateVerificationToken()
+	if err != nil {
+		return nil, err
+	}
+	user := &model.User{
+		Email:    req.Email,
+		Password: placeholder,
+		Name:     name,
+	}
+	if err := user.HashPassword(); err != nil {
+		return nil, err
+	}
+
+	token, err := email.GenerateVerificationToken()
+	if err != nil {
+		return nil, err
+	}
+
+	invitation, err := u.invitationRepo.Create(&model.Invitation{
+		Role:      req.Role,
+		InvitedBy: &inviter.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(InvitationTTL),
+	}, user)
+	if err != nil {
+		return nil, err
+	}
+
+	u.sendInvitation(invitation, name, inviter.Name, token)
+
+	response := toInvitationResponse(invitation)
+	return &response, nil
+}
+
+func (u *invitationUsecase) GetInvitations(status string) ([]dto.InvitationResponse, error) {
+	invitations, err := u.invitationRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	response := []dto.InvitationResponse{}
+	for i := range invitations {
+		if status != "" && invitations[i].Status() != status {
+			continue
+		}
+		response = append(response, toInvitationResponse(&invitations[i]))
+	}
+
+	return response, nil
+}
+
+// ResendInvitation issues a fresh link (invalidating the previous one) and restarts the expiry window
+func (u *invitationUsecase) ResendInvitation(inviterID string, id string) (*dto.InvitationResponse, error) {
+	inviter, err := u.findInviter(inviterID)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := u.invitationRepo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("invitation not found")
+		}
+		return nil, err
+	}
+	if !canAssignRole(inviter.Role, existing.Role) {
+		return nil, errors.New("you are not allowed to invite users with this role")
+	}
+
+	token, err := email.GenerateVerificationToken()
+	if err != nil {
+		return nil, err
+	}
+
+	invitation, err := u.invitationRepo.Renew(id, hashToken(token), time.Now().Add(InvitationTTL))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("invitation has already been accepted or revoked, or its user was removed")
+		}
+		return nil, err
+	}
+
+	name := invitation.Email
+	if invitation.UserID != nil {
+		if user, err := u.userRepo.FindByID(*invitation.UserID); err == nil {
+			name = user.Name
+		}
+	}
+	u.sendInvitation(invitation, name, inviter.Name, token)
+
+	response := toInvitationResponse(invitation)
+	return &response, nil
+}
+
+// RevokeInvitation revokes an open invitation and removes its pending user. Like
+// creating and resending, it is limited to roles the inviter may assign.
+func (u *invitationUsecase) RevokeInvitation(inviterID string, id string) error {
+	inviter, err := u.findInviter(inviterID)
+	if err != nil {
+		return err
+	}
+
+	existing, err := u.invitationRepo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("invitation not found or no longer pending")
+		}
+		return err
+	}
+	if !canAssignRole(inviter.Role, existing.Role) {
+		return ErrInvitationRoleForbidden
+	}
+
+	affected, err := u.invitationRepo.Revoke(id)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("invitation not found or no longer pending")
+	}
+
+	return nil
+}
+
+func (u *invitationUsecase) PreviewInvitation(token string) (*dto.InvitationPreviewResponse, error) {
+	invitation, user, err := u.findPending(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.InvitationPreviewResponse{
+		Email:     invitation.Email,
+		Name:      user.Name,
+		Role:      invitation.Role,
+		ExpiresAt: invitation.ExpiresAt.Format("2006-01-02T15:04:05Z07:00"),
+	}, nil
+}
+
+// AcceptInvitation sets the invitee's password, activates the account and signs them in
+func (u *invitationUsecase) AcceptInvitation(req *dto.AcceptInvitationRequest) (*dto.AuthResponse, error) {
+	// Hash password
+	credentials := &model.User{Password: req.Password}
+	if err := credentials.HashPassword(); err != nil {
+		return nil, err
+	}
+
+	userID, err := u.invitationRepo.Accept(hashToken(req.Token), req.Name, credentials.Password)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("invalid or expired invitation")
+		}
+		return nil, err
+	}
+
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return issueTokens(u.userRepo, u.jwtCfg, user)
+}
+
+func (u *invitationUsecase) findInviter(inviterID string) (*model.User, error) {
+	inviter, err := u.userRepo.FindByID(inviterID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+	return inviter, nil
+}
+
+func (u *invitationUsecase) findPending(token string) (*model.Invitation, *model.User, error) {
+	if token == "" {
+		return nil, nil, errors.New("invalid or expired invitation")
+	}
+
+	invitation, err := u.invitationRepo.FindPendingByTokenHash(hashToken(token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, errors.New("invalid or expired invitation")
+		}
+		return nil, nil, err
+	}
+	if invitation.UserID == nil {
+		return nil, nil, errors.New("invalid or expired invitation")
+	}
+
+	user, err := u.userRepo.FindByID(*invitation.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, errors.New("invalid or expired invitation")
+		}
+		return nil, nil, err
+	}
+
+	return invitation, user, nil
+}
+
+func (u *invitationUsecase) sendInvitation(invitation *model.Invitation, name string, inviterName string, token string) {
+	// Send invitation email in background (goroutine)
+	go func() {
+		link := fmt.Sprintf("%s/auth/invitations/accept?token=%s", u.appConfig.FrontendBaseURL(), url.QueryEscape(token))
+		if err := u.emailService.SendInvitationEmail(invitation.Email, name, inviterName, link, InvitationTTL); err != nil {
+			logger.Error("Failed to send invitation email", zap.String("invitation_id", invitation.ID), zap.Error(err))
+		}
+	}()
+}
+
+// canAssignRole reports whether a user with inviterRole may create an account with role.
+// Only superadmins can create other superadmins.
+func canAssignRole(inviterRole string, role string) bool {
+	switch inviterRole {
+	case "SUPERADMIN":
+		return true
+	case "ADMIN":
+		return role != "SUPERADMIN"
+	default:
+		return false
+	}
+}
+
+func toInvitationResponse(invitation *model.Invitation) dto.InvitationResponse {
+	return dto.InvitationResponse{
+		ID:         invitation.ID,
+		UserID:     invitation.UserID,
+		Email:      invitation.Email,
+		Role:       invitation.Role,
+		Status:     invitation.Status(),
+		InvitedBy:  invitation.InvitedBy,
+		SentCount:  invitation.SentCount,
+		LastSentAt: invitation.LastSentAt.Format("2006-01-02T15:04:05Z07:00"),
+		ExpiresAt:  invitation.ExpiresAt.Format("2006-01-02T15:04:05Z07:00"),
+		AcceptedAt: formatOptionalTime(invitation.AcceptedAt),
+		RevokedAt:  formatOptionalTime(invitation.RevokedAt),
+		CreatedAt:  invitation.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
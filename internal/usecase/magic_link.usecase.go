@@ -0,0 +1,116 @@
+package usecase
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/amirullazmi0/kratify-backend/config"
+	"github.com/amirullazmi0/kratify-backend/internal/dto"
+	"github.com/amirullazmi0/kratify-backend/internal/model"
+	"github.com/amirullazmi0/kratify-backend/internal/repository"
+	"github.com/amirullazmi0/kratify-backend/pkg/email"
+	"github.com/amirullazmi0/kratify-backend/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// MagicLinkTTL is how long an emailed sign-in link stays valid
+const MagicLinkTTL = 15 * time.Minute
+
+type MagicLinkUsecase interface {
+	RequestMagicLink(req *dto.MagicLinkRequest) (nonce string, err error)
+	VerifyMagicLink(token string, nonce string) (*dto.AuthResponse, error)
+}
+
+type magicLinkUsecase struct {
+	magicLinkRepo repository.MagicLinkRepository
+	userRepo      repository.UserRepository
+	jwtCfg        *config.JWTConfig
+	emailService  *email.EmailService
+	appConfig     *config.AppConfig
+}
+
+// NewMagicLinkUsecase creates a new passwordless login usecase
+func NewMagicLinkUsecase(magicLinkRepo repository.MagicLinkRepository, userRepo repository.UserRepository, jwtCfg *config.JWTConfig, emailService *email.EmailService, appConfig *config.AppConfig) MagicLinkUsecase {
+	return &magicLinkUsecase{
+		magicLinkRepo: magicLinkRepo,
+		userRepo:      userRepo,
+		jwtCfg:        jwtCfg,
+		emailService:  emailService,
+		appConfig:     appConfig,
+	}
+}
+
+// RequestMagicLink emails a sign-in link and returns the browser nonce the link is bound to.
+// A nonce is returned even for unknown emails so the response does not reveal which accounts exist.
+func (u *magicLinkUsecase) RequestMagicLink(req *dto.MagicLinkRequest) (string, error) {
+	nonce, err := email.GenerateVerificationToken()
+	if err != nil {
+		return "", err
+	}
+
+	user, err := u.userRepo.FindByEmail(req.Email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nonce, nil
+		}
+		return "", err
+	}
+	if !user.IsActive || user.IsDeactivated() {
+		return nonce, nil
+	}
+
+	token, err := email.GenerateVerificationToken()
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := u.magicLinkRepo.Create(&model.MagicLinkToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		NonceHash: hashToken(nonce),
+		ExpiresAt: time.Now().Add(MagicLinkTTL),
+	}); err != nil {
+		return "", err
+	}
+
+	// Send sign-in email in background (goroutine)
+	go func() {
+		link := fmt.Sprintf("%s/auth/magic-link/verify?token=%s", u.appConfig.APIBaseURL(), url.QueryEscape(token))
+		if err := u.emailService.SendMagicLinkEmail(user.Email, user.Name, link, MagicLinkTTL); err != nil {
+			logger.Error("Failed to send magic link email", zap.String("user_id", user.ID), zap.Error(err))
+		}
+	}()
+
+	return nonce, nil
+}
+
+// VerifyMagicLink consumes the token if it was requested from the same browser and issues tokens
+func (u *magicLinkUsecase) VerifyMagicLink(token string, nonce string) (*dto.AuthResponse, error) {
+	if token == "" || nonce == "" {
+		return nil, errors.New("invalid or expired sign-in link")
+	}
+
+	magicLink, err := u.magicLinkRepo.Consume(hashToken(token), hashToken(nonce))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("invalid or expired sign-in link")
+		}
+		return nil, err
+	}
+
+	user, err := u.userRepo.FindByID(magicLink.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("invalid or expired sign-in link")
+		}
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, errors.New("please verify your email first")
+	}
+
+	return issueTokens(u.userRepo, u.jwtCfg, user)
+}
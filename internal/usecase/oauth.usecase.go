@@ -0,0 +1,216 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/amirullazmi0/kratify-backend/config"
+	"github.com/amirullazmi0/kratify-backend/internal/dto"
+	"github.com/amirullazmi0/kratify-backend/internal/model"
+	"github.com/amirullazmi0/kratify-backend/internal/repository"
+	"github.com/amirullazmi0/kratify-backend/pkg/logger"
+	"github.com/amirullazmi0/kratify-backend/pkg/oidc"
+	"go.uber.org/zap"
+)
+
+// oauthStateTTL is how long a user has to complete the login at the provider
+const oauthStateTTL = 10 * time.Minute
+
+type OAuthUsecase interface {
+	GetProviders() []dto.OAuthProviderResponse
+	BeginLogin(ctx context.Context, providerName string) (authURL string, stateCookie string, err error)
+	CompleteLogin(ctx context.Context, providerName string, req *dto.OAuthCallbackRequest, stateCookie string) (*dto.AuthResponse, error)
+}
+
+type oauthUsecase struct {
+	providers    *oidc.Registry
+	userRepo     repository.UserRepository
+	identityRepo repository.UserIdentityRepository
+	jwtCfg       *config.JWTConfig
+	appConfig    *config.AppConfig
+}
+
+// NewOAuthUsecase creates a new OAuth/OpenID Connect login usecase
+func NewOAuthUsecase(providers *oidc.Registry, userRepo repository.UserRepository, identityRepo repository.UserIdentityRepository, jwtCfg *config.JWTConfig, appConfig *config.AppConfig) OAuthUsecase {
+	return &oauthUsecase{
+		providers:    providers,
+		userRepo:     userRepo,
+		identityRepo: identityRepo,
+		jwtCfg:       jwtCfg,
+		appConfig:    appConfig,
+	}
+}
+
+func (u *oauthUsecase) GetProviders() []dto.OAuthProviderResponse {
+	providers := []dto.OAuthProviderResponse{}
+	for _, name := range u.providers.Names() {
+		providers = append(providers, dto.OAuthProviderResponse{
+			Name:     name,
+			LoginURL: fmt.Sprintf("%s/auth/oauth/%s", u.appConfig.APIBaseURL(), name),
+		})
+	}
+	return providers
+}
+
+func (u *oauthUsecase) BeginLogin(ctx context.Context, providerName string) (string, string, error) {
+	provider, err := u.providers.Get(providerName)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := oidc.RandomString(24)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := oidc.RandomString(24)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, provider.RedirectURL(u.appConfig.APIBaseURL()), state, nonce, challenge)
+	if err != nil {
+		return "", "", err
+	}
+
+	stateCookie, err := oidc.EncodeState(u.jwtCfg.Secret, oidc.LoginState{
+		Provider:     providerName,
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(oauthStateTTL).Unix(),
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return authURL, stateCookie, nil
+}
+
+func (u *oauthUsecase) CompleteLogin(ctx context.Context, providerName string, req *dto.OAuthCallbackRequest, stateCookie string) (*dto.AuthResponse, error) {
+	if req.Error != "" {
+		return nil, fmt.Errorf("login was not completed at the provider: %s", req.Error)
+	}
+
+	provider, err := u.providers.Get(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	loginState, err := oidc.DecodeState(u.jwtCfg.Secret, stateCookie)
+	if err != nil {
+		return nil, err
+	}
+	if loginState.Provider != providerName || loginState.State != req.State || req.Code == "" {
+		return nil, errors.New("invalid or expired login state")
+	}
+
+	token, err := provider.Exchange(ctx, provider.RedirectURL(u.appConfig.APIBaseURL()), req.Code, loginState.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := provider.VerifyIDToken(ctx, token.IDToken, loginState.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := u.resolveUser(providerName, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return issueTokens(u.userRepo, u.jwtCfg, user)
+}
+
+// resolveUser finds the user linked to the external identity, links it to an
+// existing account with the same verified email, or creates a new verified user
+func (u *oauthUsecase) resolveUser(providerName string, claims *oidc.IDTokenClaims) (*model.User, error) {
+	identity, err := u.identityRepo.FindByProviderSubject(providerName, claims.Subject)
+	if err == nil {
+		user, err := u.userRepo.FindByID(identity.UserID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, errors.New("linked account no longer exists")
+			}
+			return nil, err
+		}
+		return user, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	// Only a verified email may be used to link or create an account
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, errors.New("the provider did not return a verified email address")
+	}
+	email := claims.Email
+
+	user, err := u.userRepo.FindByEmail(email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	if user != nil {
+		// Never link to an unverified local account: whoever registered it may not own the email
+		if !user.IsActive {
+			return nil, errors.New("an unverified account already uses this email, please verify it first")
+		}
+	} else {
+		user, err = u.createUser(email, claims.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := u.identityRepo.Create(&model.UserIdentity{
+		UserID:   user.ID,
+		Provider: providerName,
+		Subject:  claims.Subject,
+		Email:    &email,
+	}); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Linked external identity", zap.String("user_id", user.ID), zap.String("provider", providerName))
+	return user, nil
+}
+
+func (u *oauthUsecase) createUser(email string, name string) (*model.User, error) {
+	if strings.TrimSpace(name) == "" {
+		name = strings.Split(email, "@")[0]
+	}
+
+	// Social accounts get an unguessable password; they sign in through the provider
+	password, err := oidc.RandomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &model.User{
+		Email:    email,
+		Password: password,
+		Name:     name,
+		Role:     "USER",
+		IsActive: true,
+	}
+	if err := user.HashPassword(); err != nil {
+		return nil, err
+	}
+
+	userID, err := u.userRepo.Create(user)
+	if err != nil {
+		return nil, err
+	}
+	user.ID = userID
+
+	return user, nil
+}
@@ -0,0 +1,186 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/amirullazmi0/kratify-backend/config"
+	"github.com/amirullazmi0/kratify-backend/internal/dto"
+	"github.com/amirullazmi0/kratify-backend/internal/model"
+	"github.com/amirullazmi0/kratify-backend/pkg/oidc"
+	"github.com/amirullazmi0/kratify-backend/pkg/oidc/oidctest"
+)
+
+// newTestOAuthUsecase returns an OAuth usecase with the fake provider registered as "test"
+func newTestOAuthUsecase(server *oidctest.Server, users *fakeUserRepository, identities *fakeUserIdentityRepository) OAuthUsecase {
+	providers := oidc.NewRegistry(&config.OAuthConfig{Providers: []config.OIDCProviderConfig{server.Config("test")}}, nil)
+	jwtCfg := &config.JWTConfig{Secret: "test-secret", ExpiredHour: 1}
+	return NewOAuthUsecase(providers, users, identities, jwtCfg, &config.AppConfig{Port: "8080"})
+}
+
+// oauthLogin signs in at the fake provider and completes the login
+func oauthLogin(t *testing.T, server *oidctest.Server, usecase OAuthUsecase) (*dto.AuthResponse, error) {
+	t.Helper()
+
+	authURL, stateCookie, err := usecase.BeginLogin(context.Background(), "test")
+	if err != nil {
+		t.Fatalf("BeginLogin() error = %v", err)
+	}
+	code, state, err := server.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("Authorize() error = %v", err)
+	}
+
+	return usecase.CompleteLogin(context.Background(), "test", &dto.OAuthCallbackRequest{Code: code, State: state}, stateCookie)
+}
+
+func TestOAuthLoginCreatesUserWithVerifiedEmail(t *testing.T) {
+	server := oidctest.NewServer(t)
+	users := newFakeUserRepository()
+	identities := &fakeUserIdentityRepository{}
+	usecase := newTestOAuthUsecase(server, users, identities)
+
+	resp, err := oauthLogin(t, server, usecase)
+	if err != nil {
+		t.Fatalf("CompleteLogin() error = %v", err)
+	}
+	if resp.AccessToken == "" || resp.User.Email != "jane@example.com" || resp.User.Name != "Jane Doe" {
+		t.Errorf("CompleteLogin() = %+v", resp)
+	}
+
+	user, err := users.FindByEmail("jane@example.com")
+	if err != nil || !user.IsActive || user.Role != "USER" {
+		t.Fatalf("created user = %+v, %v", user, err)
+	}
+	if identity, err := identities.FindByProviderSubject("test", "subject-1"); err != nil || identity.UserID != user.ID {
+		t.Errorf("identity = %+v, %v", identity, err)
+	}
+
+	// Signing in again uses the linked identity
+	if _, err := oauthLogin(t, server, usecase); err != nil {
+		t.Fatalf("second CompleteLogin() error = %v", err)
+	}
+	if users.count() != 1 || len(identities.identities) != 1 {
+		t.Errorf("second login created %d users and %d identities", users.count(), len(identities.identities))
+	}
+}
+
+func TestOAuthLoginLinkingRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing *model.User
+		identity oidctest.Identity
+		wantErr  string
+		wantUser string
+	}{
+		{
+			name:     "links a verified email to an active account",
+			existing: &model.User{ID: "local-1", Email: "jane@example.com", Role: "USER", IsActive: true},
+			identity: oidctest.Identity{Subject: "subject-1", Email: "jane@example.com", EmailVerified: true},
+			wantUser: "local-1",
+		},
+		{
+			name:     "does not link to an unverified account",
+			existing: &model.User{ID: "local-1", Email: "jane@example.com", Role: "USER", IsActive: false},
+			identity: oidctest.Identity{Subject: "subject-1", Email: "jane@example.com", EmailVerified: true},
+			wantErr:  "unverified account",
+		},
+		{
+			name:     "does not link an unverified email",
+			existing: &model.User{ID: "local-1", Email: "jane@example.com", Role: "USER", IsActive: true},
+			identity: oidctest.Identity{Subject: "subject-1", Email: "jane@example.com", EmailVerified: false},
+			wantErr:  "verified email",
+		},
+		{
+			name:     "does not create an account for an unverified email",
+			identity: oidctest.Identity{Subject: "subject-1", Email: "new@example.com", EmailVerified: false},
+			wantErr:  "verified email",
+		},
+		{
+			name:     "does not create an account without email",
+			identity: oidctest.Identity{Subject: "subject-1", EmailVerified: true},
+			wantErr:  "verified email",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := oidctest.NewServer(t)
+			server.SetIdentity(tt.identity)
+			users := newFakeUserRepository()
+			if tt.existing != nil {
+				users = newFakeUserRepository(tt.existing)
+			}
+			identities := &fakeUserIdentityRepository{}
+
+			resp, err := oauthLogin(t, server, newTestOAuthUsecase(server, users, identities))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("CompleteLogin() error = %v, want %q", err, tt.wantErr)
+				}
+				if len(identities.identities) != 0 {
+					t.Errorf("identity linked despite the error: %+v", identities.identities)
+				}
+				if tt.existing == nil && users.count() != 0 {
+					t.Errorf("user created despite the error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("CompleteLogin() error = %v", err)
+			}
+			if resp.User.ID != tt.wantUser {
+				t.Errorf("signed in as %s, want %s", resp.User.ID, tt.wantUser)
+			}
+		})
+	}
+}
+
+func TestOAuthLoginUsesLinkedIdentityRegardlessOfEmail(t *testing.T) {
+	server := oidctest.NewServer(t)
+	// The provider no longer reports a verified email, but the subject is linked already
+	server.SetIdentity(oidctest.Identity{Subject: "subject-1", Email: "changed@example.com", EmailVerified: false})
+	users := newFakeUserRepository(&model.User{ID: "local-1", Email: "jane@example.com", Role: "USER", IsActive: true})
+	identities := &fakeUserIdentityRepository{}
+	identities.Create(&model.UserIdentity{UserID: "local-1", Provider: "test", Subject: "subject-1"})
+
+	resp, err := oauthLogin(t, server, newTestOAuthUsecase(server, users, identities))
+	if err != nil {
+		t.Fatalf("CompleteLogin() error = %v", err)
+	}
+	if resp.User.ID != "local-1" {
+		t.Errorf("signed in as %s, want local-1", resp.User.ID)
+	}
+}
+
+func TestOAuthLoginRejectsLinkedIdentityOfDeletedUser(t *testing.T) {
+	server := oidctest.NewServer(t)
+	identities := &fakeUserIdentityRepository{}
+	identities.Create(&model.UserIdentity{UserID: "deleted-user", Provider: "test", Subject: "subject-1"})
+
+	_, err := oauthLogin(t, server, newTestOAuthUsecase(server, newFakeUserRepository(), identities))
+	if err == nil || !strings.Contains(err.Error(), "no longer exists") {
+		t.Fatalf("CompleteLogin() error = %v, want linked account no longer exists", err)
+	}
+}
+
+func TestOAuthLoginRejectsStateMismatch(t *testing.T) {
+	server := oidctest.NewServer(t)
+	usecase := newTestOAuthUsecase(server, newFakeUserRepository(), &fakeUserIdentityRepository{})
+
+	authURL, stateCookie, err := usecase.BeginLogin(context.Background(), "test")
+	if err != nil {
+		t.Fatalf("BeginLogin() error = %v", err)
+	}
+	code, _, err := server.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("Authorize() error = %v", err)
+	}
+
+	req := &dto.OAuthCallbackRequest{Code: code, State: "forged-state"}
+	if _, err := usecase.CompleteLogin(context.Background(), "test", req, stateCookie); err == nil {
+		t.Fatal("CompleteLogin() accepted a forged state")
+	}
+}
@@ -0,0 +1,279 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/amirullazmi0/kratify-backend/config"
+	"github.com/amirullazmi0/kratify-backend/internal/dto"
+	"github.com/amirullazmi0/kratify-backend/internal/model"
+	"github.com/amirullazmi0/kratify-backend/internal/repository"
+	"github.com/amirullazmi0/kratify-backend/pkg/cache"
+	"github.com/amirullazmi0/kratify-backend/pkg/geo"
+	"github.com/amirullazmi0/kratify-backend/pkg/logger"
+	"github.com/amirullazmi0/kratify-backend/pkg/regions"
+	"go.uber.org/zap"
+)
+
+type RegionUsecase interface {
+	GetProvinces() ([]dto.RegionResponse, error)
+	GetRegencies(provinceCode string) ([]dto.RegionResponse, error)
+	GetDistricts(regencyCode string) ([]dto.RegionResponse, error)
+	GetVillages(districtCode string) ([]dto.RegionResponse, error)
+	SearchRegions(req *dto.RegionSearchRequest) ([]dto.RegionSuggestionResponse, error)
+	LookupPostalCode(postalCode string) ([]dto.PostalCodeCandidateResponse, error)
+	SeedRegions() error
+}
+
+// Region data only changes when it is seeded, so lookups are cached for a while
+const (
+	regionCacheTTL        = 10 * time.Minute
+	regionCacheMaxEntries = 5000
+)
+
+// searchableLevels are the levels a search returns when no level is requested
+var searchableLevels = []string{regions.LevelRegency, regions.LevelDistrict, regions.LevelVillage}
+
+type regionUsecase struct {
+	regionRepo  repository.RegionRepository
+	regionsCfg  *config.RegionsConfig
+	searchCache *cache.Cache[[]dto.RegionSuggestionResponse]
+	postalCache *cache.Cache[[]dto.PostalCodeCandidateResponse]
+}
+
+func NewRegionUsecase(regionRepo repository.RegionRepository, regionsCfg *config.RegionsConfig) RegionUsecase {
+	return &regionUsecase{
+		regionRepo:  regionRepo,
+		regionsCfg:  regionsCfg,
+		searchCache: cache.New[[]dto.RegionSuggestionResponse](regionCacheTTL, regionCacheMaxEntries),
+		postalCache: cache.New[[]dto.PostalCodeCandidateResponse](regionCacheTTL, regionCacheMaxEntries),
+	}
+}
+
+func (u *regionUsecase) GetProvinces() ([]dto.RegionResponse, error) {
+	provinces, err := u.regionRepo.FindByLevel(regions.LevelProvince)
+	if err != nil {
+		return nil, err
+	}
+
+	return toRegionResponses(provinces), nil
+}
+
+func (u *regionUsecase) GetRegencies(provinceCode string) ([]dto.RegionResponse, error) {
+	return u.getChildren(provinceCode, regions.LevelProvince)
+}
+
+func (u *regionUsecase) GetDistricts(regencyCode string) ([]dto.RegionResponse, error) {
+	return u.getChildren(regencyCode, regions.LevelRegency)
+}
+
+func (u *regionUsecase) GetVillages(districtCode string) ([]dto.RegionResponse, error) {
+	return u.getChildren(districtCode, regions.LevelDistrict)
+}
+
+// getChildren lists the regions directly below code, which must be a region of the given level
+func (u *regionUsecase) getChildren(code string, level string) ([]dto.RegionResponse, error) {
+	parent, err := u.regionRepo.FindByCode(code)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New(strings.ToLower(level) + " not found")
+		}
+		return nil, err
+	}
+	if parent.Level != level {
+		return nil, errors.New(strings.ToLower(level) + " not found")
+	}
+
+	children, err := u.regionRepo.FindChildren(parent.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	return toRegionResponses(children), nil
+}
+
+// SearchRegions returns autocomplete suggestions for a free-text query
+func (u *regionUsecase) SearchRegions(req *dto.RegionSearchRequest) ([]dto.RegionSuggestionResponse, error) {
+	query := strings.Join(strings.Fields(strings.ToLower(req.Query)), " ")
+
+	levels := searchableLevels
+	if req.Level != "" {
+		levels = []string{strings.ToUpper(req.Level)}
+	}
+
+	key := fmt.Sprintf("%s|%s|%d", query, strings.Join(levels, ","), req.Limit)
+	if cached, ok := u.searchCache.Get(key); ok {
+		return cached, nil
+	}
+
+	matches, err := u.regionRepo.Search(query, levels, req.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []dto.RegionSuggestionResponse{}
+	for i := range matches {
+		result = append(result, toRegionSuggestion(&matches[i]))
+	}
+
+	u.searchCache.Set(key, result)
+	return result, nil
+}
+
+// LookupPostalCode returns the districts whose villages use the postal code
+func (u *regionUsecase) LookupPostalCode(postalCode string) ([]dto.PostalCodeCandidateResponse, error) {
+	if cached, ok := u.postalCache.Get(postalCode); ok {
+		return cached, nil
+	}
+
+	villages, err := u.regionRepo.FindByPostalCode(postalCode)
+	if err != nil {
+		return nil, err
+	}
+
+	var districtCodes []string
+	villagesByDistrict := make(map[string][]dto.RegionSuggestionResponse)
+	for i := range villages {
+		village := &villages[i]
+		if village.ParentCode == nil {
+			continue
+		}
+		if _, ok := villagesByDistrict[*village.ParentCode]; !ok {
+			districtCodes = append(districtCodes, *village.ParentCode)
+		}
+		villagesByDistrict[*village.ParentCode] = append(villagesByDistrict[*village.ParentCode], toRegionSuggestion(village))
+	}
+
+	districts, err := u.regionRepo.FindByCodes(districtCodes)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []dto.PostalCodeCandidateResponse{}
+	for i := range districts {
+		result = append(result, dto.PostalCodeCandidateResponse{
+			RegionSuggestionResponse: toRegionSuggestion(&districts[i]),
+			Villages:                 villagesByDistrict[districts[i].Code],
+		})
+	}
+
+	u.postalCache.Set(postalCode, result)
+	return result, nil
+}
+
+// SeedRegions loads the configured region dataset into the database. Unchanged regions
+// are left untouched, so it is safe to run on every start. Without a dataset the
+// regions seeded before are used; it fails when there are none, as addresses cannot be
+// saved without them.
+func (u *regionUsecase) SeedRegions() error {
+	records, err := regions.Load(u.regionsCfg.DatasetPath)
+	switch {
+	case err == nil:
+		if err := u.regionRepo.Upsert(records); err != nil {
+			return err
+		}
+		u.searchCache.Clear()
+		u.postalCache.Clear()
+		logger.Info("Region reference data seeded", zap.Int("regions", len(records)))
+	case !errors.Is(err, regions.ErrNoDataset):
+		return err
+	}
+
+	provinces, err := u.regionRepo.FindByLevel(regions.LevelProvince)
+	if err != nil {
+		return err
+	}
+	if len(provinces) == 0 {
+		return errors.New("region reference data is missing: set REGIONS_DATASET_PATH to the Kemendagri region dataset")
+	}
+	if len(provinces) < regions.Provinces {
+		logger.Warn("Region reference data is incomplete; addresses outside its provinces cannot be saved",
+			zap.Int("provinces", len(provinces)), zap.Int("expected", regions.Provinces))
+	}
+
+	return nil
+}
+
+func toRegionResponses(list []model.Region) []dto.RegionResponse {
+	result := []dto.RegionResponse{}
+	for _, region := range list {
+		result = append(result, dto.RegionResponse{
+			Code:       region.Code,
+			ParentCode: region.ParentCode,
+			Level:      region.Level,
+			Name:       region.Name,
+			PostalCode: region.PostalCode,
+			FullName:   region.FullName,
+		})
+	}
+	return result
+}
+
+func toRegionSuggestion(region *model.Region) dto.RegionSuggestionResponse {
+	suggestion := dto.RegionSuggestionResponse{
+		ID:         region.Code,
+		Level:      region.Level,
+		Name:       region.Name,
+		FullName:   region.FullName,
+		PostalCode: region.PostalCode,
+	}
+
+	lineage := regions.Lineage(region.Code)
+	codes := []*string{&suggestion.ProvinceCode, &suggestion.RegencyCode, &suggestion.DistrictCode, &suggestion.VillageCode}
+	for i := 0; i < len(lineage) && i < len(codes); i++ {
+		*codes[i] = lineage[i]
+	}
+
+	return suggestion
+}
+
+// NewGeocoder returns the configured geocoder, falling back to region centroids
+// when it has no match or when no provider is configured
+func NewGeocoder(geocoderCfg *config.GeocoderConfig, appConfig *config.AppConfig, regionRepo repository.RegionRepository) geo.Geocoder {
+	var chain geo.Chain
+
+	switch geocoderCfg.Provider {
+	case "nominatim":
+		userAgent := geocoderCfg.UserAgent
+		if userAgent == "" {
+			userAgent = appConfig.Name
+		}
+		chain = append(chain, geo.NewNominatimGeocoder(geocoderCfg.URL, userAgent, nil))
+	case "":
+	default:
+		logger.Warn("Unknown geocoder provider, using region centroids only", zap.String("provider", geocoderCfg.Provider))
+	}
+
+	return append(chain, &regionCentroidGeocoder{regionRepo: regionRepo})
+}
+
+// regionCentroidGeocoder places an address at the centroid of its most specific region that has one
+type regionCentroidGeocoder struct {
+	regionRepo repository.RegionRepository
+}
+
+func (g *regionCentroidGeocoder) Geocode(ctx context.Context, query geo.Query) (*geo.Result, error) {
+	if query.RegionCode == "" {
+		return nil, geo.ErrNoMatch
+	}
+
+	lineage, err := g.regionRepo.FindWithAncestors(query.RegionCode)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(lineage) - 1; i >= 0; i-- {
+		if lineage[i].Latitude != nil && lineage[i].Longitude != nil {
+			return &geo.Result{
+				Point:  geo.Point{Latitude: *lineage[i].Latitude, Longitude: *lineage[i].Longitude},
+				Source: geo.SourceRegionCentroid,
+			}, nil
+		}
+	}
+
+	return nil, geo.ErrNoMatch
+}
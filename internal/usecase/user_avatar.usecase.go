@@ -0,0 +1,118 @@
+package usecase
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/amirullazmi0/kratify-backend/internal/dto"
+	"github.com/amirullazmi0/kratify-backend/internal/model"
+	"github.com/amirullazmi0/kratify-backend/pkg/avatar"
+	"github.com/amirullazmi0/kratify-backend/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// DeleteAvatar deletes the user's profile image so the generated avatar is shown again.
+// A file that cannot be deleted now is released to the garbage collection.
+func (u *userUsecase) DeleteAvatar(userID string) (*dto.UserResponse, error) {
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+	if user.AvatarID == nil {
+		return u.profileResponse(user)
+	}
+
+	image, err := u.avatarImage(user)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.userRepo.UpdateAvatar(user.ID, nil); err != nil {
+		return nil, err
+	}
+	user.AvatarID = nil
+
+	if image != nil {
+		if err := u.attachmentRepo.RemoveReference(image.ID, model.AttachmentReferenceUser, user.ID); err != nil {
+			logger.Error("Failed to release profile image", zap.String("attachment_id", image.ID), zap.Error(err))
+		} else if err := u.attachmentRepo.Delete(image); err != nil {
+			logger.Error("Failed to delete profile image", zap.String("attachment_id", image.ID), zap.Error(err))
+		}
+	}
+
+	return u.profileResponse(user)
+}
+
+// profileResponse maps the user to a response including their avatar
+func (u *userUsecase) profileResponse(user *model.User) (*dto.UserResponse, error) {
+	image, err := u.avatarImage(user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.UserResponse{
+		ID:     user.ID,
+		Email:  user.Email,
+		Name:   user.Name,
+		Avatar: avatarResponse(user, image),
+	}, nil
+}
+
+// avatarImage returns the user's profile image, or nil when they have none
+func (u *userUsecase) avatarImage(user *model.User) (*model.Attachment, error) {
+	if user.AvatarID == nil {
+		return nil, nil
+	}
+
+	image, err := u.attachmentRepo.FindByID(*user.AvatarID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return image, err
+}
+
+// avatarImages returns the profile images of the users by attachment ID
+func (u *userUsecase) avatarImages(users []model.User) (map[string]*model.Attachment, error) {
+	var ids []string
+	for _, user := range users {
+		if user.AvatarID != nil {
+			ids = append(ids, *user.AvatarID)
+		}
+	}
+
+	attachments, err := u.attachmentRepo.FindByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	images := make(map[string]*model.Attachment, len(attachments))
+	for i := range attachments {
+		images[attachments[i].ID] = &attachments[i]
+	}
+	return images, nil
+}
+
+// avatarResponse links the profile image once it is ready, with its smallest variant
+// (the thumbnail by default) as thumbnail; otherwise the user's initials are generated
+func avatarResponse(user *model.User, image *model.Attachment) *dto.AvatarResponse {
+	if image != nil {
+		attachment := toAttachmentResponse(image)
+		if attachment.URL != "" {
+			response := &dto.AvatarResponse{URL: attachment.URL, ThumbnailURL: attachment.URL}
+			smallest := 0
+			for _, variant := range attachment.Variants {
+				if smallest == 0 || variant.Width*variant.Height < smallest {
+					smallest = variant.Width * variant.Height
+					response.ThumbnailURL = variant.URL
+				}
+			}
+			return response
+		}
+	}
+
+	generated := avatar.DataURI(user.Name, user.ID)
+	return &dto.AvatarResponse{URL: generated, ThumbnailURL: generated, IsDefault: true}
+}
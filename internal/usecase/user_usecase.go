@@ -3,7 +3,6 @@ package usecase
 import (
 	"database/sql"
 	"errors"
-	"fmt"
 	"log"
 	"time"
 
@@ -15,6 +14,9 @@ import (
 	"github.com/amirullazmi0/kratify-backend/pkg/email"
 )
 
+// ErrAccountDeactivated is returned by every login flow for a self-deactivated account
+var ErrAccountDeactivated = errors.New("your account is deactivated; use the reactivation link sent to your email to restore it")
+
 type UserUsecase interface {
 	Register(req *dto.RegisterRequest) (*dto.AuthResponse, error)
 	VerifyEmail(token string) error
@@ -24,23 +26,29 @@ type UserUsecase interface {
 	GetProfile(userID string) (*dto.UserResponse, error)
 	GetAllUsers() ([]dto.UserResponse, error)
 	UpdateProfile(userID string, req *dto.UpdateUserRequest) (*dto.UserResponse, error)
+	DeleteAvatar(userID string) (*dto.UserResponse, error)
 	ChangePassword(userID string, req *dto.ChangePasswordRequest) error
 	DeleteUser(userID string) error
+	Impersonate(actorID string, targetID string, req *dto.ImpersonateRequest, meta dto.RequestMetadata) (*dto.ImpersonationResponse, error)
 }
 type userUsecase struct {
-	userRepo     repository.UserRepository
-	jwtCfg       *config.JWTConfig
-	emailService *email.EmailService
-	appConfig    *config.AppConfig
+	userRepo       repository.UserRepository
+	attachmentRepo repository.AttachmentRepository
+	auditLogRepo   repository.AuditLogRepository
+	jwtCfg         *config.JWTConfig
+	emailService   *email.EmailService
+	appConfig      *config.AppConfig
 }
 
 // NewUserUsecase creates a new user usecase
-func NewUserUsecase(userRepo repository.UserRepository, jwtCfg *config.JWTConfig, emailService *email.EmailService, appConfig *config.AppConfig) UserUsecase {
+func NewUserUsecase(userRepo repository.UserRepository, attachmentRepo repository.AttachmentRepository, auditLogRepo repository.AuditLogRepository, jwtCfg *config.JWTConfig, emailService *email.EmailService, appConfig *config.AppConfig) UserUsecase {
 	return &userUsecase{
-		userRepo:     userRepo,
-		jwtCfg:       jwtCfg,
-		emailService: emailService,
-		appConfig:    appConfig,
+		userRepo:       userRepo,
+		attachmentRepo: attachmentRepo,
+		auditLogRepo:   auditLogRepo,
+		jwtCfg:         jwtCfg,
+		emailService:   emailService,
+		appConfig:      appConfig,
 	}
 }
 
@@ -84,7 +92,7 @@ func (u *userUsecase) Register(req *dto.RegisterRequest) (*dto.AuthResponse, err
 
 	// Send verification email in background (goroutine)
 	go func() {
-		baseURL := fmt.Sprintf("http://localhost:%s/api", u.appConfig.Port)
+		baseURL := u.appConfig.APIBaseURL()
 		if err := u.emailService.SendVerificationEmail(user.Email, user.Name, verificationToken, baseURL); err != nil {
 			log.Printf("Failed to send verification email to %s: %v", user.Email, err)
 		} else {
@@ -143,27 +151,37 @@ func (u *userUsecase) Login(req *dto.LoginRequest) (*dto.AuthResponse, error) {
 		return nil, errors.New("invalid email or password")
 	}
 
+	return issueTokens(u.userRepo, u.jwtCfg, user)
+}
+
+// issueTokens generates a new access/refresh token pair for the user and stores
+// the refresh token. Every login flow (password, OAuth, magic link) goes through here.
+func issueTokens(userRepo repository.UserRepository, jwtCfg *config.JWTConfig, user *model.User) (*dto.AuthResponse, error) {
+	if user.IsDeactivated() {
+		return nil, ErrAccountDeactivated
+	}
+
 	// Generate tokens
-	accessToken, err := middleware.GenerateToken(user.ID, user.Email, user.Role, u.jwtCfg)
+	accessToken, err := middleware.GenerateToken(user.ID, user.Email, user.Role, jwtCfg)
 	if err != nil {
 		return nil, err
 	}
 
-	refreshToken, err := middleware.GenerateRefreshToken(user.ID, user.Email, user.Role, u.jwtCfg)
+	refreshToken, err := middleware.GenerateRefreshToken(user.ID, user.Email, user.Role, jwtCfg)
 	if err != nil {
 		return nil, err
 	}
 
 	// Save refresh token to database
 	refreshTokenExpiry := time.Now().Add(7 * 24 * time.Hour)
-	if err := u.userRepo.SaveRefreshToken(user.ID, refreshToken, refreshTokenExpiry); err != nil {
+	if err := userRepo.SaveRefreshToken(user.ID, refreshToken, refreshTokenExpiry); err != nil {
 		return nil, err
 	}
 
 	return &dto.AuthResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
-		ExpiresIn:    int64(u.jwtCfg.ExpiredHour * 3600),
+		ExpiresIn:    int64(jwtCfg.ExpiredHour * 3600),
 		User: dto.UserResponse{
 			ID:    user.ID,
 			Email: user.Email,
@@ -181,11 +199,7 @@ func (u *userUsecase) GetProfile(userID string) (*dto.UserResponse, error) {
 		return nil, err
 	}
 
-	return &dto.UserResponse{
-		ID:    user.ID,
-		Email: user.Email,
-		Name:  user.Name,
-	}, nil
+	return u.profileResponse(user)
 }
 
 func (u *userUsecase) GetAllUsers() ([]dto.UserResponse, error) {
@@ -194,12 +208,22 @@ func (u *userUsecase) GetAllUsers() ([]dto.UserResponse, error) {
 		return nil, err
 	}
 
+	images, err := u.avatarImages(users)
+	if err != nil {
+		return nil, err
+	}
+
 	var response []dto.UserResponse
 	for _, user := range users {
+		var image *model.Attachment
+		if user.AvatarID != nil {
+			image = images[*user.AvatarID]
+		}
 		response = append(response, dto.UserResponse{
-			ID:    user.ID,
-			Email: user.Email,
-			Name:  user.Name,
+			ID:     user.ID,
+			Email:  user.Email,
+			Name:   user.Name,
+			Avatar: avatarResponse(&user, image),
 		})
 	}
 
@@ -224,11 +248,7 @@ func (u *userUsecase) UpdateProfile(userID string, req *dto.UpdateUserRequest) (
 		return nil, err
 	}
 
-	return &dto.UserResponse{
-		ID:    user.ID,
-		Email: user.Email,
-		Name:  user.Name,
-	}, nil
+	return u.profileResponse(user)
 }
 
 func (u *userUsecase) ChangePassword(userID string, req *dto.ChangePasswordRequest) error {
@@ -276,34 +296,8 @@ func (u *userUsecase) RefreshToken(req *dto.RefreshTokenRequest) (*dto.AuthRespo
 		return nil, err
 	}
 
-	// Generate new access token
-	accessToken, err := middleware.GenerateToken(user.ID, user.Email, user.Role, u.jwtCfg)
-	if err != nil {
-		return nil, err
-	}
-
-	// Generate new refresh token
-	newRefreshToken, err := middleware.GenerateRefreshToken(user.ID, user.Email, user.Role, u.jwtCfg)
-	if err != nil {
-		return nil, err
-	}
-
-	// Update refresh token in database
-	refreshTokenExpiry := time.Now().Add(7 * 24 * time.Hour)
-	if err := u.userRepo.SaveRefreshToken(user.ID, newRefreshToken, refreshTokenExpiry); err != nil {
-		return nil, err
-	}
-
-	return &dto.AuthResponse{
-		AccessToken:  accessToken,
-		RefreshToken: newRefreshToken,
-		ExpiresIn:    int64(u.jwtCfg.ExpiredHour * 3600),
-		User: dto.UserResponse{
-			ID:    user.ID,
-			Email: user.Email,
-			Name:  user.Name,
-		},
-	}, nil
+	// Rotate the token pair
+	return issueTokens(u.userRepo, u.jwtCfg, user)
 }
 
 func (u *userUsecase) Logout(userID string) error {
@@ -319,3 +313,77 @@ func (u *userUsecase) Logout(userID string) error {
 	// Clear refresh token
 	return u.userRepo.ClearRefreshToken(userID)
 }
+
+// Impersonate issues a short-lived access token for the target user carrying the admin in the act claim.
+// No refresh token is issued, so the session ends when the token expires.
+func (u *userUsecase) Impersonate(actorID string, targetID string, req *dto.ImpersonateRequest, meta dto.RequestMetadata) (*dto.ImpersonationResponse, error) {
+	if actorID == targetID {
+		return nil, errors.New("cannot impersonate yourself")
+	}
+
+	actor, err := u.userRepo.FindByID(actorID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+
+	target, err := u.userRepo.FindByID(targetID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+	if target.Role == "SUPERADMIN" {
+		return nil, errors.New("cannot impersonate a superadmin")
+	}
+	if !target.IsActive || target.IsDeactivated() {
+		return nil, errors.New("cannot impersonate an inactive user")
+	}
+
+	ttl := time.Duration(u.jwtCfg.ImpersonationMinutes) * time.Minute
+	if ttl <= 0 {
+		ttl = 15 * time.Minute
+	}
+
+	accessToken, err := middleware.GenerateImpersonationToken(target.ID, target.Email, target.Role, middleware.ActorClaims{
+		UserID: actor.ID,
+		Email:  actor.Email,
+	}, ttl, u.jwtCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Refuse to hand out the token if the audit trail cannot record it
+	if _, err := u.auditLogRepo.Create(&model.AuditLog{
+		ActorID:       &actor.ID,
+		SubjectUserID: &target.ID,
+		Action:        model.AuditActionImpersonationStart,
+		Metadata: map[string]interface{}{
+			"reason":     req.Reason,
+			"expires_in": int64(ttl.Seconds()),
+		},
+		IP:        meta.IP,
+		UserAgent: meta.UserAgent,
+		RequestID: meta.RequestID,
+	}); err != nil {
+		return nil, err
+	}
+
+	return &dto.ImpersonationResponse{
+		AccessToken: accessToken,
+		ExpiresIn:   int64(ttl.Seconds()),
+		User: dto.UserResponse{
+			ID:    target.ID,
+			Email: target.Email,
+			Name:  target.Name,
+		},
+		Impersonator: dto.UserResponse{
+			ID:    actor.ID,
+			Email: actor.Email,
+			Name:  actor.Name,
+		},
+	}, nil
+}
@@ -16,10 +16,16 @@ import (
 	"github.com/amirullazmi0/kratify-backend/internal/repository"
 	"github.com/amirullazmi0/kratify-backend/internal/usecase"
 	"github.com/amirullazmi0/kratify-backend/pkg/database"
+	"github.com/amirullazmi0/kratify-backend/pkg/database/migrate"
 	"github.com/amirullazmi0/kratify-backend/pkg/email"
-	"github.com/amirullazmi0/kratify-backend/pkg/imagekit"
 	"github.com/amirullazmi0/kratify-backend/pkg/logger"
+	"github.com/amirullazmi0/kratify-backend/pkg/oidc"
+	"github.com/amirullazmi0/kratify-backend/pkg/scanner"
+	"github.com/amirullazmi0/kratify-backend/pkg/scheduler"
+	"github.com/amirullazmi0/kratify-backend/pkg/storage"
+	"github.com/amirullazmi0/kratify-backend/pkg/tus"
 	"github.com/amirullazmi0/kratify-backend/pkg/validator"
+	"github.com/amirullazmi0/kratify-backend/prisma"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-contrib/requestid"
@@ -50,6 +56,11 @@ import (
 // @name Authorization
 // @description Type "Bearer" followed by a space and JWT token.
 
+// @securityDefinitions.apikey APIKeyAuth
+// @in header
+// @name X-API-Key
+// @description Personal API key created from /api/users/api-keys.
+
 func main() {
 	// Load configuration
 	cfg, err := config.LoadConfig()
@@ -84,28 +95,102 @@ func main() {
 	}
 	defer db.Close()
 
+	// Database migrations: "migrate up|down [steps]|status" runs them and exits
+	migrator, err := migrate.New(db.DB, prisma.Migrations())
+	if err != nil {
+		logger.Fatal("Failed to load migrations", zap.Error(err))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate.Run(context.Background(), migrator, os.Args[2:], os.Stdout); err != nil {
+			logger.Fatal("Migration failed", zap.Error(err))
+		}
+		return
+	}
+	if cfg.Database.AutoMigrate {
+		if _, err := migrator.Up(context.Background()); err != nil {
+			logger.Fatal("Failed to migrate database", zap.Error(err))
+		}
+	}
+
 	// Initialize email service
 	emailService := email.NewEmailService(&cfg.SMTP)
 
+	// Initialize audit trail
+	auditLogRepo := repository.NewAuditLogRepository(db.DB)
+
+	// Initialize file storage
+	storageProvider, err := storage.New(&cfg.Storage, &cfg.ImageKit, &cfg.App)
+	if err != nil {
+		logger.Fatal("Failed to initialize file storage", zap.Error(err))
+	}
+	logger.Info("File storage initialized", zap.String("provider", storageProvider.Name()))
+	attachmentRepo := repository.NewAttachmentRepository(db.DB, storageProvider)
+
 	// Initialize usecases
 	userRepo := repository.NewUserRepository(db.DB)
-	userUsecase := usecase.NewUserUsecase(userRepo, &cfg.JWT, emailService, &cfg.App)
+	userUsecase := usecase.NewUserUsecase(userRepo, attachmentRepo, auditLogRepo, &cfg.JWT, emailService, &cfg.App)
 	userHandler := handler.NewUserHandler(userUsecase)
 
+	// Initialize magic link usecase
+	magicLinkRepo := repository.NewMagicLinkRepository(db.DB)
+	magicLinkUsecase := usecase.NewMagicLinkUsecase(magicLinkRepo, userRepo, &cfg.JWT, emailService, &cfg.App)
+	magicLinkHandler := handler.NewMagicLinkHandler(magicLinkUsecase)
+
+	// Initialize invitation usecase
+	invitationRepo := repository.NewInvitationRepository(db.DB)
+	invitationUsecase := usecase.NewInvitationUsecase(invitationRepo, userRepo, &cfg.JWT, emailService, &cfg.App)
+	invitationHandler := handler.NewInvitationHandler(invitationUsecase)
+
+	// Initialize OAuth (OpenID Connect) usecase
+	oidcProviders := oidc.NewRegistry(&cfg.OAuth, nil)
+	userIdentityRepo := repository.NewUserIdentityRepository(db.DB)
+	oauthUsecase := usecase.NewOAuthUsecase(oidcProviders, userRepo, userIdentityRepo, &cfg.JWT, &cfg.App)
+	oauthHandler := handler.NewOAuthHandler(oauthUsecase)
+
+	// Initialize API key usecase
+	apiKeyRepo := repository.NewAPIKeyRepository(db.DB)
+	apiKeyUsecase := usecase.NewAPIKeyUsecase(apiKeyRepo, userRepo)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyUsecase)
+
+	// Initialize region reference data
+	regionRepo := repository.NewRegionRepository(db.DB)
+	regionUsecase := usecase.NewRegionUsecase(regionRepo, &cfg.Regions)
+	regionHandler := handler.NewRegionHandler(regionUsecase)
+	if err := regionUsecase.SeedRegions(); err != nil {
+		logger.Fatal("Failed to seed region reference data", zap.Error(err))
+	}
+
 	// Initialize address usecase
 	addressRepo := repository.NewAddressRepository(db.DB)
-	addressUsecase := usecase.NewAddressUsecase(addressRepo, &cfg.JWT)
+	geocoder := usecase.NewGeocoder(&cfg.Geocoder, &cfg.App, regionRepo)
+	addressUsecase := usecase.NewAddressUsecase(addressRepo, regionRepo, geocoder, &cfg.JWT)
 	addressHandler := handler.NewAddressHandler(addressUsecase)
 
 	// Initialize attachment usecase
-	imageKitService, err := imagekit.NewImageKitService(cfg.ImageKit.PublicKey, cfg.ImageKit.PrivateKey, cfg.ImageKit.UrlEndpoint)
+	resumableUploads, err := tus.NewStore(cfg.Storage.Tus.Path)
 	if err != nil {
-		logger.Fatal("Failed to initialize ImageKit service", zap.Error(err))
+		logger.Fatal("Failed to initialize resumable upload staging", zap.Error(err))
 	}
-	attachmentRepo := repository.NewAttachmentRepository(db.DB, imageKitService)
-	attachmentUsecase := usecase.NewAttachmentUsecase(attachmentRepo)
+	var fileScanner scanner.Scanner
+	if cfg.Scanner.ClamAVAddress != "" {
+		fileScanner = scanner.NewClamAV(cfg.Scanner.ClamAVAddress, time.Duration(cfg.Scanner.TimeoutSeconds)*time.Second)
+		logger.Info("Malware scanning enabled", zap.String("clamd", cfg.Scanner.ClamAVAddress))
+	}
+	attachmentUsecase := usecase.NewAttachmentUsecase(attachmentRepo, userRepo, auditLogRepo, fileScanner, resumableUploads, &cfg.Storage, &cfg.Image)
 	attachmentHandler := handler.NewAttachmentHandler(attachmentUsecase)
 
+	// Initialize account (data export and erasure) usecase
+	accountUsecase := usecase.NewAccountUsecase(userRepo, addressRepo, attachmentRepo, apiKeyRepo, userIdentityRepo, auditLogRepo, emailService, &cfg.Account, &cfg.App)
+	accountHandler := handler.NewAccountHandler(accountUsecase)
+
+	// Background jobs
+	jobs := scheduler.New()
+	jobs.Every("account-erasure", time.Hour, accountUsecase.PurgeDueAccounts)
+	jobs.Every("expired-uploads", 15*time.Minute, attachmentUsecase.PurgeExpiredUploads)
+	jobs.Every("expired-resumable-uploads", time.Hour, attachmentUsecase.PurgeExpiredResumableUploads)
+	jobs.Every("attachment-scan-retry", 5*time.Minute, attachmentUsecase.ScanPendingAttachments)
+	jobs.Every("attachment-gc", time.Hour, attachmentUsecase.PurgeUnreferencedAttachments)
+
 	// Setup Gin
 	if !cfg.App.Debug {
 		gin.SetMode(gin.ReleaseMode)
@@ -117,11 +202,12 @@ func main() {
 	router.Use(middleware.Recovery())
 	router.Use(requestid.New())
 	router.Use(middleware.Logger())
+	router.Use(middleware.ImpersonationAudit(auditLogRepo))
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     cfg.CORS.AllowedOrigins,
-		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowMethods:     []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", middleware.APIKeyHeader, tus.HeaderResumable, tus.HeaderLength, tus.HeaderOffset, tus.HeaderMetadata},
+		ExposeHeaders:    []string{"Content-Length", "Location", handler.AttachmentIDHeader, tus.HeaderResumable, tus.HeaderVersion, tus.HeaderExtension, tus.HeaderMaxSize, tus.HeaderLength, tus.HeaderOffset, tus.HeaderMetadata, tus.HeaderExpires},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -131,6 +217,13 @@ func main() {
 		c.JSON(http.StatusOK, gin.H{"status": "ok", "timestamp": time.Now()})
 	})
 
+	// Files stored on the local filesystem
+	if local, ok := storageProvider.(*storage.LocalProvider); ok {
+		router.GET(storage.LocalRoutePrefix+"/*filepath", gin.WrapF(local.ServeFile))
+		router.HEAD(storage.LocalRoutePrefix+"/*filepath", gin.WrapF(local.ServeFile))
+		router.PUT(storage.LocalRoutePrefix+"/*filepath", gin.WrapF(local.ServeUpload))
+	}
+
 	// Swagger documentation
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -140,9 +233,16 @@ func main() {
 		userHandler,
 		addressHandler,
 		attachmentHandler,
+		apiKeyHandler,
+		oauthHandler,
+		magicLinkHandler,
+		invitationHandler,
+		accountHandler,
+		regionHandler,
 		cfg)
 
-	// Setup HTTP server
+	// Setup HTTP server. Upload bodies (tus chunks and local direct uploads) extend
+	// their deadlines to storage.UploadTimeout.
 	srv := &http.Server{
 		Addr:           ":" + cfg.App.Port,
 		Handler:        router,
@@ -173,5 +273,7 @@ func main() {
 		logger.Fatal("Server forced to shutdown", zap.Error(err))
 	}
 
+	jobs.Stop()
+
 	logger.Info("Server exited")
 }
@@ -0,0 +1,57 @@
+// Package avatar generates default avatars showing a user's initials.
+package avatar
+
+import (
+	"encoding/base64"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"strings"
+	"unicode"
+)
+
+// palette holds background colours dark enough for white text
+var palette = []string{
+	"#1abc9c", "#16a085", "#27ae60", "#2980b9", "#8e44ad", "#2c3e50",
+	"#d35400", "#c0392b", "#7f8c8d", "#e67e22", "#3867d6", "#8854d0",
+}
+
+// Initials returns the upper case first letters of the first and last word of the
+// name, e.g. "JD" for "Jane van Doe", or "?" for a name without letters
+func Initials(name string) string {
+	var letters []rune
+	for _, word := range strings.Fields(name) {
+		for _, r := range word {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				letters = append(letters, unicode.ToUpper(r))
+				break
+			}
+		}
+	}
+
+	switch len(letters) {
+	case 0:
+		return "?"
+	case 1:
+		return string(letters[0])
+	}
+	return string(letters[0]) + string(letters[len(letters)-1])
+}
+
+// SVG draws the initials of the name in white on a circle. The colour is derived
+// from seed, e.g. the user ID, so a user keeps it when they change their name.
+func SVG(name string, seed string) []byte {
+	hash := fnv.New32a()
+	hash.Write([]byte(seed))
+	background := palette[hash.Sum32()%uint32(len(palette))]
+
+	return []byte(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="128" height="128" viewBox="0 0 128 128">`+
+		`<circle cx="64" cy="64" r="64" fill="%s"/>`+
+		`<text x="64" y="64" dy=".35em" fill="#ffffff" font-family="Helvetica, Arial, sans-serif" font-size="52" font-weight="600" text-anchor="middle">%s</text>`+
+		`</svg>`, background, html.EscapeString(Initials(name))))
+}
+
+// DataURI returns the SVG as a data URI that can be used as an image URL
+func DataURI(name string, seed string) string {
+	return "data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString(SVG(name, seed))
+}
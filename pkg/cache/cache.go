@@ -0,0 +1,76 @@
+// Package cache provides a small in-memory cache with per-entry expiry.
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+type entry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// Cache is a concurrency-safe key/value cache. Entries expire after the TTL, and
+// once MaxEntries is reached expired entries are dropped, or the whole cache when none have expired.
+type Cache[V any] struct {
+	mu         sync.RWMutex
+	entries    map[string]entry[V]
+	ttl        time.Duration
+	maxEntries int
+}
+
+// New creates a cache; maxEntries <= 0 means unbounded
+func New[V any](ttl time.Duration, maxEntries int) *Cache[V] {
+	return &Cache[V]{
+		entries:    make(map[string]entry[V]),
+		ttl:        ttl,
+		maxEntries: maxEntries,
+	}
+}
+
+// Get returns the cached value and whether it was present and fresh
+func (c *Cache[V]) Get(key string) (V, bool) {
+	c.mu.RLock()
+	e, ok := c.entries[key]
+	c.mu.RUnlock()
+
+	if !ok || time.Now().After(e.expiresAt) {
+		var zero V
+		return zero, false
+	}
+	return e.value, true
+}
+
+// Set stores the value under key
+func (c *Cache[V]) Set(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.maxEntries > 0 && len(c.entries) >= c.maxEntries {
+		c.evict()
+	}
+	c.entries[key] = entry[V]{value: value, expiresAt: time.Now().Add(c.ttl)}
+}
+
+// Clear removes every entry
+func (c *Cache[V]) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]entry[V])
+}
+
+// evict drops expired entries, or everything if nothing has expired. Callers hold the lock.
+func (c *Cache[V]) evict() {
+	now := time.Now()
+	for key, e := range c.entries {
+		if now.After(e.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+
+	if len(c.entries) >= c.maxEntries {
+		c.entries = make(map[string]entry[V])
+	}
+}
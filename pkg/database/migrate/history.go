@@ -0,0 +1,202 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/amirullazmi0/kratify-backend/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	// Table records the applied migrations
+	Table = "schema_migrations"
+	// prismaTable is where the Prisma CLI records the migrations it applied
+	prismaTable = "_prisma_migrations"
+	// lockKey identifies the advisory lock held while migrating
+	lockKey int64 = 5_830_224_919_103_744_001
+)
+
+// querier is satisfied by *sql.DB, *sql.Conn and *sql.Tx
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// appliedMigration is a row of the migrations table
+type appliedMigration struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// withLock runs fn on a single connection holding the session advisory lock, waiting
+// for another replica that holds it to finish
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", lockKey).Scan(&locked); err != nil {
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+	if !locked {
+		logger.Info("Waiting for another instance to finish migrating")
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+			return fmt.Errorf("failed to take migration lock: %w", err)
+		}
+	}
+
+	err = fn(conn)
+
+	// The connection goes back to the pool, so the lock has to be released explicitly
+	if _, unlockErr := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey); unlockErr != nil {
+		logger.Error("Failed to release migration lock", zap.Error(unlockErr))
+		if err == nil {
+			err = fmt.Errorf("failed to release migration lock: %w", unlockErr)
+		}
+	}
+	return err
+}
+
+// prepare creates the migrations table, adopts the migrations the Prisma CLI applied
+// and returns the applied migrations
+func prepare(ctx context.Context, conn *sql.Conn) (map[int64]appliedMigration, error) {
+	_, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+Table+` (
+		version    BIGINT PRIMARY KEY,
+		name       TEXT NOT NULL,
+		checksum   TEXT NOT NULL,
+		applied_at TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	prismaApplied, err := readPrismaApplied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	for version, record := range prismaApplied {
+		result, err := conn.ExecContext(ctx, `INSERT INTO `+Table+` (version, name, checksum, applied_at)
+			VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING`, version, record.name, record.checksum, record.appliedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import Prisma migration %s: %w", record.name, err)
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			logger.Info("Imported Prisma migration", zap.String("migration", record.name))
+		}
+	}
+
+	return readApplied(ctx, conn)
+}
+
+// readApplied returns the applied migrations by version, including those only the
+// Prisma CLI recorded; it is empty before the first migration
+func readApplied(ctx context.Context, q querier) (map[int64]appliedMigration, error) {
+	applied, err := readPrismaApplied(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := tableExists(ctx, q, Table)
+	if err != nil || !exists {
+		return applied, err
+	}
+
+	rows, err := q.QueryContext(ctx, `SELECT version, name, checksum, applied_at FROM `+Table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int64
+		var record appliedMigration
+		if err := rows.Scan(&version, &record.name, &record.checksum, &record.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = record
+	}
+	return applied, rows.Err()
+}
+
+// readPrismaApplied returns the migrations the Prisma CLI finished and did not roll
+// back, with the checksum it recorded
+func readPrismaApplied(ctx context.Context, q querier) (map[int64]appliedMigration, error) {
+	applied := make(map[int64]appliedMigration)
+	exists, err := tableExists(ctx, q, prismaTable)
+	if err != nil || !exists {
+		return applied, err
+	}
+
+	rows, err := q.QueryContext(ctx, `SELECT migration_name, checksum, finished_at FROM `+prismaTable+`
+		WHERE finished_at IS NOT NULL AND rolled_back_at IS NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Prisma migrations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var record appliedMigration
+		if err := rows.Scan(&record.name, &record.checksum, &record.appliedAt); err != nil {
+			return nil, err
+		}
+		match := dirName.FindStringSubmatch(record.name)
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		applied[version] = record
+	}
+	return applied, rows.Err()
+}
+
+// record marks the migration as applied. While the Prisma CLI is still in use it is
+// recorded for it too, so that it does not apply the migration again.
+func record(ctx context.Context, tx *sql.Tx, migration Migration, started time.Time) error {
+	if _, err := tx.ExecContext(ctx, `INSERT INTO `+Table+` (version, name, checksum) VALUES ($1, $2, $3)`,
+		migration.Version, migration.Name, migration.Checksum); err != nil {
+		return err
+	}
+
+	exists, err := tableExists(ctx, tx, prismaTable)
+	if err != nil || !exists {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO `+prismaTable+` (id, checksum, migration_name, started_at, finished_at, applied_steps_count)
+		VALUES (gen_random_uuid()::text, $1, $2, $3, now(), 1)`, migration.Checksum, migration.Name, started)
+	return err
+}
+
+// unrecord marks the migration as not applied, for the Prisma CLI too
+func unrecord(ctx context.Context, tx *sql.Tx, migration Migration) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM `+Table+` WHERE version = $1`, migration.Version); err != nil {
+		return err
+	}
+
+	exists, err := tableExists(ctx, tx, prismaTable)
+	if err != nil || !exists {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM `+prismaTable+` WHERE migration_name = $1`, migration.Name)
+	return err
+}
+
+// tableExists reports whether the table exists in the search path
+func tableExists(ctx context.Context, q querier, table string) (bool, error) {
+	var exists bool
+	if err := q.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", table).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to look up table %s: %w", table, err)
+	}
+	return exists, nil
+}
@@ -0,0 +1,349 @@
+// Package migrate applies versioned SQL migrations embedded in the binary and records
+// them, with checksums, in the schema_migrations table.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/amirullazmi0/kratify-backend/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	// StatePending is a migration that has not been applied
+	StatePending = "pending"
+	// StateApplied is a migration that has been applied as it is now
+	StateApplied = "applied"
+	// StateModified is an applied migration whose SQL changed afterwards
+	StateModified = "modified"
+	// StateMissing is an applied migration this binary has no SQL for, e.g. one added
+	// by a newer release
+	StateMissing = "missing"
+)
+
+var (
+	ErrChecksumMismatch = errors.New("migration was modified after it was applied")
+	ErrIrreversible     = errors.New("migration has no down SQL")
+	ErrUnknownMigration = errors.New("applied migration not found")
+)
+
+var (
+	// dirName matches a Prisma style migration directory, e.g. 20251227081022_init,
+	// holding migration.sql (or up.sql) and an optional down.sql
+	dirName = regexp.MustCompile(`^(\d+)_(.+)$`)
+	// fileName matches a migration file, e.g. 20251227081022_init.up.sql
+	fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+)
+
+// Migration is one versioned schema change
+type Migration struct {
+	Version int64
+	// Name is the directory or file name without suffix, e.g. 20251227081022_init
+	Name string
+	Up   string
+	Down string
+	// Checksum is the hex SHA-256 of Up, the same checksum Prisma records
+	Checksum string
+}
+
+// Status describes a migration and whether it has been applied
+type Status struct {
+	Version   int64
+	Name      string
+	State     string
+	AppliedAt *time.Time
+}
+
+// Migrator applies migrations to a database. Up and Down hold a Postgres advisory
+// lock, so replicas starting at the same time migrate one after another.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// New loads the migrations in fsys, see Load
+func New(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Load reads the migrations at the root of fsys, ordered by version. Both the Prisma
+// layout (<version>_<name>/migration.sql) and files (<version>_<name>.up.sql and
+// .down.sql) are read; anything else is ignored.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	add := func(version string, name string, up string, down string) error {
+		v, err := strconv.ParseInt(version, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid migration version %q: %w", version, err)
+		}
+
+		migration, ok := byVersion[v]
+		if !ok {
+			migration = &Migration{Version: v, Name: name}
+			byVersion[v] = migration
+		} else if migration.Name != name {
+			return fmt.Errorf("migrations %s and %s have the same version", migration.Name, name)
+		}
+		if up != "" {
+			if migration.Up != "" {
+				return fmt.Errorf("migration %s has more than one up SQL", name)
+			}
+			migration.Up = up
+		}
+		if down != "" {
+			migration.Down = down
+		}
+		return nil
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			match := dirName.FindStringSubmatch(entry.Name())
+			if match == nil {
+				continue
+			}
+
+			up, err := readFirst(fsys, entry.Name(), "migration.sql", "up.sql")
+			if err != nil {
+				return nil, err
+			}
+			down, err := readFirst(fsys, entry.Name(), "down.sql")
+			if err != nil {
+				return nil, err
+			}
+			if err := add(match[1], entry.Name(), up, down); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+		name := match[1] + "_" + match[2]
+		if match[3] == "up" {
+			err = add(match[1], name, string(content), "")
+		} else {
+			err = add(match[1], name, "", string(content))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %s has no up SQL", migration.Name)
+		}
+		sum := sha256.Sum256([]byte(migration.Up))
+		migration.Checksum = hex.EncodeToString(sum[:])
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// readFirst returns the content of the first of the files that exists in dir, or ""
+// when none does
+func readFirst(fsys fs.FS, dir string, names ...string) (string, error) {
+	for _, name := range names {
+		content, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read migration %s: %w", path.Join(dir, name), err)
+		}
+		return string(content), nil
+	}
+	return "", nil
+}
+
+// Migrations returns the loaded migrations, ordered by version
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// Up applies the pending migrations in version order, each in its own transaction,
+// and returns the ones applied. It stops at the first failure.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := prepare(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.verify(applied); err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+
+			started := time.Now()
+			err := inTransaction(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+					return err
+				}
+				return record(ctx, tx, migration, started)
+			})
+			if err != nil {
+				return fmt.Errorf("failed to apply migration %s: %w", migration.Name, err)
+			}
+
+			logger.Info("Applied migration", zap.String("migration", migration.Name), zap.Duration("duration", time.Since(started)))
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down reverts the last steps applied migrations, newest first, and returns the ones
+// reverted. Migrations without down SQL cannot be reverted.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := prepare(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.verify(applied); err != nil {
+			return err
+		}
+
+		versions := make([]int64, 0, len(applied))
+		for version := range applied {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+		for _, version := range versions[:min(steps, len(versions))] {
+			migration := m.find(version)
+			if migration == nil {
+				return fmt.Errorf("%w: %s", ErrUnknownMigration, applied[version].name)
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("%w: %s", ErrIrreversible, migration.Name)
+			}
+
+			started := time.Now()
+			err := inTransaction(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+					return err
+				}
+				return unrecord(ctx, tx, *migration)
+			})
+			if err != nil {
+				return fmt.Errorf("failed to revert migration %s: %w", migration.Name, err)
+			}
+
+			logger.Info("Reverted migration", zap.String("migration", migration.Name), zap.Duration("duration", time.Since(started)))
+			done = append(done, *migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status lists every known migration, plus applied ones this binary has no SQL for,
+// in version order. It does not change the database.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := readApplied(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name, State: StatePending}
+		if record, ok := applied[migration.Version]; ok {
+			appliedAt := record.appliedAt
+			status.AppliedAt = &appliedAt
+			status.State = StateApplied
+			if record.checksum != migration.Checksum {
+				status.State = StateModified
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	for version, record := range applied {
+		if m.find(version) == nil {
+			appliedAt := record.appliedAt
+			statuses = append(statuses, Status{Version: version, Name: record.name, State: StateMissing, AppliedAt: &appliedAt})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+
+	return statuses, nil
+}
+
+// verify fails when an applied migration was modified. Applied migrations without SQL
+// are allowed so that an older replica still starts during a rolling deploy.
+func (m *Migrator) verify(applied map[int64]appliedMigration) error {
+	for _, migration := range m.migrations {
+		if record, ok := applied[migration.Version]; ok && record.checksum != migration.Checksum {
+			return fmt.Errorf("%w: %s", ErrChecksumMismatch, migration.Name)
+		}
+	}
+	return nil
+}
+
+// find returns the migration with the version, or nil
+func (m *Migrator) find(version int64) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+// inTransaction runs fn in a transaction on conn, committing when fn returns nil
+func inTransaction(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			logger.Error("Transaction rollback failed", zap.Error(rbErr))
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
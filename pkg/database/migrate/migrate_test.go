@@ -0,0 +1,178 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func file(content string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(content)}
+}
+
+func checksum(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		// Prisma layout, without and with down SQL
+		"20250101000000_init/migration.sql":   file("CREATE TABLE a ();"),
+		"20250102000000_b/migration.sql":      file("CREATE TABLE b ();"),
+		"20250102000000_b/down.sql":           file("DROP TABLE b;"),
+		"20250103000000_c/up.sql":             file("CREATE TABLE c ();"),
+		"20250104000000_d.up.sql":             file("CREATE TABLE d ();"),
+		"20250104000000_d.down.sql":           file("DROP TABLE d;"),
+		"migration_lock.toml":                 file(`provider = "postgresql"`),
+		"README.md":                           file("not a migration"),
+		"drafts/migration.sql":                file("CREATE TABLE draft ();"),
+		"20250105000000_e.sql":                file("CREATE TABLE e ();"),
+		"20250101000000_init/notes.txt":       file("ignored"),
+		"20250103000000_c/seed.sql":           file("ignored"),
+		"20250104000000_d.up.sql.orig":        file("ignored"),
+		"20250102000000_b/migration.sql.orig": file("ignored"),
+	}
+
+	migrations, err := Load(fsys)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	want := []Migration{
+		{Version: 20250101000000, Name: "20250101000000_init", Up: "CREATE TABLE a ();"},
+		{Version: 20250102000000, Name: "20250102000000_b", Up: "CREATE TABLE b ();", Down: "DROP TABLE b;"},
+		{Version: 20250103000000, Name: "20250103000000_c", Up: "CREATE TABLE c ();"},
+		{Version: 20250104000000, Name: "20250104000000_d", Up: "CREATE TABLE d ();", Down: "DROP TABLE d;"},
+	}
+	if len(migrations) != len(want) {
+		t.Fatalf("Load() returned %d migrations, want %d: %+v", len(migrations), len(want), migrations)
+	}
+	for i, migration := range migrations {
+		want[i].Checksum = checksum(want[i].Up)
+		if migration != want[i] {
+			t.Errorf("migration %d = %+v, want %+v", i, migration, want[i])
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+		want string
+	}{
+		{
+			name: "same version in two directories",
+			fsys: fstest.MapFS{
+				"20250101000000_a/migration.sql": file("SELECT 1;"),
+				"20250101000000_b/migration.sql": file("SELECT 2;"),
+			},
+			want: "have the same version",
+		},
+		{
+			name: "same version as directory and file",
+			fsys: fstest.MapFS{
+				"20250101000000_a/migration.sql": file("SELECT 1;"),
+				"20250101000000_b.up.sql":        file("SELECT 2;"),
+			},
+			want: "have the same version",
+		},
+		{
+			name: "up SQL as directory and file",
+			fsys: fstest.MapFS{
+				"20250101000000_a/migration.sql": file("SELECT 1;"),
+				"20250101000000_a.up.sql":        file("SELECT 2;"),
+			},
+			want: "more than one up SQL",
+		},
+		{
+			name: "only down file",
+			fsys: fstest.MapFS{
+				"20250101000000_a.down.sql": file("SELECT 1;"),
+			},
+			want: "has no up SQL",
+		},
+		{
+			name: "directory without migration.sql",
+			fsys: fstest.MapFS{
+				"20250101000000_a/down.sql": file("SELECT 1;"),
+			},
+			want: "has no up SQL",
+		},
+		{
+			name: "version out of range",
+			fsys: fstest.MapFS{
+				"99999999999999999999_a/migration.sql": file("SELECT 1;"),
+			},
+			want: "invalid migration version",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(tt.fsys)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Load() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	m, err := New(nil, fstest.MapFS{
+		"20250101000000_a/migration.sql": file("CREATE TABLE a ();"),
+		"20250102000000_b/migration.sql": file("CREATE TABLE b ();"),
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	applied := map[int64]appliedMigration{
+		20250101000000: {name: "20250101000000_a", checksum: checksum("CREATE TABLE a ();"), appliedAt: time.Now()},
+		// Applied by a newer release
+		20250109000000: {name: "20250109000000_z", checksum: checksum("CREATE TABLE z ();"), appliedAt: time.Now()},
+	}
+	if err := m.verify(applied); err != nil {
+		t.Fatalf("verify() error = %v", err)
+	}
+
+	applied[20250102000000] = appliedMigration{name: "20250102000000_b", checksum: checksum("CREATE TABLE b (id INT);"), appliedAt: time.Now()}
+	err = m.verify(applied)
+	if !errors.Is(err, ErrChecksumMismatch) || !strings.Contains(err.Error(), "20250102000000_b") {
+		t.Fatalf("verify() error = %v, want ErrChecksumMismatch for 20250102000000_b", err)
+	}
+}
+
+func TestRunArguments(t *testing.T) {
+	// The arguments are checked before the database is used
+	m := &Migrator{}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{args: nil, want: Usage},
+		{args: []string{"sideways"}, want: `unknown command "sideways"`},
+		{args: []string{"down", "x"}, want: `invalid steps "x"`},
+		{args: []string{"down", "0"}, want: `invalid steps "0"`},
+		{args: []string{"down", "-2"}, want: `invalid steps "-2"`},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		err := Run(context.Background(), m, tt.args, &out)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Run(%q) error = %v, want %q", tt.args, err, tt.want)
+		}
+		if out.Len() != 0 {
+			t.Errorf("Run(%q) wrote %q", tt.args, out.String())
+		}
+	}
+}
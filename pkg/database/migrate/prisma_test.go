@@ -0,0 +1,77 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/amirullazmi0/kratify-backend/prisma"
+)
+
+// firstRevertible is the oldest of the shipped migrations that has down SQL; the
+// migrations before it predate the Go migrator
+const firstRevertible = 20261018090000
+
+func TestPrismaMigrationsAreRevertible(t *testing.T) {
+	migrations, err := Load(prisma.Migrations())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	for _, migration := range migrations {
+		if migration.Version >= firstRevertible && migration.Down == "" {
+			t.Errorf("%s has no down.sql", migration.Name)
+		}
+	}
+}
+
+// TestPrismaMigrationsUpDown applies the shipped migrations to an empty schema,
+// reverts the revertible ones and applies them again
+func TestPrismaMigrationsUpDown(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	// The migrations expect these extensions in the public schema, where the first test
+	// run installs them rather than in the schema that is dropped afterwards
+	for _, extension := range []string{"pg_trgm", "unaccent"} {
+		if _, err := db.Exec(`CREATE EXTENSION IF NOT EXISTS "` + extension + `" SCHEMA public`); err != nil {
+			t.Skipf("extension %s is not available: %v", extension, err)
+		}
+	}
+
+	m, err := New(db, prisma.Migrations())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	all := m.Migrations()
+
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+
+	revertible := 0
+	for _, migration := range all {
+		if migration.Version >= firstRevertible {
+			revertible++
+		}
+	}
+	reverted, err := m.Down(ctx, revertible)
+	if err != nil {
+		t.Fatalf("Down(%d) error = %v", revertible, err)
+	}
+	if len(reverted) != revertible {
+		t.Fatalf("Down(%d) reverted %d migrations", revertible, len(reverted))
+	}
+	var exists bool
+	db.QueryRow("SELECT to_regclass('api_keys') IS NOT NULL").Scan(&exists)
+	if exists {
+		t.Error("api_keys still exists after reverting its migration")
+	}
+
+	applied, err := m.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up() after Down error = %v", err)
+	}
+	if len(applied) != revertible {
+		t.Errorf("Up() after Down applied %d migrations, want %d", len(applied), revertible)
+	}
+}
@@ -287,7 +287,7 @@ func (qb *QueryBuilder) BuildCount() (string, []interface{}) {
 }
 
 // Execute executes the query and returns rows
-func (qb *QueryBuilder) Execute(db *sql.DB) (*sql.Rows, error) {
+func (qb *QueryBuilder) Execute(db Executor) (*sql.Rows, error) {
 	query, args := qb.Build()
 	start := time.Now()
 	rows, err := db.Query(query, args...)
@@ -369,7 +369,7 @@ func (ib *InsertBuilder) BuildResult() QueryResult {
 }
 
 // Execute executes the insert query and returns UUID
-func (ib *InsertBuilder) Execute(db *sql.DB) (string, error) {
+func (ib *InsertBuilder) Execute(db Executor) (string, error) {
 	query, args := ib.Build()
 	start := time.Now()
 	var id string
@@ -466,7 +466,7 @@ func (ub *UpdateBuilder) BuildResult() QueryResult {
 }
 
 // Execute executes the update query
-func (ub *UpdateBuilder) Execute(db *sql.DB) (int64, error) {
+func (ub *UpdateBuilder) Execute(db Executor) (int64, error) {
 	query, args := ub.Build()
 	start := time.Now()
 	result, err := db.Exec(query, args...)
@@ -589,7 +589,7 @@ func (db *DeleteBuilder) BuildResult() QueryResult {
 }
 
 // Execute executes the delete query
-func (db *DeleteBuilder) Execute(sqlDB *sql.DB) (int64, error) {
+func (db *DeleteBuilder) Execute(sqlDB Executor) (int64, error) {
 	query, args := db.Build()
 	start := time.Now()
 	result, err := sqlDB.Exec(query, args...)
@@ -620,7 +620,7 @@ func (db *DeleteBuilder) Execute(sqlDB *sql.DB) (int64, error) {
 }
 
 // RawQuery executes a raw SQL query
-func RawQuery(db *sql.DB, query string, args ...interface{}) (*sql.Rows, error) {
+func RawQuery(db Executor, query string, args ...interface{}) (*sql.Rows, error) {
 	start := time.Now()
 	rows, err := db.Query(query, args...)
 	duration := time.Since(start)
@@ -643,7 +643,7 @@ func RawQuery(db *sql.DB, query string, args ...interface{}) (*sql.Rows, error)
 }
 
 // RawExec executes a raw SQL command
-func RawExec(db *sql.DB, query string, args ...interface{}) (sql.Result, error) {
+func RawExec(db Executor, query string, args ...interface{}) (sql.Result, error) {
 	start := time.Now()
 	result, err := db.Exec(query, args...)
 	duration := time.Since(start)
@@ -672,7 +672,7 @@ func RawExec(db *sql.DB, query string, args ...interface{}) (sql.Result, error)
 }
 
 // RawQueryRow executes a raw SQL query for single row
-func RawQueryRow(db *sql.DB, query string, args ...interface{}) *sql.Row {
+func RawQueryRow(db Executor, query string, args ...interface{}) *sql.Row {
 	start := time.Now()
 	row := db.QueryRow(query, args...)
 	duration := time.Since(start)
@@ -691,7 +691,7 @@ func RawQueryRow(db *sql.DB, query string, args ...interface{}) *sql.Row {
 // Helper functions for common aggregate queries
 
 // Count returns count of rows
-func Count(db *sql.DB, table string, where string, args ...interface{}) (int64, error) {
+func Count(db Executor, table string, where string, args ...interface{}) (int64, error) {
 	query := fmt.Sprintf("SELECT COUNT(*) FROM %s", table)
 	if where != "" {
 		query += " WHERE " + where
@@ -713,11 +713,16 @@ func Count(db *sql.DB, table string, where string, args ...interface{}) (int64,
 }
 
 // Exists checks if rows exist
-func Exists(db *sql.DB, table string, where string, args ...interface{}) (bool, error) {
+func Exists(db Executor, table string, where string, args ...interface{}) (bool, error) {
 	count, err := Count(db, table, where, args...)
 	return count > 0, err
 }
 
+// HasExtension reports whether a PostgreSQL extension is installed
+func HasExtension(db Executor, name string) (bool, error) {
+	return Exists(db, "pg_extension", "extname = $1", name)
+}
+
 // Paginate helper for pagination
 type PaginationResult struct {
 	Page       int         `json:"page"`
@@ -766,7 +771,7 @@ func (bib *BulkInsertBuilder) AddRow(values ...interface{}) *BulkInsertBuilder {
 	return bib
 }
 
-func (bib *BulkInsertBuilder) Execute(db *sql.DB) (int64, error) {
+func (bib *BulkInsertBuilder) Execute(db Executor) (int64, error) {
 	if len(bib.rows) == 0 {
 		return 0, fmt.Errorf("no rows to insert")
 	}
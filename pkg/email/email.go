@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"html/template"
 	"net/smtp"
+	"time"
 
 	"github.com/amirullazmi0/kratify-backend/config"
 )
@@ -157,6 +158,109 @@ func (s *EmailService) SendVerificationEmail(to, name, verificationToken, baseUR
 	return s.SendEmail(to, "Verify Your Email Address", body.String())
 }
 
+// actionEmailTemplate is the shared layout for transactional emails with a single call-to-action button
+const actionEmailTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{.Title}}</title>
+</head>
+<body style="margin: 0; padding: 0; font-family: Arial, sans-serif; background-color: #f4f4f4;">
+    <table role="presentation" style="width: 100%; border-collapse: collapse;">
+        <tr>
+            <td align="center" style="padding: 40px 0;">
+                <table role="presentation" style="width: 600px; border-collapse: collapse; background-color: #ffffff; border-radius: 8px; box-shadow: 0 2px 4px rgba(0,0,0,0.1);">
+                    <tr>
+                        <td style="padding: 40px 40px 30px; text-align: center; background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); border-radius: 8px 8px 0 0;">
+                            <h1 style="margin: 0; color: #ffffff; font-size: 28px; font-weight: bold;">{{.Title}}</h1>
+                        </td>
+                    </tr>
+                    <tr>
+                        <td style="padding: 40px;">
+                            <h2 style="margin: 0 0 20px; color: #333333; font-size: 24px;">Hi {{.Name}},</h2>
+                            <p style="margin: 0 0 30px; color: #666666; font-size: 16px; line-height: 1.6;">{{.Message}}</p>
+                            <table role="presentation" style="margin: 0 auto;">
+                                <tr>
+                                    <td style="border-radius: 6px; background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);">
+                                        <a href="{{.Link}}" target="_blank" style="display: inline-block; padding: 16px 48px; color: #ffffff; text-decoration: none; font-size: 16px; font-weight: bold; border-radius: 6px;">{{.ButtonText}}</a>
+                                    </td>
+                                </tr>
+                            </table>
+                            <p style="margin: 30px 0 0; color: #999999; font-size: 14px; line-height: 1.6;">Or copy and paste this link in your browser:</p>
+                            <p style="margin: 10px 0 0; color: #667eea; font-size: 14px; word-break: break-all;">{{.Link}}</p>
+                            <div style="margin-top: 40px; padding-top: 30px; border-top: 1px solid #eeeeee;">
+                                <p style="margin: 0; color: #999999; font-size: 14px;">{{.Footnote}}</p>
+                            </div>
+                        </td>
+                    </tr>
+                    <tr>
+                        <td style="padding: 30px 40px; text-align: center; background-color: #f9f9f9; border-radius: 0 0 8px 8px;">
+                            <p style="margin: 0 0 10px; color: #999999; font-size: 14px;">Best regards,<br><strong>{{.AppName}} Team</strong></p>
+                        </td>
+                    </tr>
+                </table>
+            </td>
+        </tr>
+    </table>
+</body>
+</html>
+`
+
+// sendActionEmail renders actionEmailTemplate and sends it
+func (s *EmailService) sendActionEmail(to, subject string, data map[string]string) error {
+	t, err := template.New("action").Parse(actionEmailTemplate)
+	if err != nil {
+		return err
+	}
+
+	data["AppName"] = "Kratify Backend"
+
+	var body bytes.Buffer
+	if err := t.Execute(&body, data); err != nil {
+		return err
+	}
+
+	return s.SendEmail(to, subject, body.String())
+}
+
+// SendMagicLinkEmail sends a passwordless sign-in link
+func (s *EmailService) SendMagicLinkEmail(to, name, magicLink string, validFor time.Duration) error {
+	return s.sendActionEmail(to, "Your Sign-in Link", map[string]string{
+		"Title":      "Sign in to your account",
+		"Name":       name,
+		"Message":    "Click the button below to sign in. The link only works once and only in the browser where you requested it.",
+		"ButtonText": "Sign In",
+		"Link":       magicLink,
+		"Footnote":   fmt.Sprintf("This link will expire in %d minutes. If you didn't request it, you can safely ignore this email.", int(validFor.Minutes())),
+	})
+}
+
+// SendInvitationEmail sends an account invitation link
+func (s *EmailService) SendInvitationEmail(to, name, inviterName, inviteLink string, validFor time.Duration) error {
+	return s.sendActionEmail(to, "You're Invited", map[string]string{
+		"Title":      "You've been invited",
+		"Name":       name,
+		"Message":    fmt.Sprintf("%s has created an account for you. Click the button below to set your password and activate it.", inviterName),
+		"ButtonText": "Accept Invitation",
+		"Link":       inviteLink,
+		"Footnote":   fmt.Sprintf("This invitation will expire in %d days. If you weren't expecting it, you can safely ignore this email.", int(validFor.Hours()/24)),
+	})
+}
+
+// SendReactivationEmail sends a link to reactivate a deactivated account
+func (s *EmailService) SendReactivationEmail(to, name, reactivationLink string, validUntil time.Time) error {
+	return s.sendActionEmail(to, "Your Account Has Been Deactivated", map[string]string{
+		"Title":      "Your account has been deactivated",
+		"Name":       name,
+		"Message":    "You have been signed out everywhere. Changed your mind? Click the button below to reactivate your account.",
+		"ButtonText": "Reactivate Account",
+		"Link":       reactivationLink,
+		"Footnote":   fmt.Sprintf("This link works until %s. After that your account and its data will be permanently deleted.", validUntil.Format("2 January 2006")),
+	})
+}
+
 // GenerateVerificationToken generates a random verification token
 func GenerateVerificationToken() (string, error) {
 	b := make([]byte, 32)
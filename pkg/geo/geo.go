@@ -0,0 +1,54 @@
+// Package geo provides distance calculations and geocoding.
+package geo
+
+import "math"
+
+// EarthRadiusKm is the mean Earth radius used for distance calculations
+const EarthRadiusKm = 6371.0088
+
+// Point is a WGS84 coordinate in degrees
+type Point struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Distance returns the great-circle distance between two points in kilometres (Haversine formula)
+func Distance(a, b Point) float64 {
+	lat1 := toRadians(a.Latitude)
+	lat2 := toRadians(b.Latitude)
+	dLat := lat2 - lat1
+	dLng := toRadians(b.Longitude - a.Longitude)
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+
+	return 2 * EarthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// BoundingBox returns the south-west and north-east corners of a box that contains
+// every point within radiusKm of center. It is a cheap pre-filter for Distance.
+func BoundingBox(center Point, radiusKm float64) (Point, Point) {
+	dLat := toDegrees(radiusKm / EarthRadiusKm)
+
+	// Longitude degrees shrink towards the poles; near them the box spans every longitude
+	dLng := 180.0
+	if cos := math.Cos(toRadians(center.Latitude)); cos > 1e-6 {
+		dLng = math.Min(180, toDegrees(radiusKm/(EarthRadiusKm*cos)))
+	}
+
+	return Point{Latitude: math.Max(-90, center.Latitude-dLat), Longitude: center.Longitude - dLng},
+		Point{Latitude: math.Min(90, center.Latitude+dLat), Longitude: center.Longitude + dLng}
+}
+
+// Valid reports whether the point is a valid WGS84 coordinate
+func (p Point) Valid() bool {
+	return p.Latitude >= -90 && p.Latitude <= 90 && p.Longitude >= -180 && p.Longitude <= 180
+}
+
+func toRadians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+func toDegrees(radians float64) float64 {
+	return radians * 180 / math.Pi
+}
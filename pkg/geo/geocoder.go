@@ -0,0 +1,56 @@
+package geo
+
+import (
+	"context"
+	"errors"
+)
+
+// Coordinate sources, describing where an address's coordinates came from
+const (
+	SourceManual         = "manual"
+	SourceGeocoder       = "geocoder"
+	SourceRegionCentroid = "region_centroid"
+)
+
+// ErrNoMatch is returned by a geocoder that cannot place the query
+var ErrNoMatch = errors.New("no geocoding match")
+
+// Query describes an address to geocode
+type Query struct {
+	// Text is the free-text address, e.g. "Jl. Sudirman No. 1, Senayan, Jakarta Selatan"
+	Text string
+	// RegionCode is the most specific known region (Kemendagri code)
+	RegionCode string
+	// CountryCode is an ISO 3166-1 alpha-2 code used to narrow the search
+	CountryCode string
+}
+
+// Result is a geocoded point and the source that produced it
+type Result struct {
+	Point  Point
+	Source string
+}
+
+// Geocoder turns an address into coordinates
+type Geocoder interface {
+	Geocode(ctx context.Context, query Query) (*Result, error)
+}
+
+// Chain tries each geocoder in turn and returns the first match
+type Chain []Geocoder
+
+// Geocode returns the first match, ErrNoMatch when no geocoder matched, or the
+// last error when a geocoder failed and none of the later ones matched
+func (c Chain) Geocode(ctx context.Context, query Query) (*Result, error) {
+	lastErr := ErrNoMatch
+	for _, geocoder := range c {
+		result, err := geocoder.Geocode(ctx, query)
+		if err == nil {
+			return result, nil
+		}
+		if !errors.Is(err, ErrNoMatch) {
+			lastErr = err
+		}
+	}
+	return nil, lastErr
+}
@@ -0,0 +1,96 @@
+package geo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultNominatimURL is the public OpenStreetMap Nominatim instance
+const DefaultNominatimURL = "https://nominatim.openstreetmap.org"
+
+// NominatimGeocoder geocodes free-text addresses with a Nominatim (OpenStreetMap) server.
+// The public instance requires an identifying User-Agent and at most one request per second.
+type NominatimGeocoder struct {
+	baseURL    string
+	userAgent  string
+	httpClient *http.Client
+}
+
+// NewNominatimGeocoder creates a Nominatim geocoder; an empty baseURL uses the public instance
+func NewNominatimGeocoder(baseURL string, userAgent string, httpClient *http.Client) *NominatimGeocoder {
+	if baseURL == "" {
+		baseURL = DefaultNominatimURL
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 5 * time.Second}
+	}
+
+	return &NominatimGeocoder{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		userAgent:  userAgent,
+		httpClient: httpClient,
+	}
+}
+
+// Geocode looks up query.Text and returns the best match
+func (g *NominatimGeocoder) Geocode(ctx context.Context, query Query) (*Result, error) {
+	if strings.TrimSpace(query.Text) == "" {
+		return nil, ErrNoMatch
+	}
+
+	params := url.Values{}
+	params.Set("q", query.Text)
+	params.Set("format", "jsonv2")
+	params.Set("limit", "1")
+	if query.CountryCode != "" {
+		params.Set("countrycodes", strings.ToLower(query.CountryCode))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.baseURL+"/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if g.userAgent != "" {
+		req.Header.Set("User-Agent", g.userAgent)
+	}
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("nominatim search returned %d", resp.StatusCode)
+	}
+
+	var places []struct {
+		Lat string `json:"lat"`
+		Lon string `json:"lon"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&places); err != nil {
+		return nil, err
+	}
+	if len(places) == 0 {
+		return nil, ErrNoMatch
+	}
+
+	lat, err := strconv.ParseFloat(places[0].Lat, 64)
+	if err != nil {
+		return nil, err
+	}
+	lng, err := strconv.ParseFloat(places[0].Lon, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{Point: Point{Latitude: lat, Longitude: lng}, Source: SourceGeocoder}, nil
+}
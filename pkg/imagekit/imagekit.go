@@ -3,24 +3,39 @@ package imagekit
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"path"
 	"strings"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	ik "github.com/imagekit-developer/imagekit-go/v2"
 	"github.com/imagekit-developer/imagekit-go/v2/option"
 )
 
 type ImageKitService interface {
-	UploadFile(ctx context.Context, file io.Reader, fileName string, folder string) (*ik.FileUploadResponse, error)
+	UploadFile(ctx context.Context, file io.Reader, fileName string, folder string, private bool) (*ik.FileUploadResponse, error)
+	ListFiles(ctx context.Context, folder string) ([]ik.AssetListResponseUnion, error)
+	FindFile(ctx context.Context, filePath string) (*ik.AssetListResponseUnion, error)
+	DeleteFile(ctx context.Context, fileID string) error
+	ClientUploadToken(params map[string]string, expire time.Time) (string, error)
+	SignURL(fileURL string, expire time.Time) string
 }
 
+// UploadEndpoint is where clients upload files with a ClientUploadToken; the V2 API
+// rejects parameters that differ from the signed ones
+const UploadEndpoint = "https://upload.imagekit.io/api/v2/files/upload"
+
 type imageKitService struct {
 	client      *ik.Client
 	publicKey   string
+	privateKey  string
 	urlEndpoint string
 }
 
@@ -36,11 +51,13 @@ func NewImageKitService(publicKey, privateKey, urlEndpoint string) (ImageKitServ
 	return &imageKitService{
 		client:      &client,
 		publicKey:   publicKey,
+		privateKey:  privateKey,
 		urlEndpoint: urlEndpoint,
 	}, nil
 }
 
-func (s *imageKitService) UploadFile(ctx context.Context, file io.Reader, fileName string, folder string) (*ik.FileUploadResponse, error) {
+// UploadFile uploads to folder; private files can only be fetched with a SignURL URL
+func (s *imageKitService) UploadFile(ctx context.Context, file io.Reader, fileName string, folder string, private bool) (*ik.FileUploadResponse, error) {
 	// Normalize folder (optional)
 	folder = normalizeFolder(folder)
 
@@ -66,6 +83,9 @@ func (s *imageKitService) UploadFile(ctx context.Context, file io.Reader, fileNa
 	if folder != "" {
 		params.Folder = ik.String(folder)
 	}
+	if private {
+		params.IsPrivateFile = ik.Bool(true)
+	}
 
 	resp, err := s.client.Files.Upload(ctx, params)
 	if err != nil {
@@ -75,6 +95,102 @@ func (s *imageKitService) UploadFile(ctx context.Context, file io.Reader, fileNa
 	return resp, nil
 }
 
+// listPageSize is the page size used when listing a folder (ImageKit allows up to 1000)
+const listPageSize = 100
+
+// ListFiles returns every file stored directly in folder
+func (s *imageKitService) ListFiles(ctx context.Context, folder string) ([]ik.AssetListResponseUnion, error) {
+	folder = normalizeFolder(folder)
+
+	var files []ik.AssetListResponseUnion
+	for skip := int64(0); ; skip += listPageSize {
+		page, err := s.client.Assets.List(ctx, ik.AssetListParams{
+			Path:  ik.String(folder + "/"),
+			Type:  ik.AssetListParamsTypeFile,
+			Limit: ik.Int(listPageSize),
+			Skip:  ik.Int(skip),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list imagekit files: %w", err)
+		}
+		if page == nil {
+			break
+		}
+
+		files = append(files, *page...)
+		if len(*page) < listPageSize {
+			break
+		}
+	}
+
+	return files, nil
+}
+
+// ErrFileNotFound is returned by FindFile when no file has the path
+var ErrFileNotFound = errors.New("imagekit file not found")
+
+// FindFile returns the file stored at filePath (folder and name)
+func (s *imageKitService) FindFile(ctx context.Context, filePath string) (*ik.AssetListResponseUnion, error) {
+	filePath = normalizeFolder(filePath)
+	folder, name := path.Split(filePath)
+
+	page, err := s.client.Assets.List(ctx, ik.AssetListParams{
+		Path:        ik.String(folder),
+		Type:        ik.AssetListParamsTypeFile,
+		SearchQuery: ik.String(fmt.Sprintf("name = %q", name)),
+		Limit:       ik.Int(1),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find imagekit file: %w", err)
+	}
+	if page == nil || len(*page) == 0 {
+		return nil, ErrFileNotFound
+	}
+
+	file := (*page)[0]
+	return &file, nil
+}
+
+// DeleteFile removes a file by its ImageKit file ID
+func (s *imageKitService) DeleteFile(ctx context.Context, fileID string) error {
+	if err := s.client.Files.Delete(ctx, fileID); err != nil {
+		return fmt.Errorf("failed to delete file from imagekit: %w", err)
+	}
+	return nil
+}
+
+// ClientUploadToken signs a client-side upload with exactly the given parameters
+// (fileName, folder, ...), valid until expire (at most one hour ahead). The client
+// sends the parameters as form fields next to the token and the file.
+func (s *imageKitService) ClientUploadToken(params map[string]string, expire time.Time) (string, error) {
+	claims := jwt.MapClaims{
+		"iat": time.Now().Unix(),
+		"exp": expire.Unix(),
+	}
+	for name, value := range params {
+		claims[name] = value
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = s.publicKey
+	return token.SignedString([]byte(s.privateKey))
+}
+
+// SignURL signs a URL below the URL endpoint so it can be fetched until expire,
+// which is required for private files
+func (s *imageKitService) SignURL(fileURL string, expire time.Time) string {
+	endpoint := strings.TrimRight(s.urlEndpoint, "/") + "/"
+
+	mac := hmac.New(sha1.New, []byte(s.privateKey))
+	fmt.Fprintf(mac, "%s%d", strings.TrimPrefix(fileURL, endpoint), expire.Unix())
+
+	separator := "?"
+	if strings.Contains(fileURL, "?") {
+		separator = "&"
+	}
+	return fmt.Sprintf("%s%sik-t=%d&ik-s=%s", fileURL, separator, expire.Unix(), hex.EncodeToString(mac.Sum(nil)))
+}
+
 func normalizeFolder(folder string) string {
 	folder = strings.TrimSpace(folder)
 	if folder == "" {
@@ -0,0 +1,214 @@
+package imageproc
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Output formats
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatWebP = "webp"
+)
+
+// DefaultJPEGQuality is used when Options.JPEGQuality is not set
+const DefaultJPEGQuality = 85
+
+// Variant is a resized copy to generate
+type Variant struct {
+	Name string
+	// MaxSize is the longest edge in pixels; smaller images are not enlarged
+	MaxSize int
+	// Format is FormatJPEG or FormatWebP (lossless)
+	Format string
+}
+
+// Options configures Process
+type Options struct {
+	Variants    []Variant
+	JPEGQuality int
+}
+
+// Output is an encoded image
+type Output struct {
+	// Name is the variant name, empty for the original
+	Name        string
+	Data        []byte
+	ContentType string
+	Extension   string
+	Width       int
+	Height      int
+}
+
+// Result holds the cleaned original and the generated variants
+type Result struct {
+	Original Output
+	Variants []Output
+}
+
+// Supported reports whether Process accepts the MIME type
+func Supported(contentType string) bool {
+	switch contentType {
+	case "image/jpeg", "image/png", "image/webp":
+		return true
+	}
+	return false
+}
+
+// Process rotates the image upright according to its EXIF orientation and re-encodes
+// it, which drops all metadata (EXIF, GPS, XMP, ...). JPEG and PNG originals keep their
+// format. WebP originals become JPEG, as lossless WebP is usually much larger than the
+// lossy upload; only those with transparency stay lossless WebP.
+func Process(data []byte, opts Options) (*Result, error) {
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	img = Orient(img, Orientation(data))
+
+	if opts.JPEGQuality <= 0 || opts.JPEGQuality > 100 {
+		opts.JPEGQuality = DefaultJPEGQuality
+	}
+	if format == FormatWebP && opaque(img) {
+		format = FormatJPEG
+	}
+
+	original, err := encode(img, format, opts.JPEGQuality)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Original: *original}
+	for _, variant := range opts.Variants {
+		output, err := encode(Fit(img, variant.MaxSize), variant.Format, opts.JPEGQuality)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s variant: %w", variant.Name, err)
+		}
+		output.Name = variant.Name
+		result.Variants = append(result.Variants, *output)
+	}
+
+	return result, nil
+}
+
+// Fit scales the image down so its longest edge is at most maxSize
+func Fit(img image.Image, maxSize int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if maxSize <= 0 || (width <= maxSize && height <= maxSize) {
+		return img
+	}
+
+	if width >= height {
+		height = max(1, height*maxSize/width)
+		width = maxSize
+	} else {
+		width = max(1, width*maxSize/height)
+		height = maxSize
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
+
+// Orient applies an EXIF orientation (1-8) so the image displays upright
+func Orient(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	src := toNRGBA(img)
+	width, height := src.Rect.Dx(), src.Rect.Dy()
+
+	// Orientations 5-8 swap width and height
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				dx, dy = width-1-x, y
+			case 3: // rotated 180
+				dx, dy = width-1-x, height-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, height-1-y
+			case 5: // mirrored along the top-left diagonal
+				dx, dy = y, x
+			case 6: // rotated 90 clockwise
+				dx, dy = height-1-y, x
+			case 7: // mirrored along the top-right diagonal
+				dx, dy = height-1-y, width-1-x
+			case 8: // rotated 90 counter-clockwise
+				dx, dy = y, width-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):dst.PixOffset(dx, dy)+4], src.Pix[src.PixOffset(x, y):src.PixOffset(x, y)+4])
+		}
+	}
+
+	return dst
+}
+
+// opaque reports whether the image has no transparent pixels
+func opaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
+
+// toNRGBA returns the image as an NRGBA image with its origin at 0,0
+func toNRGBA(img image.Image) *image.NRGBA {
+	if nrgba, ok := img.(*image.NRGBA); ok && nrgba.Rect.Min == (image.Point{}) {
+		return nrgba
+	}
+
+	bounds := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Src)
+	return dst
+}
+
+func encode(img image.Image, format string, jpegQuality int) (*Output, error) {
+	var buf bytes.Buffer
+	output := &Output{
+		Width:  img.Bounds().Dx(),
+		Height: img.Bounds().Dy(),
+	}
+
+	switch format {
+	case FormatJPEG:
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, err
+		}
+		output.ContentType, output.Extension = "image/jpeg", ".jpg"
+	case FormatPNG:
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+		output.ContentType, output.Extension = "image/png", ".png"
+	case FormatWebP:
+		if err := nativewebp.Encode(&buf, img, nil); err != nil {
+			return nil, err
+		}
+		output.ContentType, output.Extension = "image/webp", ".webp"
+	default:
+		return nil, fmt.Errorf("unsupported image format %q", format)
+	}
+
+	output.Data = buf.Bytes()
+	return output, nil
+}
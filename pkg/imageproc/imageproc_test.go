@@ -0,0 +1,361 @@
+package imageproc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/HugoSmits86/nativewebp"
+)
+
+// gpsMarker stands in for GPS coordinates in the test EXIF blocks
+const gpsMarker = "GPS 52.3676N 4.9041E"
+
+// testTIFF returns a TIFF structure whose IFD0 holds the orientation, followed by
+// gpsMarker
+func testTIFF(order binary.ByteOrder, orientation int) []byte {
+	var buf bytes.Buffer
+	if order == binary.LittleEndian {
+		buf.WriteString("II")
+	} else {
+		buf.WriteString("MM")
+	}
+	binary.Write(&buf, order, uint16(42))
+	binary.Write(&buf, order, uint32(8)) // IFD0 offset
+
+	binary.Write(&buf, order, uint16(2)) // entries
+	// ImageDescription, an ASCII tag before the orientation
+	binary.Write(&buf, order, uint16(0x010E))
+	binary.Write(&buf, order, uint16(2))
+	binary.Write(&buf, order, uint32(4))
+	buf.WriteString("test")
+	// Orientation, SHORT
+	binary.Write(&buf, order, uint16(exifOrientationTag))
+	binary.Write(&buf, order, uint16(3))
+	binary.Write(&buf, order, uint32(1))
+	binary.Write(&buf, order, uint16(orientation))
+	binary.Write(&buf, order, uint16(0))
+	binary.Write(&buf, order, uint32(0)) // no next IFD
+
+	buf.WriteString(gpsMarker)
+	return buf.Bytes()
+}
+
+// testImage returns a width x height image in which every pixel differs
+func testImage(width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 40), G: uint8(y * 40), B: uint8(x*7 + y*11), A: 255})
+		}
+	}
+	return img
+}
+
+// testJPEG encodes the image as JPEG with the EXIF block in an APP1 segment after SOI
+func testJPEG(t *testing.T, img image.Image, tiff []byte) []byte {
+	t.Helper()
+
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, img, &jpeg.Options{Quality: 90}); err != nil {
+		t.Fatalf("failed to encode JPEG: %v", err)
+	}
+	if tiff == nil {
+		return encoded.Bytes()
+	}
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	var buf bytes.Buffer
+	buf.Write(encoded.Bytes()[:2])
+	buf.Write([]byte{0xFF, 0xE1})
+	binary.Write(&buf, binary.BigEndian, uint16(len(segment)+2))
+	buf.Write(segment)
+	buf.Write(encoded.Bytes()[2:])
+	return buf.Bytes()
+}
+
+// testPNG encodes the image as PNG with the EXIF block in an eXIf chunk after IHDR
+func testPNG(t *testing.T, img image.Image, tiff []byte) []byte {
+	t.Helper()
+
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, img); err != nil {
+		t.Fatalf("failed to encode PNG: %v", err)
+	}
+	if tiff == nil {
+		return encoded.Bytes()
+	}
+
+	// The signature (8 bytes) and IHDR (8 + 13 + 4 bytes) come first
+	data := encoded.Bytes()
+	var buf bytes.Buffer
+	buf.Write(data[:33])
+	binary.Write(&buf, binary.BigEndian, uint32(len(tiff)))
+	chunk := append([]byte("eXIf"), tiff...)
+	buf.Write(chunk)
+	binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(chunk))
+	buf.Write(data[33:])
+	return buf.Bytes()
+}
+
+// testWebP encodes the image as an extended lossless WebP file with an EXIF chunk
+func testWebP(t *testing.T, img image.Image, exif []byte) []byte {
+	t.Helper()
+
+	var encoded bytes.Buffer
+	if err := nativewebp.Encode(&encoded, img, nil); err != nil {
+		t.Fatalf("failed to encode WebP: %v", err)
+	}
+	if exif == nil {
+		return encoded.Bytes()
+	}
+
+	chunk := func(buf *bytes.Buffer, chunkType string, data []byte) {
+		buf.WriteString(chunkType)
+		binary.Write(buf, binary.LittleEndian, uint32(len(data)))
+		buf.Write(data)
+		if len(data)%2 == 1 {
+			buf.WriteByte(0)
+		}
+	}
+
+	bounds := img.Bounds()
+	vp8x := make([]byte, 10)
+	vp8x[0] = 0x08 // EXIF flag
+	putUint24 := func(b []byte, v int) { b[0], b[1], b[2] = byte(v), byte(v>>8), byte(v>>16) }
+	putUint24(vp8x[4:7], bounds.Dx()-1)
+	putUint24(vp8x[7:10], bounds.Dy()-1)
+
+	var chunks bytes.Buffer
+	chunk(&chunks, "VP8X", vp8x)
+	// The simple format holds a single image chunk after the 12 byte header
+	chunks.Write(encoded.Bytes()[12:])
+	chunk(&chunks, "EXIF", exif)
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(chunks.Len()+4))
+	buf.WriteString("WEBP")
+	buf.Write(chunks.Bytes())
+	return buf.Bytes()
+}
+
+func TestOrientation(t *testing.T) {
+	img := testImage(4, 3)
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{name: "JPEG little endian", data: testJPEG(t, img, testTIFF(binary.LittleEndian, 6)), want: 6},
+		{name: "JPEG big endian", data: testJPEG(t, img, testTIFF(binary.BigEndian, 3)), want: 3},
+		{name: "JPEG without EXIF", data: testJPEG(t, img, nil), want: 1},
+		{name: "PNG eXIf", data: testPNG(t, img, testTIFF(binary.BigEndian, 8)), want: 8},
+		{name: "PNG without eXIf", data: testPNG(t, img, nil), want: 1},
+		{name: "WebP EXIF", data: testWebP(t, img, testTIFF(binary.LittleEndian, 5)), want: 5},
+		{name: "WebP EXIF with JPEG prefix", data: testWebP(t, img, append([]byte("Exif\x00\x00"), testTIFF(binary.BigEndian, 7)...)), want: 7},
+		{name: "WebP without EXIF", data: testWebP(t, img, nil), want: 1},
+		{name: "out of range orientation", data: testJPEG(t, img, testTIFF(binary.LittleEndian, 9)), want: 1},
+		{name: "truncated", data: testJPEG(t, img, testTIFF(binary.LittleEndian, 6))[:30], want: 1},
+		{name: "not an image", data: []byte("GIF89a"), want: 1},
+		{name: "empty", data: nil, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Orientation(tt.data); got != tt.want {
+				t.Errorf("Orientation() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// grid is an image as rows of pixel values, used to compute the expected result of
+// Orient independently of it
+type grid [][]color.NRGBA
+
+func gridOf(img *image.NRGBA) grid {
+	g := make(grid, img.Rect.Dy())
+	for y := range g {
+		g[y] = make([]color.NRGBA, img.Rect.Dx())
+		for x := range g[y] {
+			g[y][x] = img.NRGBAAt(x, y)
+		}
+	}
+	return g
+}
+
+func (g grid) flipHorizontal() grid {
+	out := make(grid, len(g))
+	for y := range g {
+		out[y] = make([]color.NRGBA, len(g[y]))
+		for x := range g[y] {
+			out[y][x] = g[y][len(g[y])-1-x]
+		}
+	}
+	return out
+}
+
+func (g grid) rotateClockwise() grid {
+	height, width := len(g), len(g[0])
+	out := make(grid, width)
+	for y := range out {
+		out[y] = make([]color.NRGBA, height)
+		for x := range out[y] {
+			out[y][x] = g[height-1-x][y]
+		}
+	}
+	return out
+}
+
+func TestOrient(t *testing.T) {
+	src := testImage(3, 2)
+	g := gridOf(src)
+	rotate180 := g.rotateClockwise().rotateClockwise()
+
+	want := map[int]grid{
+		1: g,
+		2: g.flipHorizontal(),
+		3: rotate180,
+		4: rotate180.flipHorizontal(),
+		5: g.rotateClockwise().flipHorizontal(),
+		6: g.rotateClockwise(),
+		7: g.rotateClockwise().flipHorizontal().rotateClockwise().rotateClockwise(),
+		8: rotate180.rotateClockwise(),
+	}
+
+	for orientation := 1; orientation <= 8; orientation++ {
+		got := gridOf(toNRGBA(Orient(src, orientation)))
+		expected := want[orientation]
+		if len(got) != len(expected) || len(got[0]) != len(expected[0]) {
+			t.Errorf("Orient(%d) is %dx%d, want %dx%d", orientation, len(got[0]), len(got), len(expected[0]), len(expected))
+			continue
+		}
+		for y := range expected {
+			for x := range expected[y] {
+				if got[y][x] != expected[y][x] {
+					t.Errorf("Orient(%d) pixel %d,%d = %v, want %v", orientation, x, y, got[y][x], expected[y][x])
+				}
+			}
+		}
+	}
+
+	for _, orientation := range []int{0, 9, -1} {
+		if Orient(src, orientation) != image.Image(src) {
+			t.Errorf("Orient(%d) changed the image", orientation)
+		}
+	}
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		width, height int
+		maxSize       int
+		wantWidth     int
+		wantHeight    int
+	}{
+		{width: 400, height: 200, maxSize: 100, wantWidth: 100, wantHeight: 50},
+		{width: 200, height: 400, maxSize: 100, wantWidth: 50, wantHeight: 100},
+		{width: 300, height: 300, maxSize: 100, wantWidth: 100, wantHeight: 100},
+		{width: 1000, height: 3, maxSize: 100, wantWidth: 100, wantHeight: 1},
+		{width: 80, height: 60, maxSize: 100, wantWidth: 80, wantHeight: 60},
+		{width: 400, height: 200, maxSize: 0, wantWidth: 400, wantHeight: 200},
+	}
+
+	for _, tt := range tests {
+		got := Fit(image.NewNRGBA(image.Rect(0, 0, tt.width, tt.height)), tt.maxSize).Bounds()
+		if got.Dx() != tt.wantWidth || got.Dy() != tt.wantHeight {
+			t.Errorf("Fit(%dx%d, %d) = %dx%d, want %dx%d", tt.width, tt.height, tt.maxSize, got.Dx(), got.Dy(), tt.wantWidth, tt.wantHeight)
+		}
+	}
+}
+
+func TestProcessStripsMetadata(t *testing.T) {
+	img := testImage(40, 20)
+	tiff := testTIFF(binary.LittleEndian, 6)
+	opts := Options{Variants: []Variant{{Name: "thumbnail", MaxSize: 10, Format: FormatWebP}, {Name: "medium", MaxSize: 30, Format: FormatJPEG}}}
+
+	tests := []struct {
+		name            string
+		data            []byte
+		wantContentType string
+	}{
+		{name: "JPEG", data: testJPEG(t, img, tiff), wantContentType: "image/jpeg"},
+		{name: "PNG", data: testPNG(t, img, tiff), wantContentType: "image/png"},
+		// Opaque WebP originals are stored lossy rather than as larger lossless WebP
+		{name: "WebP", data: testWebP(t, img, tiff), wantContentType: "image/jpeg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !bytes.Contains(tt.data, []byte(gpsMarker)) || Orientation(tt.data) != 6 {
+				t.Fatal("test image has no EXIF block")
+			}
+
+			result, err := Process(tt.data, opts)
+			if err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+
+			// Orientation 6 turns the 40x20 image upright as 20x40
+			original := result.Original
+			if original.ContentType != tt.wantContentType || original.Width != 20 || original.Height != 40 {
+				t.Errorf("original = %s %dx%d", original.ContentType, original.Width, original.Height)
+			}
+
+			outputs := append([]Output{original}, result.Variants...)
+			for _, output := range outputs {
+				if bytes.Contains(output.Data, []byte(gpsMarker)) || bytes.Contains(output.Data, []byte("Exif")) ||
+					bytes.Contains(output.Data, []byte("eXIf")) || bytes.Contains(output.Data, []byte("EXIF")) {
+					t.Errorf("%q output still contains EXIF", output.Name)
+				}
+				if Orientation(output.Data) != 1 {
+					t.Errorf("%q output has an orientation", output.Name)
+				}
+				if _, _, err := image.Decode(bytes.NewReader(output.Data)); err != nil {
+					t.Errorf("%q output cannot be decoded: %v", output.Name, err)
+				}
+			}
+
+			if len(result.Variants) != 2 {
+				t.Fatalf("variants = %d, want 2", len(result.Variants))
+			}
+			thumbnail, medium := result.Variants[0], result.Variants[1]
+			if thumbnail.Name != "thumbnail" || thumbnail.ContentType != "image/webp" || thumbnail.Width != 5 || thumbnail.Height != 10 {
+				t.Errorf("thumbnail = %s %s %dx%d", thumbnail.Name, thumbnail.ContentType, thumbnail.Width, thumbnail.Height)
+			}
+			if medium.Name != "medium" || medium.ContentType != "image/jpeg" || medium.Width != 15 || medium.Height != 30 {
+				t.Errorf("medium = %s %s %dx%d", medium.Name, medium.ContentType, medium.Width, medium.Height)
+			}
+		})
+	}
+}
+
+func TestProcessRejectsInvalidInput(t *testing.T) {
+	if _, err := Process([]byte("not an image"), Options{}); err == nil {
+		t.Error("Process() accepted data that is not an image")
+	}
+
+	data := testPNG(t, testImage(4, 4), nil)
+	if _, err := Process(data, Options{Variants: []Variant{{Name: "gif", MaxSize: 2, Format: "gif"}}}); err == nil {
+		t.Error("Process() accepted an unsupported variant format")
+	}
+}
+
+func TestProcessKeepsTransparentWebP(t *testing.T) {
+	img := testImage(8, 8)
+	img.SetNRGBA(0, 0, color.NRGBA{})
+
+	result, err := Process(testWebP(t, img, nil), Options{})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if result.Original.ContentType != "image/webp" || result.Original.Extension != ".webp" {
+		t.Errorf("original = %s %s, want lossless WebP", result.Original.ContentType, result.Original.Extension)
+	}
+}
@@ -0,0 +1,241 @@
+package oidc_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amirullazmi0/kratify-backend/pkg/oidc"
+	"github.com/amirullazmi0/kratify-backend/pkg/oidc/oidctest"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const redirectURL = "http://localhost:8080/api/auth/oauth/test/callback"
+
+func TestDiscoverAndFetchKeys(t *testing.T) {
+	server := oidctest.NewServer(t)
+	provider := oidc.NewProvider(server.Config("test"), nil)
+	ctx := context.Background()
+
+	discovery, err := provider.Discover(ctx)
+	if err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+	if discovery.Issuer != server.URL || discovery.TokenEndpoint != server.URL+"/token" || discovery.JWKSURI != server.URL+"/jwks" {
+		t.Errorf("Discover() = %+v", discovery)
+	}
+
+	// The keys are fetched once and then cached
+	for i := 0; i < 2; i++ {
+		if _, err := provider.VerifyIDToken(ctx, server.Sign(server.IDToken(oidctest.Identity{Subject: "s"}, "n")), "n"); err != nil {
+			t.Fatalf("VerifyIDToken() error = %v", err)
+		}
+	}
+	if got := server.JWKSRequests(); got != 1 {
+		t.Errorf("JWKS fetched %d times, want 1", got)
+	}
+}
+
+func TestDiscoverRejectsIssuerMismatch(t *testing.T) {
+	server := oidctest.NewServer(t)
+	server.SetDiscoveryIssuer("https://evil.example.com")
+	provider := oidc.NewProvider(server.Config("test"), nil)
+
+	if _, err := provider.Discover(context.Background()); err == nil || !strings.Contains(err.Error(), "issuer mismatch") {
+		t.Fatalf("Discover() error = %v, want issuer mismatch", err)
+	}
+}
+
+func TestAuthorizationCodeFlowWithPKCE(t *testing.T) {
+	server := oidctest.NewServer(t)
+	provider := oidc.NewProvider(server.Config("test"), nil)
+	ctx := context.Background()
+
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		t.Fatalf("NewPKCE() error = %v", err)
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	if challenge != base64.RawURLEncoding.EncodeToString(sum[:]) {
+		t.Fatalf("challenge is not the S256 of the verifier")
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, redirectURL, "state-1", "nonce-1", challenge)
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+	u, _ := url.Parse(authURL)
+	if got := u.Query().Get("scope"); got != "openid email profile" {
+		t.Errorf("scope = %q", got)
+	}
+
+	code, state, err := server.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("Authorize() error = %v", err)
+	}
+	if state != "state-1" {
+		t.Errorf("state = %q, want state-1", state)
+	}
+
+	token, err := provider.Exchange(ctx, redirectURL, code, verifier)
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	claims, err := provider.VerifyIDToken(ctx, token.IDToken, "nonce-1")
+	if err != nil {
+		t.Fatalf("VerifyIDToken() error = %v", err)
+	}
+	if claims.Subject != "subject-1" || claims.Email != "jane@example.com" || !claims.EmailVerified {
+		t.Errorf("claims = %+v", claims)
+	}
+
+	// A code can only be exchanged once
+	if _, err := provider.Exchange(ctx, redirectURL, code, verifier); err == nil {
+		t.Error("Exchange() of a used code succeeded")
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	server := oidctest.NewServer(t)
+	provider := oidc.NewProvider(server.Config("test"), nil)
+	ctx := context.Background()
+
+	_, challenge, _ := oidc.NewPKCE()
+	otherVerifier, _, _ := oidc.NewPKCE()
+
+	authURL, err := provider.AuthCodeURL(ctx, redirectURL, "state", "nonce", challenge)
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+	code, _, err := server.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("Authorize() error = %v", err)
+	}
+
+	if _, err := provider.Exchange(ctx, redirectURL, code, otherVerifier); err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Fatalf("Exchange() error = %v, want invalid_grant", err)
+	}
+}
+
+func TestVerifyIDTokenRejectsInvalidTokens(t *testing.T) {
+	server := oidctest.NewServer(t)
+	provider := oidc.NewProvider(server.Config("test"), nil)
+	identity := oidctest.Identity{Subject: "subject-1", Email: "jane@example.com", EmailVerified: true}
+
+	signed := func(modify func(claims jwt.MapClaims)) string {
+		claims := server.IDToken(identity, "nonce")
+		modify(claims)
+		return server.Sign(claims)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		nonce string
+		want  string
+	}{
+		{
+			name:  "wrong issuer",
+			token: signed(func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }),
+			nonce: "nonce",
+			want:  "invalid issuer",
+		},
+		{
+			name:  "wrong audience",
+			token: signed(func(c jwt.MapClaims) { c["aud"] = "another-client" }),
+			nonce: "nonce",
+			want:  "invalid audience",
+		},
+		{
+			name:  "wrong nonce",
+			token: signed(func(jwt.MapClaims) {}),
+			nonce: "another-nonce",
+			want:  "nonce mismatch",
+		},
+		{
+			name:  "expired",
+			token: signed(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }),
+			nonce: "nonce",
+			want:  "token is expired",
+		},
+		{
+			name:  "without expiry",
+			token: signed(func(c jwt.MapClaims) { delete(c, "exp") }),
+			nonce: "nonce",
+			want:  "exp claim is required",
+		},
+		{
+			name:  "without subject",
+			token: signed(func(c jwt.MapClaims) { delete(c, "sub") }),
+			nonce: "nonce",
+			want:  "missing subject",
+		},
+		{
+			name: "alg none",
+			token: func() string {
+				token := jwt.NewWithClaims(jwt.SigningMethodNone, server.IDToken(identity, "nonce"))
+				token.Header["kid"] = oidctest.KeyID
+				s, _ := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+				return s
+			}(),
+			nonce: "nonce",
+			want:  "signing method none is invalid",
+		},
+		{
+			name: "alg HS256 with the public key as secret",
+			token: func() string {
+				token := jwt.NewWithClaims(jwt.SigningMethodHS256, server.IDToken(identity, "nonce"))
+				token.Header["kid"] = oidctest.KeyID
+				s, _ := token.SignedString(server.Key.PublicKey.N.Bytes())
+				return s
+			}(),
+			nonce: "nonce",
+			want:  "signing method HS256 is invalid",
+		},
+		{
+			name: "signed by another key",
+			token: func() string {
+				other := oidctest.NewServer(t)
+				claims := server.IDToken(identity, "nonce")
+				return other.Sign(claims)
+			}(),
+			nonce: "nonce",
+			want:  "verification error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := provider.VerifyIDToken(context.Background(), tt.token, tt.nonce)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("VerifyIDToken() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestStateRoundTrip(t *testing.T) {
+	state := oidc.LoginState{Provider: "test", State: "s", Nonce: "n", CodeVerifier: "v", ExpiresAt: time.Now().Add(time.Minute).Unix()}
+	encoded, err := oidc.EncodeState("secret", state)
+	if err != nil {
+		t.Fatalf("EncodeState() error = %v", err)
+	}
+
+	decoded, err := oidc.DecodeState("secret", encoded)
+	if err != nil || *decoded != state {
+		t.Fatalf("DecodeState() = %+v, %v", decoded, err)
+	}
+	if _, err := oidc.DecodeState("other-secret", encoded); err == nil {
+		t.Error("DecodeState() accepted a state signed with another secret")
+	}
+
+	state.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+	expired, _ := oidc.EncodeState("secret", state)
+	if _, err := oidc.DecodeState("secret", expired); err == nil {
+		t.Error("DecodeState() accepted an expired state")
+	}
+}
@@ -0,0 +1,259 @@
+// Package oidctest provides a local fake OpenID Connect provider for tests.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/amirullazmi0/kratify-backend/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// ClientID and ClientSecret are the credentials the provider accepts
+	ClientID     = "test-client"
+	ClientSecret = "test-secret"
+	// KeyID identifies the signing key in the JWKS
+	KeyID = "test-key"
+)
+
+// Identity is the account a user signs in with at the provider
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// authorization is a code issued by Authorize, waiting to be exchanged
+type authorization struct {
+	challenge   string
+	nonce       string
+	redirectURL string
+	identity    Identity
+}
+
+// Server is a fake provider serving discovery, JWKS and the token endpoint. Users
+// sign in by calling Authorize with the URL the client redirects them to.
+type Server struct {
+	*httptest.Server
+	Key *rsa.PrivateKey
+
+	mu              sync.Mutex
+	identity        Identity
+	codes           map[string]authorization
+	jwksRequests    int
+	idTokenClaims   func(claims jwt.MapClaims)
+	discoveryIssuer string
+}
+
+// NewServer starts a provider that is closed when the test ends
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate signing key: %v", err)
+	}
+
+	s := &Server{
+		Key:      key,
+		identity: Identity{Subject: "subject-1", Email: "jane@example.com", EmailVerified: true, Name: "Jane Doe"},
+		codes:    map[string]authorization{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.serveDiscovery)
+	mux.HandleFunc("/jwks", s.serveJWKS)
+	mux.HandleFunc("/token", s.serveToken)
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// Config returns the provider configuration of a client of the server
+func (s *Server) Config(name string) config.OIDCProviderConfig {
+	return config.OIDCProviderConfig{
+		Name:         name,
+		Issuer:       s.URL,
+		ClientID:     ClientID,
+		ClientSecret: ClientSecret,
+		Scopes:       []string{"openid", "email", "profile"},
+	}
+}
+
+// SetIdentity sets the account users sign in with
+func (s *Server) SetIdentity(identity Identity) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.identity = identity
+}
+
+// ModifyIDToken changes the claims of the ID tokens issued from now on, e.g. to
+// issue tokens for another audience
+func (s *Server) ModifyIDToken(fn func(claims jwt.MapClaims)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.idTokenClaims = fn
+}
+
+// SetDiscoveryIssuer makes discovery announce another issuer than the server URL
+func (s *Server) SetDiscoveryIssuer(issuer string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.discoveryIssuer = issuer
+}
+
+// JWKSRequests returns how often the JWKS was fetched
+func (s *Server) JWKSRequests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.jwksRequests
+}
+
+// Authorize signs the user in at the authorization URL and returns the code and state
+// the provider would redirect back with
+func (s *Server) Authorize(authURL string) (code string, state string, err error) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		return "", "", err
+	}
+	params := u.Query()
+	if params.Get("client_id") != ClientID || params.Get("response_type") != "code" {
+		return "", "", errors.New("invalid authorization request")
+	}
+	if params.Get("code_challenge_method") != "S256" || params.Get("code_challenge") == "" {
+		return "", "", errors.New("authorization request without PKCE")
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	code = base64.RawURLEncoding.EncodeToString(b)
+
+	s.mu.Lock()
+	s.codes[code] = authorization{
+		challenge:   params.Get("code_challenge"),
+		nonce:       params.Get("nonce"),
+		redirectURL: params.Get("redirect_uri"),
+		identity:    s.identity,
+	}
+	s.mu.Unlock()
+
+	return code, params.Get("state"), nil
+}
+
+// IDToken returns valid ID token claims for the identity
+func (s *Server) IDToken(identity Identity, nonce string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":            s.URL,
+		"sub":            identity.Subject,
+		"aud":            ClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"nonce":          nonce,
+		"email":          identity.Email,
+		"email_verified": identity.EmailVerified,
+		"name":           identity.Name,
+	}
+}
+
+// Sign signs the claims with the server key (RS256)
+func (s *Server) Sign(claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = KeyID
+	signed, err := token.SignedString(s.Key)
+	if err != nil {
+		panic(err)
+	}
+	return signed
+}
+
+func (s *Server) serveDiscovery(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	issuer := s.discoveryIssuer
+	s.mu.Unlock()
+	if issuer == "" {
+		issuer = s.URL
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 issuer,
+		"authorization_endpoint": s.URL + "/authorize",
+		"token_endpoint":         s.URL + "/token",
+		"jwks_uri":               s.URL + "/jwks",
+	})
+}
+
+func (s *Server) serveJWKS(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.jwksRequests++
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": KeyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(s.Key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.Key.E)).Bytes()),
+		}},
+	})
+}
+
+func (s *Server) serveToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" ||
+		r.PostForm.Get("client_id") != ClientID || r.PostForm.Get("client_secret") != ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	s.mu.Lock()
+	auth, ok := s.codes[r.PostForm.Get("code")]
+	delete(s.codes, r.PostForm.Get("code"))
+	modify := s.idTokenClaims
+	s.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || auth.redirectURL != r.PostForm.Get("redirect_uri") ||
+		base64.RawURLEncoding.EncodeToString(sum[:]) != auth.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := s.IDToken(auth.identity, auth.nonce)
+	if modify != nil {
+		modify(claims)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     s.Sign(claims),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
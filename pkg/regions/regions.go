@@ -0,0 +1,212 @@
+// Package regions reads the Indonesian administrative region reference data
+// (Kemendagri codes) the application is seeded with.
+package regions
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Region levels, matching the "RegionLevel" database enum
+const (
+	LevelProvince = "PROVINCE"
+	LevelRegency  = "REGENCY"
+	LevelDistrict = "DISTRICT"
+	LevelVillage  = "VILLAGE"
+)
+
+// Provinces is the number of provinces a complete dataset has
+const Provinces = 38
+
+// ErrNoDataset is returned by Load when no dataset is configured
+var ErrNoDataset = errors.New("no region dataset configured")
+
+// Record is one row of the dataset
+type Record struct {
+	Code       string
+	ParentCode string
+	Level      string
+	Name       string
+	PostalCode string
+	// FullName is the name followed by the names of its ancestors,
+	// e.g. "Senayan, Kebayoran Baru, Kota Adm. Jakarta Selatan, DKI Jakarta"
+	FullName string
+	// Latitude and Longitude are the approximate centroid, when known
+	Latitude  *float64
+	Longitude *float64
+}
+
+// Load parses the dataset at path, a CSV with a "code,name,postal_code,latitude,longitude"
+// header of which only code and name are required. Codes are dotted Kemendagri codes
+// (e.g. 31.74.06.1001), so the level and parent follow from the code. Records are
+// returned parents first, in file order.
+func Load(path string) ([]Record, error) {
+	if path == "" {
+		return nil, ErrNoDataset
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read region dataset: %w", err)
+	}
+	defer file.Close()
+
+	return parse(file)
+}
+
+func parse(r io.Reader) ([]Record, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read region dataset header: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	if _, ok := columns["code"]; !ok {
+		return nil, errors.New("region dataset header must have a code column")
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, errors.New("region dataset header must have a name column")
+	}
+
+	field := func(row []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	// full names of the regions read so far, by code
+	seen := make(map[string]string)
+	var records []Record
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		record := Record{
+			Code:       field(row, "code"),
+			Name:       field(row, "name"),
+			PostalCode: field(row, "postal_code"),
+		}
+
+		record.Latitude, err = parseCoordinate(field(row, "latitude"), 90)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: latitude: %w", line, err)
+		}
+		record.Longitude, err = parseCoordinate(field(row, "longitude"), 180)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: longitude: %w", line, err)
+		}
+		if (record.Latitude == nil) != (record.Longitude == nil) {
+			return nil, fmt.Errorf("line %d: latitude and longitude must be set together", line)
+		}
+
+		record.Level, record.ParentCode, err = classify(record.Code)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if record.Name == "" {
+			return nil, fmt.Errorf("line %d: region %s has no name", line, record.Code)
+		}
+		if _, ok := seen[record.Code]; ok {
+			return nil, fmt.Errorf("line %d: duplicate region %s", line, record.Code)
+		}
+
+		record.FullName = record.Name
+		if record.ParentCode != "" {
+			parentName, ok := seen[record.ParentCode]
+			if !ok {
+				return nil, fmt.Errorf("line %d: parent %s of region %s must be listed before it", line, record.ParentCode, record.Code)
+			}
+			record.FullName += ", " + parentName
+		}
+
+		seen[record.Code] = record.FullName
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// parseCoordinate parses an optional coordinate within [-limit, limit]
+func parseCoordinate(value string, limit float64) (*float64, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	coordinate, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, err
+	}
+	if coordinate < -limit || coordinate > limit {
+		return nil, fmt.Errorf("%v is out of range", coordinate)
+	}
+	return &coordinate, nil
+}
+
+// classify derives the level and parent code from a dotted region code
+func classify(code string) (level string, parentCode string, err error) {
+	parts := strings.Split(code, ".")
+	for _, part := range parts {
+		if part == "" || strings.Trim(part, "0123456789") != "" {
+			return "", "", fmt.Errorf("invalid region code %q", code)
+		}
+	}
+
+	switch len(parts) {
+	case 1:
+		level = LevelProvince
+	case 2:
+		level = LevelRegency
+	case 3:
+		level = LevelDistrict
+	case 4:
+		level = LevelVillage
+	default:
+		return "", "", fmt.Errorf("invalid region code %q", code)
+	}
+
+	if len(parts) > 1 {
+		parentCode = strings.Join(parts[:len(parts)-1], ".")
+	}
+	return level, parentCode, nil
+}
+
+// ChildLevel returns the level directly below level, or an empty string for villages
+func ChildLevel(level string) string {
+	switch level {
+	case LevelProvince:
+		return LevelRegency
+	case LevelRegency:
+		return LevelDistrict
+	case LevelDistrict:
+		return LevelVillage
+	}
+	return ""
+}
+
+// Lineage returns the codes of the region and its ancestors, province first
+// (e.g. 31.74.06 gives 31, 31.74, 31.74.06)
+func Lineage(code string) []string {
+	parts := strings.Split(code, ".")
+	codes := make([]string, len(parts))
+	for i := range parts {
+		codes[i] = strings.Join(parts[:i+1], ".")
+	}
+	return codes
+}
@@ -0,0 +1,35 @@
+package regions
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLoadWithoutDataset(t *testing.T) {
+	if _, err := Load(""); !errors.Is(err, ErrNoDataset) {
+		t.Fatalf("Load(\"\") error = %v, want ErrNoDataset", err)
+	}
+}
+
+func TestLoadSample(t *testing.T) {
+	records, err := Load("testdata/sample.csv")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	first := records[0]
+	if first.Code != "31" || first.Level != LevelProvince || first.ParentCode != "" {
+		t.Errorf("records[0] = %+v", first)
+	}
+
+	seen := make(map[string]bool)
+	for _, record := range records {
+		if record.ParentCode != "" && !seen[record.ParentCode] {
+			t.Errorf("%s is listed before its parent %s", record.Code, record.ParentCode)
+		}
+		if record.Level == LevelVillage && record.PostalCode == "" {
+			t.Errorf("village %s has no postal code", record.Code)
+		}
+		seen[record.Code] = true
+	}
+}
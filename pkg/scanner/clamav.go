@@ -0,0 +1,118 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// chunkSize is the size of the chunks streamed to clamd
+const chunkSize = 64 << 10
+
+// defaultTimeout applies when no timeout is configured
+const defaultTimeout = 60 * time.Second
+
+// ClamAV scans files with a clamd daemon using the INSTREAM command
+type ClamAV struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewClamAV connects to clamd at address: "host:port" or "tcp://host:port" for TCP,
+// "unix:///path/to/clamd.sock" for a Unix socket
+func NewClamAV(address string, timeout time.Duration) *ClamAV {
+	network := "tcp"
+	switch {
+	case strings.HasPrefix(address, "unix://"):
+		network, address = "unix", strings.TrimPrefix(address, "unix://")
+	case strings.HasPrefix(address, "tcp://"):
+		address = strings.TrimPrefix(address, "tcp://")
+	}
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	return &ClamAV{network: network, address: address, timeout: timeout}
+}
+
+// Scan streams the content to clamd in length-prefixed chunks and parses the reply,
+// e.g. "stream: OK" or "stream: Eicar-Signature FOUND"
+func (c *ClamAV) Scan(ctx context.Context, r io.Reader) (*Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	// The z prefix makes clamd expect a NUL terminated command and reply
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return nil, fmt.Errorf("failed to send to clamd: %w", err)
+	}
+
+	buf := make([]byte, chunkSize)
+	size := make([]byte, 4)
+	for {
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			_, err := conn.Write(size)
+			if err == nil {
+				_, err = conn.Write(buf[:n])
+			}
+			if err != nil {
+				// clamd closes the connection when the stream exceeds its StreamMaxLength;
+				// its reply explains why
+				break
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return nil, readErr
+		}
+	}
+
+	// A zero length chunk ends the stream
+	binary.BigEndian.PutUint32(size, 0)
+	conn.Write(size)
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		return nil, fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+
+	return parseReply(strings.TrimRight(reply, "\x00\n"))
+}
+
+// parseReply interprets a clamd scan reply
+func parseReply(reply string) (*Result, error) {
+	if strings.HasSuffix(reply, " ERROR") {
+		return nil, fmt.Errorf("clamd failed to scan: %s", reply)
+	}
+
+	_, verdict, found := strings.Cut(reply, ": ")
+	switch {
+	case !found:
+		return nil, fmt.Errorf("unexpected clamd reply %q", reply)
+	case verdict == "OK":
+		return &Result{Clean: true}, nil
+	case strings.HasSuffix(verdict, " FOUND"):
+		return &Result{Signature: strings.TrimSuffix(verdict, " FOUND")}, nil
+	}
+	return nil, fmt.Errorf("unexpected clamd reply %q", reply)
+}
@@ -0,0 +1,229 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubStream is what the stub clamd received in one INSTREAM session
+type stubStream struct {
+	command string
+	chunks  []int
+	content []byte
+}
+
+// stubClamd serves one connection at a time like clamd: it reads the INSTREAM
+// session and writes the reply returned by reply. A negative maxSize disables the
+// stream limit.
+type stubClamd struct {
+	listener net.Listener
+	maxSize  int
+	reply    func(stream *stubStream) string
+	streams  chan *stubStream
+}
+
+func newStubClamd(t *testing.T, maxSize int, reply func(stream *stubStream) string) *stubClamd {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	stub := &stubClamd{listener: listener, maxSize: maxSize, reply: reply, streams: make(chan *stubStream, 10)}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go stub.serve(conn)
+		}
+	}()
+	return stub
+}
+
+func (s *stubClamd) address() string {
+	return s.listener.Addr().String()
+}
+
+func (s *stubClamd) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	command, err := r.ReadString(0)
+	if err != nil {
+		return
+	}
+	stream := &stubStream{command: command}
+	defer func() { s.streams <- stream }()
+
+	size := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(r, size); err != nil {
+			return
+		}
+		n := int(binary.BigEndian.Uint32(size))
+		if n == 0 {
+			break
+		}
+		chunk := make([]byte, n)
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			return
+		}
+		stream.chunks = append(stream.chunks, n)
+		stream.content = append(stream.content, chunk...)
+
+		if s.maxSize >= 0 && len(stream.content) > s.maxSize {
+			// Like clamd, reply at once and stop reading the stream; drain the rest so
+			// the client can still read the reply
+			conn.Write([]byte("INSTREAM size limit exceeded. ERROR\x00"))
+			conn.(*net.TCPConn).CloseWrite()
+			io.Copy(io.Discard, r)
+			return
+		}
+	}
+
+	conn.Write([]byte(s.reply(stream) + "\x00"))
+}
+
+func TestClamAVScanReplies(t *testing.T) {
+	tests := []struct {
+		name          string
+		reply         string
+		wantClean     bool
+		wantSignature string
+		wantErr       string
+	}{
+		{name: "clean", reply: "stream: OK", wantClean: true},
+		{name: "infected", reply: "stream: Eicar-Test-Signature FOUND", wantSignature: "Eicar-Test-Signature"},
+		{name: "scan error", reply: "stream: Can't allocate memory ERROR", wantErr: "clamd failed to scan"},
+		{name: "unexpected", reply: "PONG", wantErr: "unexpected clamd reply"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newStubClamd(t, -1, func(*stubStream) string { return tt.reply })
+			clamav := NewClamAV(stub.address(), time.Second)
+
+			result, err := clamav.Scan(context.Background(), strings.NewReader("content"))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Scan() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Scan() error = %v", err)
+			}
+			if result.Clean != tt.wantClean || result.Signature != tt.wantSignature {
+				t.Errorf("Scan() = %+v", result)
+			}
+		})
+	}
+}
+
+func TestClamAVStreamsChunks(t *testing.T) {
+	stub := newStubClamd(t, -1, func(*stubStream) string { return "stream: OK" })
+	clamav := NewClamAV("tcp://"+stub.address(), time.Second)
+
+	content := bytes.Repeat([]byte("0123456789abcdef"), (2*chunkSize+1000)/16)
+	if _, err := clamav.Scan(context.Background(), bytes.NewReader(content)); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	stream := <-stub.streams
+	if stream.command != "zINSTREAM\x00" {
+		t.Errorf("command = %q, want zINSTREAM", stream.command)
+	}
+	if !bytes.Equal(stream.content, content) {
+		t.Errorf("clamd received %d bytes, want %d", len(stream.content), len(content))
+	}
+	if len(stream.chunks) != 3 {
+		t.Errorf("chunks = %v, want 3", stream.chunks)
+	}
+	for _, n := range stream.chunks {
+		if n > chunkSize {
+			t.Errorf("chunk of %d bytes exceeds %d", n, chunkSize)
+		}
+	}
+}
+
+func TestClamAVSizeLimit(t *testing.T) {
+	stub := newStubClamd(t, chunkSize, func(*stubStream) string { return "stream: OK" })
+	clamav := NewClamAV(stub.address(), time.Second)
+
+	content := bytes.Repeat([]byte{'x'}, 4*chunkSize)
+	_, err := clamav.Scan(context.Background(), bytes.NewReader(content))
+	if err == nil || !strings.Contains(err.Error(), "size limit exceeded") {
+		t.Fatalf("Scan() error = %v, want size limit exceeded", err)
+	}
+}
+
+func TestClamAVTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	// Accept and never reply
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.Copy(io.Discard, conn)
+	}()
+
+	clamav := NewClamAV(listener.Addr().String(), 200*time.Millisecond)
+	started := time.Now()
+	if _, err := clamav.Scan(context.Background(), strings.NewReader("content")); err == nil {
+		t.Fatal("Scan() succeeded without a reply")
+	}
+	if elapsed := time.Since(started); elapsed > 2*time.Second {
+		t.Errorf("Scan() returned after %s, want about 200ms", elapsed)
+	}
+}
+
+func TestClamAVConnectionRefused(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	_, err = NewClamAV(address, time.Second).Scan(context.Background(), strings.NewReader("content"))
+	if err == nil || !strings.Contains(err.Error(), "failed to connect to clamd") {
+		t.Fatalf("Scan() error = %v, want connect error", err)
+	}
+}
+
+func TestNewClamAVAddress(t *testing.T) {
+	tests := []struct {
+		address     string
+		wantNetwork string
+		wantAddress string
+	}{
+		{address: "localhost:3310", wantNetwork: "tcp", wantAddress: "localhost:3310"},
+		{address: "tcp://clamav:3310", wantNetwork: "tcp", wantAddress: "clamav:3310"},
+		{address: "unix:///var/run/clamav/clamd.sock", wantNetwork: "unix", wantAddress: "/var/run/clamav/clamd.sock"},
+	}
+
+	for _, tt := range tests {
+		clamav := NewClamAV(tt.address, 0)
+		if clamav.network != tt.wantNetwork || clamav.address != tt.wantAddress || clamav.timeout != defaultTimeout {
+			t.Errorf("NewClamAV(%q) = %+v", tt.address, clamav)
+		}
+	}
+}
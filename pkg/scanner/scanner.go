@@ -0,0 +1,20 @@
+// Package scanner checks uploaded files for malware.
+package scanner
+
+import (
+	"context"
+	"io"
+)
+
+// Result is the verdict on a scanned file
+type Result struct {
+	Clean bool
+	// Signature names the malware found; empty when the file is clean
+	Signature string
+}
+
+// Scanner checks the content read from r for malware. An error means the file could
+// not be scanned, not that it is infected.
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) (*Result, error)
+}
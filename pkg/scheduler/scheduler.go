@@ -0,0 +1,70 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/amirullazmi0/kratify-backend/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Job is a unit of background work; it should return promptly once ctx is cancelled
+type Job func(ctx context.Context) error
+
+// Scheduler runs named jobs at fixed intervals until it is stopped
+type Scheduler struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates a scheduler whose jobs stop when Stop is called
+func New() *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{ctx: ctx, cancel: cancel}
+}
+
+// Every runs job once immediately and then every interval. Runs never overlap.
+func (s *Scheduler) Every(name string, interval time.Duration, job Job) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			s.run(name, job)
+
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	logger.Info("Scheduled background job", zap.String("job", name), zap.Duration("interval", interval))
+}
+
+// Stop cancels running jobs and waits for them to return
+func (s *Scheduler) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *Scheduler) run(name string, job Job) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("Background job panicked", zap.String("job", name), zap.Any("panic", r))
+		}
+	}()
+
+	start := time.Now()
+	if err := job(s.ctx); err != nil {
+		logger.Error("Background job failed", zap.String("job", name), zap.Error(err))
+		return
+	}
+	logger.Debug("Background job finished", zap.String("job", name), zap.Duration("duration", time.Since(start)))
+}
@@ -0,0 +1,216 @@
+// Package spreadsheet reads and writes tabular files (CSV and XLSX) as a header row plus data rows.
+package spreadsheet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Supported formats
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// ErrUnsupportedFormat is returned for files that are neither CSV nor XLSX
+var ErrUnsupportedFormat = errors.New("unsupported file format, use CSV or XLSX")
+
+// sheetName is the sheet written to, and read first, in XLSX files
+const sheetName = "Sheet1"
+
+// formulaPrefixes start the values spreadsheet programs evaluate as formulas
+const formulaPrefixes = "=+-@\t\r"
+
+// Table is a header row and the data rows below it
+type Table struct {
+	Header []string
+	Rows   [][]string
+	// Lines holds the 1-based sheet row number of each entry in Rows; only set by Read
+	Lines []int
+}
+
+// FormatFromFilename returns the format matching the file extension
+func FormatFromFilename(filename string) (string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return FormatCSV, nil
+	case ".xlsx":
+		return FormatXLSX, nil
+	}
+	return "", ErrUnsupportedFormat
+}
+
+// ContentType returns the MIME type of the format
+func ContentType(format string) string {
+	if format == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv"
+}
+
+// Read parses the first sheet of an XLSX file, or a CSV file. Blank rows are skipped
+// and every row is padded to the header length.
+func Read(format string, r io.Reader) (*Table, error) {
+	var records [][]string
+	var lines []int
+
+	switch format {
+	case FormatCSV:
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid CSV file: %w", err)
+			}
+			// The CSV reader skips empty lines, so take the line number from the reader
+			line, _ := reader.FieldPos(0)
+			records = append(records, record)
+			lines = append(lines, line)
+		}
+	case FormatXLSX:
+		file, err := excelize.OpenReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid XLSX file: %w", err)
+		}
+		defer file.Close()
+
+		sheets := file.GetSheetList()
+		if len(sheets) == 0 {
+			return nil, errors.New("XLSX file has no sheets")
+		}
+		records, err = file.GetRows(sheets[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid XLSX file: %w", err)
+		}
+		for i := range records {
+			lines = append(lines, i+1)
+		}
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+
+	if len(records) == 0 {
+		return nil, errors.New("file is empty")
+	}
+
+	table := &Table{Header: make([]string, len(records[0]))}
+	for i, name := range records[0] {
+		// Drop the UTF-8 byte order mark spreadsheet programs add to CSV files
+		table.Header[i] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+	}
+
+	for i, record := range records[1:] {
+		if isBlank(record) {
+			continue
+		}
+		row := make([]string, len(table.Header))
+		for i := 0; i < len(row) && i < len(record); i++ {
+			row[i] = unescapeFormula(strings.TrimSpace(record[i]))
+		}
+		table.Rows = append(table.Rows, row)
+		table.Lines = append(table.Lines, lines[i+1])
+	}
+
+	return table, nil
+}
+
+// Write encodes the table in the given format. Values that a spreadsheet program would
+// evaluate as a formula, e.g. "=HYPERLINK(...)" or "+62812...", are prefixed with a
+// quote so they are shown as text; Read removes the quote again.
+func Write(format string, table *Table) ([]byte, error) {
+	escaped := &Table{Header: escapeFormulas(table.Header), Rows: make([][]string, len(table.Rows))}
+	for i, row := range table.Rows {
+		escaped.Rows[i] = escapeFormulas(row)
+	}
+	table = escaped
+
+	switch format {
+	case FormatCSV:
+		var buf bytes.Buffer
+		writer := csv.NewWriter(&buf)
+		if err := writer.Write(table.Header); err != nil {
+			return nil, err
+		}
+		if err := writer.WriteAll(table.Rows); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case FormatXLSX:
+		file := excelize.NewFile()
+		defer file.Close()
+
+		rows := append([][]string{table.Header}, table.Rows...)
+		for i, row := range rows {
+			cell, err := excelize.CoordinatesToCellName(1, i+1)
+			if err != nil {
+				return nil, err
+			}
+			values := make([]interface{}, len(row))
+			for j, value := range row {
+				values[j] = value
+			}
+			if err := file.SetSheetRow(sheetName, cell, &values); err != nil {
+				return nil, err
+			}
+		}
+
+		var buf bytes.Buffer
+		if err := file.Write(&buf); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+// Column returns the index of the named header column, or -1
+func (t *Table) Column(name string) int {
+	for i, header := range t.Header {
+		if header == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// escapeFormulas returns the values with those that start like a formula prefixed with a quote
+func escapeFormulas(values []string) []string {
+	escaped := make([]string, len(values))
+	for i, value := range values {
+		if value != "" && strings.ContainsRune(formulaPrefixes, rune(value[0])) {
+			value = "'" + value
+		}
+		escaped[i] = value
+	}
+	return escaped
+}
+
+// unescapeFormula removes the quote escapeFormulas adds
+func unescapeFormula(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune(formulaPrefixes, rune(value[1])) {
+		return value[1:]
+	}
+	return value
+}
+
+func isBlank(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,51 @@
+package spreadsheet
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteEscapesFormulas(t *testing.T) {
+	table := &Table{
+		Header: []string{"label", "phone", "note"},
+		Rows: [][]string{
+			{"=HYPERLINK(\"http://evil.example\",\"Home\")", "+6281234567890", "@SUM(A1:A2)"},
+			{"-1", "081234567890", "a = b"},
+		},
+	}
+
+	for _, format := range []string{FormatCSV, FormatXLSX} {
+		t.Run(format, func(t *testing.T) {
+			data, err := Write(format, table)
+			if err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+
+			if format == FormatCSV {
+				lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+				if want := `"'=HYPERLINK(""http://evil.example"",""Home"")",'+6281234567890,'@SUM(A1:A2)`; lines[1] != want {
+					t.Errorf("CSV row = %s, want %s", lines[1], want)
+				}
+				if want := "'-1,081234567890,a = b"; lines[2] != want {
+					t.Errorf("CSV row = %s, want %s", lines[2], want)
+				}
+			}
+
+			// Reading the export back returns the original values
+			read, err := Read(format, bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			for i, row := range table.Rows {
+				if strings.Join(read.Rows[i], "|") != strings.Join(row, "|") {
+					t.Errorf("row %d = %q, want %q", i, read.Rows[i], row)
+				}
+			}
+		})
+	}
+
+	if table.Rows[0][1] != "+6281234567890" {
+		t.Error("Write() modified the table")
+	}
+}
@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/amirullazmi0/kratify-backend/pkg/imagekit"
+	ik "github.com/imagekit-developer/imagekit-go/v2"
+)
+
+// ImageKitProvider stores files in the ImageKit media library. ImageKit makes
+// uploaded names unique, so the key of a stored object differs from the requested one.
+type ImageKitProvider struct {
+	service     imagekit.ImageKitService
+	urlEndpoint string
+	httpClient  *http.Client
+}
+
+// NewImageKitProvider serves files from urlEndpoint
+func NewImageKitProvider(service imagekit.ImageKitService, urlEndpoint string) *ImageKitProvider {
+	return &ImageKitProvider{
+		service:     service,
+		urlEndpoint: strings.TrimRight(urlEndpoint, "/"),
+		httpClient:  http.DefaultClient,
+	}
+}
+
+func (p *ImageKitProvider) Name() string {
+	return ProviderImageKit
+}
+
+func (p *ImageKitProvider) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (*Object, error) {
+	folder, name := path.Split(cleanKey(key))
+
+	resp, err := p.service.UploadFile(ctx, r, name, folder, IsPrivate(key))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Object{
+		Key:         cleanKey(resp.FilePath),
+		ID:          resp.FileID,
+		URL:         resp.URL,
+		Size:        int64(resp.Size),
+		ContentType: contentType,
+	}, nil
+}
+
+// Get downloads the object from its URL, signed for private objects
+func (p *ImageKitProvider) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	object, err := p.Stat(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fileURL := object.URL
+	if IsPrivate(key) {
+		fileURL = p.service.SignURL(fileURL, time.Now().Add(time.Minute))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to download file from imagekit: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, nil, fmt.Errorf("failed to download file from imagekit: status %d", resp.StatusCode)
+	}
+
+	return resp.Body, object, nil
+}
+
+func (p *ImageKitProvider) Delete(ctx context.Context, key string) error {
+	file, err := p.find(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	return p.service.DeleteFile(ctx, file.FileID)
+}
+
+func (p *ImageKitProvider) URL(key string) string {
+	return p.urlEndpoint + "/" + cleanKey(key)
+}
+
+func (p *ImageKitProvider) Stat(ctx context.Context, key string) (*Object, error) {
+	file, err := p.find(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Object{
+		Key:         cleanKey(file.FilePath),
+		ID:          file.FileID,
+		URL:         file.URL,
+		Size:        int64(file.Size),
+		ContentType: file.Mime,
+		ModifiedAt:  file.UpdatedAt,
+	}, nil
+}
+
+// SignedURL signs the URL of the object; ImageKit only serves private files this way
+func (p *ImageKitProvider) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	return p.service.SignURL(p.URL(key), time.Now().Add(expiry)), nil
+}
+
+// PresignUpload returns the parameters of an ImageKit client-side upload. The token
+// is bound to the name and folder of key, which is unique, and forbids overwriting,
+// so it cannot be used to replace another file.
+func (p *ImageKitProvider) PresignUpload(ctx context.Context, key string, contentType string, maxSize int64, expiry time.Duration) (*UploadTarget, error) {
+	// ImageKit rejects tokens that expire more than an hour ahead
+	expiresAt := time.Now().Add(min(expiry, time.Hour))
+
+	folder, name := path.Split(cleanKey(key))
+	fields := map[string]string{
+		"fileName":          name,
+		"folder":            "/" + strings.TrimSuffix(folder, "/"),
+		"useUniqueFileName": "false",
+		"overwriteFile":     "false",
+	}
+	if IsPrivate(key) {
+		fields["isPrivateFile"] = "true"
+	}
+
+	token, err := p.service.ClientUploadToken(fields, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	fields["token"] = token
+
+	return &UploadTarget{
+		Method:    http.MethodPost,
+		URL:       imagekit.UploadEndpoint,
+		Fields:    fields,
+		FileField: "file",
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// find looks up the file stored at key; ImageKit addresses files by ID only
+func (p *ImageKitProvider) find(ctx context.Context, key string) (*ik.AssetListResponseUnion, error) {
+	file, err := p.service.FindFile(ctx, cleanKey(key))
+	if errors.Is(err, imagekit.ErrFileNotFound) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
@@ -0,0 +1,285 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocalRoutePrefix is the route local files are served from by default
+const LocalRoutePrefix = "/files"
+
+// defaultLocalPath is used when STORAGE_LOCAL_PATH is not set
+const defaultLocalPath = "storage"
+
+// LocalProvider stores files in a directory on the local filesystem
+type LocalProvider struct {
+	root       string
+	baseURL    string
+	signingKey []byte
+}
+
+// NewLocalProvider stores files below root, served from baseURL. signingKey signs
+// the URLs clients upload to directly and download private files from.
+func NewLocalProvider(root string, baseURL string, signingKey string) (*LocalProvider, error) {
+	if root == "" {
+		root = defaultLocalPath
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &LocalProvider{
+		root:       root,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		signingKey: []byte(signingKey),
+	}, nil
+}
+
+// Root returns the directory files are stored in
+func (p *LocalProvider) Root() string {
+	return p.root
+}
+
+func (p *LocalProvider) Name() string {
+	return ProviderLocal
+}
+
+// Put writes the content to a temporary file first so readers never see a partial file
+func (p *LocalProvider) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (*Object, error) {
+	key = cleanKey(key)
+	target := p.path(key)
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return nil, err
+	}
+
+	return p.Stat(ctx, key)
+}
+
+func (p *LocalProvider) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	object, err := p.Stat(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := os.Open(p.path(object.Key))
+	if err != nil {
+		return nil, nil, notFound(err)
+	}
+
+	return file, object, nil
+}
+
+func (p *LocalProvider) Delete(ctx context.Context, key string) error {
+	return notFound(os.Remove(p.path(cleanKey(key))))
+}
+
+func (p *LocalProvider) URL(key string) string {
+	return p.baseURL + "/" + cleanKey(key)
+}
+
+func (p *LocalProvider) Stat(ctx context.Context, key string) (*Object, error) {
+	key = cleanKey(key)
+	info, err := os.Stat(p.path(key))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if info.IsDir() {
+		return nil, ErrNotFound
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType, err = sniffFile(p.path(key))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Object{
+		Key:         key,
+		ID:          key,
+		URL:         p.URL(key),
+		Size:        info.Size(),
+		ContentType: contentType,
+		ModifiedAt:  info.ModTime(),
+	}, nil
+}
+
+// SignedURL returns a URL that ServeFile accepts until expiry has passed
+func (p *LocalProvider) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	key = cleanKey(key)
+	expires := time.Now().Add(expiry).Unix()
+
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", signature(p.signingKey, http.MethodGet, key, expires))
+
+	return p.URL(key) + "?" + query.Encode(), nil
+}
+
+// ServeFile serves GET and HEAD requests for stored files. It is mounted under
+// LocalRoutePrefix. Files under PrivateFolder need a URL signed by SignedURL.
+func (p *LocalProvider) ServeFile(w http.ResponseWriter, r *http.Request) {
+	key := cleanKey(strings.TrimPrefix(r.URL.Path, LocalRoutePrefix))
+
+	if IsPrivate(key) {
+		query := r.URL.Query()
+		expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+		if err != nil || !validSignature(signature(p.signingKey, http.MethodGet, key, expires), query.Get("signature")) {
+			http.Error(w, "invalid signature", http.StatusForbidden)
+			return
+		}
+		if time.Now().Unix() > expires {
+			http.Error(w, "download URL has expired", http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Cache-Control", "private, no-store")
+	}
+
+	object, err := p.Stat(r.Context(), key)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, "failed to read file", http.StatusInternalServerError)
+		return
+	}
+	file, err := os.Open(p.path(key))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", object.ContentType)
+	http.ServeContent(w, r, path.Base(key), object.ModifiedAt, file)
+}
+
+// PresignUpload returns a signed URL the file can be PUT to; see ServeUpload
+func (p *LocalProvider) PresignUpload(ctx context.Context, key string, contentType string, maxSize int64, expiry time.Duration) (*UploadTarget, error) {
+	key = cleanKey(key)
+	expiresAt := time.Now().Add(expiry)
+
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expiresAt.Unix(), 10))
+	query.Set("max_size", strconv.FormatInt(maxSize, 10))
+	query.Set("signature", signature(p.signingKey, http.MethodPut, key, expiresAt.Unix(), maxSize))
+
+	return &UploadTarget{
+		Method:    http.MethodPut,
+		URL:       p.URL(key) + "?" + query.Encode(),
+		Headers:   map[string]string{"Content-Type": contentType},
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// UploadTimeout replaces the server's read and write timeouts for an upload body,
+// which may take minutes on a slow connection
+const UploadTimeout = 15 * time.Minute
+
+// ServeUpload stores the body of a PUT request to a URL signed by PresignUpload.
+// It is mounted under LocalRoutePrefix.
+func (p *LocalProvider) ServeUpload(w http.ResponseWriter, r *http.Request) {
+	key := cleanKey(strings.TrimPrefix(r.URL.Path, LocalRoutePrefix))
+	query := r.URL.Query()
+
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid expires", http.StatusBadRequest)
+		return
+	}
+	maxSize, err := strconv.ParseInt(query.Get("max_size"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid max_size", http.StatusBadRequest)
+		return
+	}
+
+	if !validSignature(signature(p.signingKey, http.MethodPut, key, expires, maxSize), query.Get("signature")) {
+		http.Error(w, "invalid signature", http.StatusForbidden)
+		return
+	}
+	if time.Now().Unix() > expires {
+		http.Error(w, "upload URL has expired", http.StatusForbidden)
+		return
+	}
+	if r.ContentLength > maxSize {
+		http.Error(w, "file too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// Only signed requests get the longer deadline; servers without deadlines ignore it
+	controller := http.NewResponseController(w)
+	controller.SetReadDeadline(time.Now().Add(UploadTimeout))
+	controller.SetWriteDeadline(time.Now().Add(UploadTimeout))
+
+	body := http.MaxBytesReader(w, r.Body, maxSize)
+	if _, err := p.Put(r.Context(), key, body, r.ContentLength, r.Header.Get("Content-Type")); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "file too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "failed to store file", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (p *LocalProvider) path(key string) string {
+	return filepath.Join(p.root, filepath.FromSlash(key))
+}
+
+// sniffFile detects the content type from the first 512 bytes of a file
+func sniffFile(name string) (string, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return "", notFound(err)
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	return http.DetectContentType(head[:n]), nil
+}
+
+// notFound maps a missing file to ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
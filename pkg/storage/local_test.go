@@ -0,0 +1,260 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testBaseURL = "http://localhost:8080" + LocalRoutePrefix
+
+func newTestLocalProvider(t *testing.T) *LocalProvider {
+	t.Helper()
+
+	provider, err := NewLocalProvider(t.TempDir(), testBaseURL, "test-signing-key")
+	if err != nil {
+		t.Fatalf("NewLocalProvider() error = %v", err)
+	}
+	return provider
+}
+
+// serve sends a request for the URL, which must lie below testBaseURL, to handler
+func serve(handler http.HandlerFunc, method string, rawURL string, body io.Reader) *httptest.ResponseRecorder {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		panic(err)
+	}
+	req := httptest.NewRequest(method, u.RequestURI(), body)
+	recorder := httptest.NewRecorder()
+	handler(recorder, req)
+	return recorder
+}
+
+func TestLocalProviderRoundTrip(t *testing.T) {
+	provider := newTestLocalProvider(t)
+	ctx := context.Background()
+
+	object, err := provider.Put(ctx, "images/photo.txt", strings.NewReader("hello"), 5, "text/plain")
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if object.Key != "images/photo.txt" || object.ID != object.Key || object.Size != 5 || object.URL != testBaseURL+"/images/photo.txt" {
+		t.Errorf("Put() = %+v", object)
+	}
+	if !strings.HasPrefix(object.ContentType, "text/plain") {
+		t.Errorf("content type = %q, want text/plain", object.ContentType)
+	}
+
+	reader, _, err := provider.Get(ctx, "images/photo.txt")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	content, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(content) != "hello" {
+		t.Errorf("Get() content = %q", content)
+	}
+
+	if err := provider.Delete(ctx, "images/photo.txt"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := provider.Stat(ctx, "images/photo.txt"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Stat() after Delete error = %v, want ErrNotFound", err)
+	}
+	if err := provider.Delete(ctx, "images/photo.txt"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Delete() error = %v, want ErrNotFound", err)
+	}
+	if _, _, err := provider.Get(ctx, "images"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of a directory error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLocalProviderSniffsContentType(t *testing.T) {
+	provider := newTestLocalProvider(t)
+
+	png := "\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 32)
+	object, err := provider.Put(context.Background(), "images/no-extension", strings.NewReader(png), -1, "")
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if object.ContentType != "image/png" {
+		t.Errorf("content type = %q, want image/png", object.ContentType)
+	}
+}
+
+func TestLocalProviderStaysInRoot(t *testing.T) {
+	provider := newTestLocalProvider(t)
+
+	object, err := provider.Put(context.Background(), "../../escape.txt", strings.NewReader("x"), 1, "text/plain")
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if object.Key != "escape.txt" {
+		t.Errorf("key = %q, want escape.txt", object.Key)
+	}
+	if _, err := os.Stat(filepath.Join(provider.Root(), "escape.txt")); err != nil {
+		t.Errorf("file not stored in root: %v", err)
+	}
+}
+
+func TestServeFile(t *testing.T) {
+	provider := newTestLocalProvider(t)
+	ctx := context.Background()
+	provider.Put(ctx, "images/public.txt", strings.NewReader("public"), -1, "text/plain")
+	provider.Put(ctx, "private/documents/secret.txt", strings.NewReader("secret"), -1, "text/plain")
+	provider.Put(ctx, "private/staging/images/unscanned.txt", strings.NewReader("unscanned"), -1, "text/plain")
+
+	signed, _ := provider.SignedURL(ctx, "private/documents/secret.txt", time.Minute)
+	expired, _ := provider.SignedURL(ctx, "private/documents/secret.txt", -time.Minute)
+	otherKey, _ := provider.SignedURL(ctx, "private/documents/other.txt", time.Minute)
+	otherProvider, _ := NewLocalProvider(t.TempDir(), testBaseURL, "another-signing-key")
+	otherSigningKey, _ := otherProvider.SignedURL(ctx, "private/documents/secret.txt", time.Minute)
+
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "public file", url: provider.URL("images/public.txt"), wantStatus: http.StatusOK, wantBody: "public"},
+		{name: "missing file", url: provider.URL("images/missing.txt"), wantStatus: http.StatusNotFound},
+		{name: "signed private file", url: signed, wantStatus: http.StatusOK, wantBody: "secret"},
+		{name: "unsigned private file", url: provider.URL("private/documents/secret.txt"), wantStatus: http.StatusForbidden},
+		{name: "expired signature", url: expired, wantStatus: http.StatusForbidden},
+		{name: "signature for another key", url: strings.Replace(otherKey, "other.txt", "secret.txt", 1), wantStatus: http.StatusForbidden},
+		{name: "signed with another signing key", url: otherSigningKey, wantStatus: http.StatusForbidden},
+		{name: "extended expiry", url: strings.Replace(signed, "expires=", "expires=9", 1), wantStatus: http.StatusForbidden},
+		{name: "staged file awaiting a scan", url: provider.URL("private/staging/images/unscanned.txt"), wantStatus: http.StatusForbidden},
+		{name: "staged file under its public key", url: provider.URL("images/unscanned.txt"), wantStatus: http.StatusNotFound},
+		{name: "private file through a traversal", url: testBaseURL + "/images/../private/documents/secret.txt", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := serve(provider.ServeFile, http.MethodGet, tt.url, nil)
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body.String())
+			}
+			if tt.wantBody != "" && recorder.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", recorder.Body.String(), tt.wantBody)
+			}
+		})
+	}
+
+	recorder := serve(provider.ServeFile, http.MethodGet, signed, nil)
+	if got := recorder.Header().Get("Cache-Control"); got != "private, no-store" {
+		t.Errorf("Cache-Control of a private file = %q", got)
+	}
+}
+
+func TestServeUpload(t *testing.T) {
+	provider := newTestLocalProvider(t)
+	ctx := context.Background()
+
+	presign := func(key string, maxSize int64, expiry time.Duration) string {
+		target, err := provider.PresignUpload(ctx, key, "text/plain", maxSize, expiry)
+		if err != nil {
+			t.Fatalf("PresignUpload() error = %v", err)
+		}
+		if target.Method != http.MethodPut || target.Headers["Content-Type"] != "text/plain" {
+			t.Fatalf("PresignUpload() = %+v", target)
+		}
+		return target.URL
+	}
+
+	tests := []struct {
+		name       string
+		url        string
+		body       io.Reader
+		wantStatus int
+		wantStored bool
+	}{
+		{name: "valid upload", url: presign("uploads/a.txt", 10, time.Minute), body: strings.NewReader("hello"), wantStatus: http.StatusCreated, wantStored: true},
+		{name: "expired", url: presign("uploads/a.txt", 10, -time.Minute), body: strings.NewReader("hello"), wantStatus: http.StatusForbidden},
+		{name: "larger max_size", url: strings.Replace(presign("uploads/a.txt", 10, time.Minute), "max_size=10", "max_size=1000", 1), body: strings.NewReader("hello"), wantStatus: http.StatusForbidden},
+		{name: "another key", url: strings.Replace(presign("uploads/a.txt", 10, time.Minute), "a.txt", "b.txt", 1), body: strings.NewReader("hello"), wantStatus: http.StatusForbidden},
+		{name: "too large", url: presign("uploads/a.txt", 3, time.Minute), body: strings.NewReader("hello"), wantStatus: http.StatusRequestEntityTooLarge},
+		// Without a Content-Length the size is only known while reading
+		{name: "too large without length", url: presign("uploads/a.txt", 3, time.Minute), body: io.MultiReader(strings.NewReader("hel"), strings.NewReader("lo")), wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "missing expires", url: testBaseURL + "/uploads/a.txt?max_size=10&signature=x", body: strings.NewReader("hello"), wantStatus: http.StatusBadRequest},
+		{name: "missing max_size", url: testBaseURL + "/uploads/a.txt?expires=1&signature=x", body: strings.NewReader("hello"), wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider.Delete(ctx, "uploads/a.txt")
+
+			recorder := serve(provider.ServeUpload, http.MethodPut, tt.url, tt.body)
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body.String())
+			}
+
+			_, err := provider.Stat(ctx, "uploads/a.txt")
+			if stored := err == nil; stored != tt.wantStored {
+				t.Errorf("stored = %v, want %v", stored, tt.wantStored)
+			}
+		})
+	}
+}
+
+// slowReader returns its content one byte at a time, waiting before each
+type slowReader struct {
+	content string
+	delay   time.Duration
+}
+
+func (r *slowReader) Read(p []byte) (int, error) {
+	if r.content == "" {
+		return 0, io.EOF
+	}
+	time.Sleep(r.delay)
+	p[0] = r.content[0]
+	r.content = r.content[1:]
+	return 1, nil
+}
+
+func TestServeUploadOutlastsServerTimeouts(t *testing.T) {
+	provider := newTestLocalProvider(t)
+	ctx := context.Background()
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(provider.ServeUpload))
+	server.Config.ReadTimeout = 100 * time.Millisecond
+	server.Config.WriteTimeout = 100 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	target, err := provider.PresignUpload(ctx, "uploads/slow.txt", "text/plain", 10, time.Minute)
+	if err != nil {
+		t.Fatalf("PresignUpload() error = %v", err)
+	}
+	u, _ := url.Parse(target.URL)
+
+	// The body takes about three times the server's timeouts to arrive
+	req, _ := http.NewRequest(http.MethodPut, server.URL+u.RequestURI(), &slowReader{content: "hello", delay: 60 * time.Millisecond})
+	req.ContentLength = 5
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusCreated)
+	}
+
+	reader, _, err := provider.Get(ctx, "uploads/slow.txt")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer reader.Close()
+	if content, _ := io.ReadAll(reader); string(content) != "hello" {
+		t.Errorf("stored content = %q", content)
+	}
+}
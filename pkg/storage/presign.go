@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// Presigner is implemented by providers that accept uploads sent directly by clients
+type Presigner interface {
+	// PresignUpload returns a short-lived target a client can upload one file of at
+	// most maxSize bytes to, stored under key
+	PresignUpload(ctx context.Context, key string, contentType string, maxSize int64, expiry time.Duration) (*UploadTarget, error)
+}
+
+// Signer is implemented by providers that can hand out short-lived download URLs,
+// including for private objects
+type Signer interface {
+	// SignedURL returns a URL the object under key can be downloaded from until expiry has passed
+	SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error)
+}
+
+// UploadTarget tells a client how to upload a file directly to storage
+type UploadTarget struct {
+	// Method is PUT (send the file as the request body) or POST (multipart form)
+	Method string
+	URL    string
+	// Headers must be sent with the request
+	Headers map[string]string
+	// Fields must be sent as form fields of a POST upload, before the file
+	Fields map[string]string
+	// FileField is the form field of the file in a POST upload
+	FileField string
+	ExpiresAt time.Time
+}
+
+// signature is the hex HMAC-SHA256 of the parts joined by newlines
+func signature(key []byte, parts ...interface{}) string {
+	mac := hmac.New(sha256.New, key)
+	for i, part := range parts {
+		if i > 0 {
+			mac.Write([]byte("\n"))
+		}
+		fmt.Fprint(mac, part)
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// validSignature compares signatures in constant time
+func validSignature(expected string, actual string) bool {
+	return hmac.Equal([]byte(expected), []byte(actual))
+}
@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/amirullazmi0/kratify-backend/config"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// unknownSizePartSize is the multipart chunk size used when the upload size is unknown;
+// without it the client would buffer parts sized for a 5 TiB object
+const unknownSizePartSize = 16 << 20
+
+// S3Provider stores files in a bucket of an S3-compatible object store
+type S3Provider struct {
+	client    *minio.Client
+	bucket    string
+	publicURL string
+}
+
+// NewS3Provider connects to the configured bucket
+func NewS3Provider(cfg *config.S3StorageConfig) (*S3Provider, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, errors.New("s3 storage requires S3_ENDPOINT and S3_BUCKET")
+	}
+
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create s3 client: %w", err)
+	}
+
+	publicURL := cfg.PublicURL
+	if publicURL == "" {
+		publicURL = client.EndpointURL().String() + "/" + cfg.Bucket
+	}
+
+	return &S3Provider{
+		client:    client,
+		bucket:    cfg.Bucket,
+		publicURL: strings.TrimRight(publicURL, "/"),
+	}, nil
+}
+
+func (p *S3Provider) Name() string {
+	return ProviderS3
+}
+
+func (p *S3Provider) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (*Object, error) {
+	key = cleanKey(key)
+
+	opts := minio.PutObjectOptions{ContentType: contentType}
+	if size < 0 {
+		opts.PartSize = unknownSizePartSize
+	}
+
+	info, err := p.client.PutObject(ctx, p.bucket, key, r, size, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload object to s3: %w", err)
+	}
+
+	return &Object{
+		Key:         key,
+		ID:          key,
+		URL:         p.URL(key),
+		Size:        info.Size,
+		ContentType: contentType,
+		ModifiedAt:  info.LastModified,
+	}, nil
+}
+
+func (p *S3Provider) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	object, err := p.Stat(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reader, err := p.client.GetObject(ctx, p.bucket, object.Key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, nil, s3Error(err)
+	}
+
+	return reader, object, nil
+}
+
+func (p *S3Provider) Delete(ctx context.Context, key string) error {
+	// S3 reports success for missing keys, so check first to honour ErrNotFound
+	if _, err := p.Stat(ctx, key); err != nil {
+		return err
+	}
+
+	if err := p.client.RemoveObject(ctx, p.bucket, cleanKey(key), minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to delete object from s3: %w", err)
+	}
+	return nil
+}
+
+func (p *S3Provider) URL(key string) string {
+	return p.publicURL + "/" + cleanKey(key)
+}
+
+func (p *S3Provider) Stat(ctx context.Context, key string) (*Object, error) {
+	key = cleanKey(key)
+	info, err := p.client.StatObject(ctx, p.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, s3Error(err)
+	}
+
+	return &Object{
+		Key:         key,
+		ID:          key,
+		URL:         p.URL(key),
+		Size:        info.Size,
+		ContentType: info.ContentType,
+		ModifiedAt:  info.LastModified,
+	}, nil
+}
+
+// PresignUpload returns a presigned PUT URL. S3 cannot limit the size of a PUT, so
+// the size is checked once the upload is completed.
+func (p *S3Provider) PresignUpload(ctx context.Context, key string, contentType string, maxSize int64, expiry time.Duration) (*UploadTarget, error) {
+	presigned, err := p.client.PresignedPutObject(ctx, p.bucket, cleanKey(key), expiry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to presign s3 upload: %w", err)
+	}
+
+	return &UploadTarget{
+		Method:    http.MethodPut,
+		URL:       presigned.String(),
+		Headers:   map[string]string{"Content-Type": contentType},
+		ExpiresAt: time.Now().Add(expiry),
+	}, nil
+}
+
+// SignedURL returns a presigned GET URL. Objects under PrivateFolder should be left
+// out of any public-read bucket policy.
+func (p *S3Provider) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	presigned, err := p.client.PresignedGetObject(ctx, p.bucket, cleanKey(key), expiry, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign s3 download: %w", err)
+	}
+
+	return presigned.String(), nil
+}
+
+// s3Error maps a missing object to ErrNotFound
+func s3Error(err error) error {
+	if minio.ToErrorResponse(err).StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amirullazmi0/kratify-backend/config"
+	"github.com/minio/minio-go/v7"
+)
+
+// newTestS3Provider connects to the MinIO server at MINIO_TEST_ENDPOINT, e.g.
+//
+//	docker run -p 9000:9000 minio/minio server /data
+//	MINIO_TEST_ENDPOINT=localhost:9000 go test ./pkg/storage
+//
+// The credentials default to minioadmin and the bucket, created when missing, to
+// kratify-test.
+func newTestS3Provider(t *testing.T) *S3Provider {
+	t.Helper()
+
+	endpoint := os.Getenv("MINIO_TEST_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("MINIO_TEST_ENDPOINT is not set")
+	}
+	env := func(name string, fallback string) string {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+		return fallback
+	}
+
+	provider, err := NewS3Provider(&config.S3StorageConfig{
+		Endpoint:  endpoint,
+		Region:    "us-east-1",
+		Bucket:    env("MINIO_TEST_BUCKET", "kratify-test"),
+		AccessKey: env("MINIO_TEST_ACCESS_KEY", "minioadmin"),
+		SecretKey: env("MINIO_TEST_SECRET_KEY", "minioadmin"),
+	})
+	if err != nil {
+		t.Fatalf("NewS3Provider() error = %v", err)
+	}
+
+	ctx := context.Background()
+	exists, err := provider.client.BucketExists(ctx, provider.bucket)
+	if err != nil {
+		t.Fatalf("failed to reach MinIO: %v", err)
+	}
+	if !exists {
+		if err := provider.client.MakeBucket(ctx, provider.bucket, minio.MakeBucketOptions{Region: "us-east-1"}); err != nil {
+			t.Fatalf("failed to create bucket: %v", err)
+		}
+	}
+	return provider
+}
+
+func TestS3ProviderRoundTrip(t *testing.T) {
+	provider := newTestS3Provider(t)
+	ctx := context.Background()
+	key, _ := NewKey("test", "hello.txt")
+	t.Cleanup(func() { provider.Delete(ctx, key) })
+
+	// An unknown size is uploaded in parts
+	object, err := provider.Put(ctx, key, strings.NewReader("hello"), -1, "text/plain")
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if object.Key != key || object.Size != 5 {
+		t.Errorf("Put() = %+v", object)
+	}
+
+	stat, err := provider.Stat(ctx, key)
+	if err != nil || stat.ContentType != "text/plain" || stat.Size != 5 {
+		t.Fatalf("Stat() = %+v, %v", stat, err)
+	}
+
+	reader, _, err := provider.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	content, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(content) != "hello" {
+		t.Errorf("Get() content = %q", content)
+	}
+
+	if err := provider.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := provider.Stat(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Stat() after Delete error = %v, want ErrNotFound", err)
+	}
+	if err := provider.Delete(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Delete() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestS3ProviderSignedURLs(t *testing.T) {
+	provider := newTestS3Provider(t)
+	ctx := context.Background()
+	key, _ := NewKey("private/test", "upload.txt")
+	t.Cleanup(func() { provider.Delete(ctx, key) })
+
+	target, err := provider.PresignUpload(ctx, key, "text/plain", 100, time.Minute)
+	if err != nil {
+		t.Fatalf("PresignUpload() error = %v", err)
+	}
+	req, _ := http.NewRequestWithContext(ctx, target.Method, target.URL, bytes.NewReader([]byte("uploaded")))
+	for name, value := range target.Headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("upload status = %d", resp.StatusCode)
+	}
+
+	signed, err := provider.SignedURL(ctx, key, time.Minute)
+	if err != nil {
+		t.Fatalf("SignedURL() error = %v", err)
+	}
+	resp, err = http.Get(signed)
+	if err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	content, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(content) != "uploaded" {
+		t.Errorf("download = %d %q", resp.StatusCode, content)
+	}
+
+	// The bucket is not public, so the plain URL is refused
+	resp, err = http.Get(provider.URL(key))
+	if err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		t.Error("private object downloaded without a signature")
+	}
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/amirullazmi0/kratify-backend/config"
+	"github.com/amirullazmi0/kratify-backend/pkg/imagekit"
+)
+
+// Provider names
+const (
+	ProviderLocal    = "local"
+	ProviderS3       = "s3"
+	ProviderImageKit = "imagekit"
+)
+
+// ErrNotFound is returned when no object is stored under a key
+var ErrNotFound = errors.New("object not found")
+
+// PrivateFolder holds objects that must not be publicly reachable. Providers store
+// them privately where they can; clients reach them through SignedURL.
+const PrivateFolder = "private"
+
+// IsPrivate reports whether the key lies in PrivateFolder
+func IsPrivate(key string) bool {
+	return strings.HasPrefix(cleanKey(key), PrivateFolder+"/")
+}
+
+// Object describes a stored file
+type Object struct {
+	// Key is the slash separated path of the object, e.g. "images/3f9a1c2b-photo.jpg"
+	Key string
+	// ID is the provider's own identifier (the ImageKit file ID); it equals Key for other providers
+	ID          string
+	URL         string
+	Size        int64
+	ContentType string
+	ModifiedAt  time.Time
+}
+
+// Provider stores files by key
+type Provider interface {
+	// Name returns the provider name recorded with each stored file
+	Name() string
+	// Put stores the content under key. size may be -1 when unknown. The key of the
+	// returned object may differ from the requested one if the provider renames files.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (*Object, error)
+	// Get opens the object; the caller must close the reader
+	Get(ctx context.Context, key string) (io.ReadCloser, *Object, error)
+	Delete(ctx context.Context, key string) error
+	// URL returns the public URL of the object without checking that it exists
+	URL(key string) string
+	Stat(ctx context.Context, key string) (*Object, error)
+}
+
+// New returns the provider selected in the configuration
+func New(storageCfg *config.StorageConfig, imageKitCfg *config.ImageKitConfig, appConfig *config.AppConfig) (Provider, error) {
+	provider := storageCfg.Provider
+	if provider == "" {
+		provider = ProviderLocal
+		if strings.TrimSpace(imageKitCfg.PrivateKey) != "" {
+			provider = ProviderImageKit
+		}
+	}
+
+	switch provider {
+	case ProviderLocal:
+		baseURL := storageCfg.Local.URL
+		if baseURL == "" {
+			baseURL = strings.TrimRight(appConfig.BaseURL, "/") + LocalRoutePrefix
+		}
+		return NewLocalProvider(storageCfg.Local.Path, baseURL, storageCfg.SigningKey)
+	case ProviderS3:
+		return NewS3Provider(&storageCfg.S3)
+	case ProviderImageKit:
+		service, err := imagekit.NewImageKitService(imageKitCfg.PublicKey, imageKitCfg.PrivateKey, imageKitCfg.UrlEndpoint)
+		if err != nil {
+			return nil, err
+		}
+		return NewImageKitProvider(service, imageKitCfg.UrlEndpoint), nil
+	}
+
+	return nil, fmt.Errorf("unknown storage provider %q", provider)
+}
+
+var unsafeKeyChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// NewKey returns a unique key for a file uploaded to folder
+func NewKey(folder string, fileName string) (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	name := strings.Trim(unsafeKeyChars.ReplaceAllString(path.Base(fileName), "-"), "-.")
+	if name == "" {
+		name = "file"
+	}
+
+	return path.Join(strings.Trim(folder, "/"), hex.EncodeToString(b)+"-"+name), nil
+}
+
+// cleanKey normalises a key to a relative slash separated path without ".." elements
+func cleanKey(key string) string {
+	return strings.TrimPrefix(path.Clean("/"+key), "/")
+}
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestNewKey(t *testing.T) {
+	tests := []struct {
+		folder   string
+		fileName string
+		want     string
+	}{
+		{folder: "images", fileName: "photo.jpg", want: `^images/[0-9a-f]{16}-photo\.jpg$`},
+		{folder: "/private/documents/", fileName: "KTP scan (1).pdf", want: `^private/documents/[0-9a-f]{16}-KTP-scan-1-\.pdf$`},
+		{folder: "images", fileName: "../../etc/passwd", want: `^images/[0-9a-f]{16}-passwd$`},
+		{folder: "images", fileName: "...", want: `^images/[0-9a-f]{16}-file$`},
+	}
+
+	for _, tt := range tests {
+		key, err := NewKey(tt.folder, tt.fileName)
+		if err != nil {
+			t.Fatalf("NewKey() error = %v", err)
+		}
+		if !regexp.MustCompile(tt.want).MatchString(key) {
+			t.Errorf("NewKey(%q, %q) = %q, want %s", tt.folder, tt.fileName, key, tt.want)
+		}
+	}
+
+	first, _ := NewKey("images", "photo.jpg")
+	second, _ := NewKey("images", "photo.jpg")
+	if first == second {
+		t.Errorf("NewKey() returned %q twice", first)
+	}
+}
+
+func TestIsPrivate(t *testing.T) {
+	tests := map[string]bool{
+		"private/documents/a.pdf":    true,
+		"/private/a.pdf":             true,
+		"images/../private/a.pdf":    true,
+		"images/private/a.pdf":       false,
+		"privateer/a.pdf":            false,
+		"private/../images/a.pdf":    false,
+		"private/quarantine/a.exe":   true,
+		"../private/documents/a.pdf": true,
+	}
+
+	for key, want := range tests {
+		if got := IsPrivate(key); got != want {
+			t.Errorf("IsPrivate(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestSignature(t *testing.T) {
+	key := []byte("signing-key")
+	base := signature(key, "GET", "private/a.pdf", int64(100))
+
+	if !validSignature(base, signature(key, "GET", "private/a.pdf", int64(100))) {
+		t.Error("the same parts produced another signature")
+	}
+
+	others := []string{
+		signature([]byte("other-key"), "GET", "private/a.pdf", int64(100)),
+		signature(key, "PUT", "private/a.pdf", int64(100)),
+		signature(key, "GET", "private/b.pdf", int64(100)),
+		signature(key, "GET", "private/a.pdf", int64(101)),
+		// Parts are separated, so moving characters between them changes the signature
+		signature(key, "GET", "private/a.pdf1", int64(0)),
+	}
+	for i, other := range others {
+		if validSignature(base, other) {
+			t.Errorf("signature %d matches the original", i)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+// Package tus stages resumable uploads (tus 1.0, https://tus.io/protocols/resumable-upload)
+// on the local disk until all their bytes have arrived.
+package tus
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Protocol headers and values
+const (
+	Version    = "1.0.0"
+	Extensions = "creation,termination,expiration"
+	// ContentType is required on PATCH requests
+	ContentType = "application/offset+octet-stream"
+
+	HeaderResumable = "Tus-Resumable"
+	HeaderVersion   = "Tus-Version"
+	HeaderExtension = "Tus-Extension"
+	HeaderMaxSize   = "Tus-Max-Size"
+	HeaderLength    = "Upload-Length"
+	HeaderOffset    = "Upload-Offset"
+	HeaderMetadata  = "Upload-Metadata"
+	HeaderExpires   = "Upload-Expires"
+)
+
+// defaultPath is used when no staging directory is configured
+const defaultPath = "uploads/tus"
+
+var (
+	// ErrNotFound is returned for unknown uploads
+	ErrNotFound = errors.New("upload not found")
+	// ErrOffsetMismatch is returned when a chunk does not start at the current offset
+	ErrOffsetMismatch = errors.New("upload offset does not match")
+	// ErrExceedsLength is returned when a chunk goes past the declared length
+	ErrExceedsLength = errors.New("chunk exceeds the upload length")
+	// ErrLocked is returned while another request is writing to the upload
+	ErrLocked = errors.New("upload is locked by another request")
+)
+
+// Upload is the state of a staged upload
+type Upload struct {
+	ID       string            `json:"id"`
+	OwnerID  string            `json:"owner_id"`
+	Length   int64             `json:"length"`
+	Metadata map[string]string `json:"metadata"`
+	// Offset is the number of bytes received; it is read from the data file
+	Offset    int64     `json:"-"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// AttachmentID is set once the upload is complete and registered; the data is then removed
+	AttachmentID string `json:"attachment_id,omitempty"`
+}
+
+// Done reports whether all bytes have been received
+func (u *Upload) Done() bool {
+	return u.Offset >= u.Length
+}
+
+// Store keeps every upload as an <id>.info file with its state and an <id>.bin file
+// with the bytes received so far
+type Store struct {
+	dir   string
+	locks sync.Map
+}
+
+// NewStore creates the staging directory if needed
+func NewStore(dir string) (*Store, error) {
+	if dir == "" {
+		dir = defaultPath
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &Store{dir: dir}, nil
+}
+
+// Create stages a new, empty upload
+func (s *Store) Create(ownerID string, length int64, metadata map[string]string, expiresAt time.Time) (*Upload, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	upload := &Upload{
+		ID:        id,
+		OwnerID:   ownerID,
+		Length:    length,
+		Metadata:  metadata,
+		ExpiresAt: expiresAt,
+	}
+
+	data, err := os.OpenFile(s.dataPath(id), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	data.Close()
+
+	if err := s.save(upload); err != nil {
+		os.Remove(s.dataPath(id))
+		return nil, err
+	}
+
+	return upload, nil
+}
+
+// Get returns the state of an upload
+func (s *Store) Get(id string) (*Upload, error) {
+	if !validID(id) {
+		return nil, ErrNotFound
+	}
+
+	content, err := os.ReadFile(s.infoPath(id))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	var upload Upload
+	if err := json.Unmarshal(content, &upload); err != nil {
+		return nil, fmt.Errorf("invalid upload state %s: %w", id, err)
+	}
+
+	if upload.AttachmentID != "" {
+		upload.Offset = upload.Length
+		return &upload, nil
+	}
+
+	info, err := os.Stat(s.dataPath(id))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	upload.Offset = info.Size()
+
+	return &upload, nil
+}
+
+// Lock gives the caller exclusive access to an upload until the returned function is
+// called. It fails with ErrLocked rather than waiting, as a client that retries a chunk
+// must not be blocked by its own stalled request. Released locks are dropped, so only
+// uploads being written to hold one, whether they later complete, expire or never existed.
+func (s *Store) Lock(id string) (func(), error) {
+	for {
+		value, _ := s.locks.LoadOrStore(id, &sync.Mutex{})
+		mutex := value.(*sync.Mutex)
+		if !mutex.TryLock() {
+			return nil, ErrLocked
+		}
+
+		// The previous holder dropped this mutex before releasing it; holding it would not
+		// exclude a request that stored a new one
+		if current, ok := s.locks.Load(id); !ok || current != value {
+			mutex.Unlock()
+			continue
+		}
+
+		return func() {
+			s.locks.CompareAndDelete(id, value)
+			mutex.Unlock()
+		}, nil
+	}
+}
+
+// WriteChunk appends the chunk at offset, which must equal the bytes received so far.
+// Bytes read before the reader fails are kept, so the client can resume after them.
+// The caller must hold the lock of the upload.
+func (s *Store) WriteChunk(upload *Upload, offset int64, r io.Reader) error {
+	if offset != upload.Offset {
+		return ErrOffsetMismatch
+	}
+
+	data, err := os.OpenFile(s.dataPath(upload.ID), os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	defer data.Close()
+
+	// Read one byte past the remaining length to detect chunks that are too long
+	remaining := upload.Length - upload.Offset
+	written, err := io.Copy(data, io.LimitReader(r, remaining+1))
+	if written > remaining {
+		if truncateErr := data.Truncate(upload.Length); truncateErr != nil {
+			return truncateErr
+		}
+		upload.Offset = upload.Length
+		return ErrExceedsLength
+	}
+	upload.Offset += written
+
+	return err
+}
+
+// Open returns the received bytes of an upload for reading
+func (s *Store) Open(id string) (*os.File, error) {
+	return os.Open(s.dataPath(id))
+}
+
+// Complete records the attachment registered for a finished upload and removes its data.
+// The state is kept until expiry so that clients can still query the final offset.
+func (s *Store) Complete(upload *Upload, attachmentID string) error {
+	upload.AttachmentID = attachmentID
+	if err := s.save(upload); err != nil {
+		return err
+	}
+
+	if err := os.Remove(s.dataPath(upload.ID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Delete removes an upload and its data
+func (s *Store) Delete(id string) error {
+	for _, name := range []string{s.dataPath(id), s.infoPath(id)} {
+		if err := os.Remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Expired returns the uploads whose expiry has passed, oldest first
+func (s *Store) Expired(now time.Time) ([]*Upload, error) {
+	names, err := filepath.Glob(filepath.Join(s.dir, "*.info"))
+	if err != nil {
+		return nil, err
+	}
+
+	var uploads []*Upload
+	for _, name := range names {
+		upload, err := s.Get(strings.TrimSuffix(filepath.Base(name), ".info"))
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		if now.After(upload.ExpiresAt) {
+			uploads = append(uploads, upload)
+		}
+	}
+
+	sort.Slice(uploads, func(i, j int) bool {
+		return uploads[i].ExpiresAt.Before(uploads[j].ExpiresAt)
+	})
+	return uploads, nil
+}
+
+// save writes the state to a temporary file first so readers never see a partial file
+func (s *Store) save(upload *Upload) error {
+	content, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+
+	temp := s.infoPath(upload.ID) + ".tmp"
+	if err := os.WriteFile(temp, content, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(temp, s.infoPath(upload.ID))
+}
+
+func (s *Store) infoPath(id string) string {
+	return filepath.Join(s.dir, id+".info")
+}
+
+func (s *Store) dataPath(id string) string {
+	return filepath.Join(s.dir, id+".bin")
+}
+
+// ParseMetadata decodes an Upload-Metadata header: comma separated pairs of a key
+// and an optional base64 encoded value
+func ParseMetadata(header string) (map[string]string, error) {
+	metadata := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		key, encoded, _ := strings.Cut(pair, " ")
+		value, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of metadata %q", key)
+		}
+		metadata[key] = string(value)
+	}
+
+	return metadata, nil
+}
+
+// EncodeMetadata encodes metadata for the Upload-Metadata header
+func EncodeMetadata(metadata map[string]string) string {
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+" "+base64.StdEncoding.EncodeToString([]byte(metadata[key])))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func newID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// validID keeps IDs taken from URLs from escaping the staging directory
+func validID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
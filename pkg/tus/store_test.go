@@ -0,0 +1,117 @@
+package tus
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	return store
+}
+
+func lockCount(s *Store) int {
+	count := 0
+	s.locks.Range(func(any, any) bool {
+		count++
+		return true
+	})
+	return count
+}
+
+func TestLockIsExclusive(t *testing.T) {
+	store := newTestStore(t)
+
+	unlock, err := store.Lock("a")
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	if _, err := store.Lock("a"); !errors.Is(err, ErrLocked) {
+		t.Errorf("second Lock() error = %v, want ErrLocked", err)
+	}
+	other, err := store.Lock("b")
+	if err != nil {
+		t.Fatalf("Lock() of another upload error = %v", err)
+	}
+	other()
+
+	unlock()
+	unlock, err = store.Lock("a")
+	if err != nil {
+		t.Fatalf("Lock() after unlock error = %v", err)
+	}
+	unlock()
+}
+
+func TestLockIsDroppedWhenReleased(t *testing.T) {
+	store := newTestStore(t)
+
+	upload, err := store.Create("owner", 3, nil, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// Completed, expired and unknown uploads must not keep a lock
+	for _, id := range []string{upload.ID, "unknown"} {
+		unlock, err := store.Lock(id)
+		if err != nil {
+			t.Fatalf("Lock(%q) error = %v", id, err)
+		}
+		if id == upload.ID {
+			if err := store.Complete(upload, "attachment"); err != nil {
+				t.Fatalf("Complete() error = %v", err)
+			}
+		}
+		unlock()
+	}
+
+	unlock, _ := store.Lock(upload.ID)
+	if err := store.Delete(upload.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	unlock()
+
+	if n := lockCount(store); n != 0 {
+		t.Errorf("store holds %d locks after every one was released", n)
+	}
+}
+
+func TestLockUnderContention(t *testing.T) {
+	store := newTestStore(t)
+
+	var holders, overlaps atomic.Int32
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 200 {
+				unlock, err := store.Lock("a")
+				if err != nil {
+					continue
+				}
+				if holders.Add(1) > 1 {
+					overlaps.Add(1)
+				}
+				holders.Add(-1)
+				unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := overlaps.Load(); n > 0 {
+		t.Errorf("lock was held by more than one request %d times", n)
+	}
+	if n := lockCount(store); n != 0 {
+		t.Errorf("store holds %d locks after every one was released", n)
+	}
+}
@@ -0,0 +1,179 @@
+package upload
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	// Image formats whose dimensions can be checked
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	// ErrFileTooLarge is wrapped by violations of a size or dimension limit
+	ErrFileTooLarge = errors.New("file too large")
+	// ErrUnsupportedType is wrapped by violations of the allowed types or extensions
+	ErrUnsupportedType = errors.New("unsupported file type")
+)
+
+// Policy declares what files an upload accepts. Zero limits are not enforced.
+type Policy struct {
+	// AllowedTypes are MIME types, checked against the type detected from the file's magic bytes
+	AllowedTypes []string
+	// AllowedExtensions are lower case file name extensions including the dot
+	AllowedExtensions []string
+	MaxSize           int64
+	MaxWidth          int
+	MaxHeight         int
+	// MaxPixels bounds width times height, and with it the memory needed to decode the image
+	MaxPixels int64
+}
+
+// File describes an accepted upload
+type File struct {
+	ContentType string
+	Size        int64
+	// Width and Height are only set for images
+	Width  int
+	Height int
+}
+
+// Violation explains why a file was rejected; it wraps ErrFileTooLarge or ErrUnsupportedType
+type Violation struct {
+	err     error
+	message string
+}
+
+func (v *Violation) Error() string {
+	return v.message
+}
+
+func (v *Violation) Unwrap() error {
+	return v.err
+}
+
+func tooLarge(format string, args ...interface{}) error {
+	return &Violation{err: ErrFileTooLarge, message: fmt.Sprintf(format, args...)}
+}
+
+func unsupported(format string, args ...interface{}) error {
+	return &Violation{err: ErrUnsupportedType, message: fmt.Sprintf(format, args...)}
+}
+
+// Check validates the file against the policy by reading its header, then rewinds it
+func (p Policy) Check(fileName string, file io.ReadSeeker) (*File, error) {
+	size, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.CheckSize(size); err != nil {
+		return nil, err
+	}
+	if size == 0 {
+		return nil, unsupported("file is empty")
+	}
+
+	ext := strings.ToLower(filepath.Ext(fileName))
+	if len(p.AllowedExtensions) > 0 && !slices.Contains(p.AllowedExtensions, ext) {
+		return nil, unsupported("file extension %q is not allowed, use %s", ext, strings.Join(p.AllowedExtensions, ", "))
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+
+	result := &File{
+		ContentType: DetectContentType(head[:n]),
+		Size:        size,
+	}
+	if len(p.AllowedTypes) > 0 && !slices.Contains(p.AllowedTypes, result.ContentType) {
+		return nil, unsupported("file content is %s, allowed types are %s", result.ContentType, strings.Join(p.AllowedTypes, ", "))
+	}
+
+	if strings.HasPrefix(result.ContentType, "image/") && (p.MaxWidth > 0 || p.MaxHeight > 0 || p.MaxPixels > 0) {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		config, _, err := image.DecodeConfig(file)
+		if err != nil {
+			return nil, unsupported("image cannot be decoded: %v", err)
+		}
+		result.Width, result.Height = config.Width, config.Height
+
+		if (p.MaxWidth > 0 && config.Width > p.MaxWidth) || (p.MaxHeight > 0 && config.Height > p.MaxHeight) {
+			return nil, tooLarge("image is %dx%d pixels, the maximum is %dx%d", config.Width, config.Height, p.MaxWidth, p.MaxHeight)
+		}
+		if pixels := int64(config.Width) * int64(config.Height); p.MaxPixels > 0 && pixels > p.MaxPixels {
+			return nil, tooLarge("image has %.1f megapixels, the maximum is %.1f", float64(pixels)/1e6, float64(p.MaxPixels)/1e6)
+		}
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// CheckSize rejects a file larger than MaxSize, such as a file re-encoded after Check
+func (p Policy) CheckSize(size int64) error {
+	if p.MaxSize > 0 && size > p.MaxSize {
+		return tooLarge("file is %s, the maximum is %s", FormatSize(size), FormatSize(p.MaxSize))
+	}
+	return nil
+}
+
+// CheckDeclared validates what a client says it will upload, so that direct uploads
+// can be refused before they start. The content is checked again by Check once uploaded.
+func (p Policy) CheckDeclared(fileName string, contentType string, size int64) error {
+	if err := p.CheckSize(size); err != nil {
+		return err
+	}
+
+	ext := strings.ToLower(filepath.Ext(fileName))
+	if len(p.AllowedExtensions) > 0 && !slices.Contains(p.AllowedExtensions, ext) {
+		return unsupported("file extension %q is not allowed, use %s", ext, strings.Join(p.AllowedExtensions, ", "))
+	}
+	if len(p.AllowedTypes) > 0 && !slices.Contains(p.AllowedTypes, contentType) {
+		return unsupported("file type %s is not allowed, allowed types are %s", contentType, strings.Join(p.AllowedTypes, ", "))
+	}
+
+	return nil
+}
+
+// DetectContentType returns the MIME type matching the magic bytes of head,
+// without parameters such as the charset
+func DetectContentType(head []byte) string {
+	contentType := http.DetectContentType(head)
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = strings.TrimSpace(contentType[:i])
+	}
+	return contentType
+}
+
+// FormatSize renders a byte count for error messages, e.g. "5 MB"
+func FormatSize(size int64) string {
+	switch {
+	case size >= 1<<20 && size%(1<<20) == 0:
+		return fmt.Sprintf("%d MB", size>>20)
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%d KB", size>>10)
+	}
+	return fmt.Sprintf("%d bytes", size)
+}
@@ -50,6 +50,12 @@ func formatErrorMessage(e validator.FieldError) string {
 		return fmt.Sprintf("%s must be greater than or equal to %s", e.Field(), e.Param())
 	case "lte":
 		return fmt.Sprintf("%s must be less than or equal to %s", e.Field(), e.Param())
+	case "latitude":
+		return fmt.Sprintf("%s must be between -90 and 90", e.Field())
+	case "longitude":
+		return fmt.Sprintf("%s must be between -180 and 180", e.Field())
+	case "oneof":
+		return fmt.Sprintf("%s must be one of: %s", e.Field(), e.Param())
 	default:
 		return fmt.Sprintf("%s is invalid", e.Field())
 	}
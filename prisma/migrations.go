@@ -0,0 +1,18 @@
+// Package prisma embeds the SQL migrations so the binary can apply them itself, see
+// pkg/database/migrate.
+package prisma
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed migrations
+var migrations embed.FS
+
+// Migrations returns the migration directories, e.g. 20251227081022_init/migration.sql
+func Migrations() fs.FS {
+	// Sub only fails for an invalid path
+	sub, _ := fs.Sub(migrations, "migrations")
+	return sub
+}
@@ -0,0 +1,2 @@
+-- DropTable
+DROP TABLE "api_keys";
@@ -0,0 +1,2 @@
+-- DropTable
+DROP TABLE "magic_link_tokens";
@@ -0,0 +1,23 @@
+-- CreateTable
+CREATE TABLE "audit_logs" (
+    "id" UUID NOT NULL DEFAULT gen_random_uuid(),
+    "actor_id" UUID,
+    "subject_user_id" UUID,
+    "action" VARCHAR(100) NOT NULL,
+    "metadata" JSONB,
+    "ip" VARCHAR(64),
+    "user_agent" TEXT,
+    "request_id" VARCHAR(64),
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "audit_logs_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE INDEX "audit_logs_actor_id_idx" ON "audit_logs"("actor_id");
+
+-- CreateIndex
+CREATE INDEX "audit_logs_subject_user_id_idx" ON "audit_logs"("subject_user_id");
+
+-- CreateIndex
+CREATE INDEX "audit_logs_action_created_at_idx" ON "audit_logs"("action", "created_at");
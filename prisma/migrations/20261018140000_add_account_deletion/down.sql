@@ -0,0 +1,6 @@
+-- DropIndex
+DROP INDEX "users_deletion_scheduled_at_idx";
+
+-- AlterTable
+ALTER TABLE "users" DROP COLUMN "deletion_requested_at",
+DROP COLUMN "deletion_scheduled_at";
@@ -0,0 +1,8 @@
+-- DropIndex
+DROP INDEX "users_reactivation_token_hash_idx";
+
+-- AlterTable
+ALTER TABLE "users" DROP COLUMN "deactivated_at",
+DROP COLUMN "sessions_revoked_at",
+DROP COLUMN "reactivation_token_hash",
+DROP COLUMN "reactivation_expiry";
@@ -0,0 +1,8 @@
+-- AlterTable
+ALTER TABLE "users" ADD COLUMN "deactivated_at" TIMESTAMP(3),
+ADD COLUMN "sessions_revoked_at" TIMESTAMP(3),
+ADD COLUMN "reactivation_token_hash" VARCHAR(64),
+ADD COLUMN "reactivation_expiry" TIMESTAMP(3);
+
+-- CreateIndex
+CREATE INDEX "users_reactivation_token_hash_idx" ON "users"("reactivation_token_hash");
@@ -0,0 +1,2 @@
+-- DropIndex; the primary flags fixed up by the migration are kept
+DROP INDEX "addresses_user_id_primary_key";
@@ -0,0 +1,21 @@
+-- Keep only the most recent primary address per user
+UPDATE "addresses" SET "is_primary" = false
+WHERE "is_primary" = true AND "deleted_at" IS NULL AND "id" NOT IN (
+    SELECT DISTINCT ON ("user_id") "id" FROM "addresses"
+    WHERE "is_primary" = true AND "deleted_at" IS NULL
+    ORDER BY "user_id", "created_at" DESC
+);
+
+-- Promote the most recent address of users without a primary address
+UPDATE "addresses" SET "is_primary" = true
+WHERE "id" IN (
+    SELECT DISTINCT ON ("user_id") "id" FROM "addresses" a
+    WHERE "deleted_at" IS NULL AND NOT EXISTS (
+        SELECT 1 FROM "addresses" p
+        WHERE p."user_id" = a."user_id" AND p."is_primary" = true AND p."deleted_at" IS NULL
+    )
+    ORDER BY "user_id", "created_at" DESC
+);
+
+-- CreateIndex (partial unique index, not expressible in schema.prisma)
+CREATE UNIQUE INDEX "addresses_user_id_primary_key" ON "addresses"("user_id") WHERE "is_primary" = true AND "deleted_at" IS NULL;
@@ -0,0 +1,14 @@
+-- DropIndex
+DROP INDEX "addresses_village_code_idx";
+
+-- AlterTable
+ALTER TABLE "addresses" DROP COLUMN "province_code",
+DROP COLUMN "regency_code",
+DROP COLUMN "district_code",
+DROP COLUMN "village_code";
+
+-- DropTable
+DROP TABLE "regions";
+
+-- DropEnum
+DROP TYPE "RegionLevel";
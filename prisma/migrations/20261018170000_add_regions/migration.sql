@@ -0,0 +1,48 @@
+-- CreateEnum
+CREATE TYPE "RegionLevel" AS ENUM ('PROVINCE', 'REGENCY', 'DISTRICT', 'VILLAGE');
+
+-- CreateTable
+CREATE TABLE "regions" (
+    "code" VARCHAR(13) NOT NULL,
+    "parent_code" VARCHAR(13),
+    "level" "RegionLevel" NOT NULL,
+    "name" VARCHAR(255) NOT NULL,
+    "postal_code" VARCHAR(10),
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "regions_pkey" PRIMARY KEY ("code")
+);
+
+-- CreateIndex
+CREATE INDEX "regions_parent_code_idx" ON "regions"("parent_code");
+
+-- CreateIndex
+CREATE INDEX "regions_level_idx" ON "regions"("level");
+
+-- CreateIndex
+CREATE INDEX "regions_postal_code_idx" ON "regions"("postal_code");
+
+-- AddForeignKey
+ALTER TABLE "regions" ADD CONSTRAINT "regions_parent_code_fkey" FOREIGN KEY ("parent_code") REFERENCES "regions"("code") ON DELETE RESTRICT ON UPDATE CASCADE;
+
+-- AlterTable
+ALTER TABLE "addresses" ADD COLUMN "province_code" VARCHAR(13),
+ADD COLUMN "regency_code" VARCHAR(13),
+ADD COLUMN "district_code" VARCHAR(13),
+ADD COLUMN "village_code" VARCHAR(13);
+
+-- CreateIndex
+CREATE INDEX "addresses_village_code_idx" ON "addresses"("village_code");
+
+-- AddForeignKey
+ALTER TABLE "addresses" ADD CONSTRAINT "addresses_province_code_fkey" FOREIGN KEY ("province_code") REFERENCES "regions"("code") ON DELETE SET NULL ON UPDATE CASCADE;
+
+-- AddForeignKey
+ALTER TABLE "addresses" ADD CONSTRAINT "addresses_regency_code_fkey" FOREIGN KEY ("regency_code") REFERENCES "regions"("code") ON DELETE SET NULL ON UPDATE CASCADE;
+
+-- AddForeignKey
+ALTER TABLE "addresses" ADD CONSTRAINT "addresses_district_code_fkey" FOREIGN KEY ("district_code") REFERENCES "regions"("code") ON DELETE SET NULL ON UPDATE CASCADE;
+
+-- AddForeignKey
+ALTER TABLE "addresses" ADD CONSTRAINT "addresses_village_code_fkey" FOREIGN KEY ("village_code") REFERENCES "regions"("code") ON DELETE SET NULL ON UPDATE CASCADE;
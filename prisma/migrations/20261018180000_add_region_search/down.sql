@@ -0,0 +1,8 @@
+-- DropIndex
+DROP INDEX "regions_full_name_trgm_idx";
+
+-- AlterTable
+ALTER TABLE "regions" DROP COLUMN "full_name";
+
+-- DropFunction; the pg_trgm and unaccent extensions stay installed, as they may be used elsewhere
+DROP FUNCTION "f_unaccent"(text);
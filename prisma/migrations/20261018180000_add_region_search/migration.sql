@@ -0,0 +1,26 @@
+-- CreateExtension
+CREATE EXTENSION IF NOT EXISTS "pg_trgm";
+
+-- CreateExtension
+CREATE EXTENSION IF NOT EXISTS "unaccent";
+
+-- unaccent() is only STABLE, so wrap it in an IMMUTABLE function that can be indexed
+CREATE OR REPLACE FUNCTION "f_unaccent"(text) RETURNS text
+LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT
+AS $$ SELECT public.unaccent('public.unaccent'::regdictionary, $1) $$;
+
+-- AlterTable
+ALTER TABLE "regions" ADD COLUMN "full_name" TEXT NOT NULL DEFAULT '';
+
+-- Backfill the full name (region followed by its ancestors, e.g. "Senayan, Kebayoran Baru, Kota Adm. Jakarta Selatan, DKI Jakarta")
+WITH RECURSIVE "paths" AS (
+    SELECT "code", "name"::TEXT AS "full_name" FROM "regions" WHERE "parent_code" IS NULL
+    UNION ALL
+    SELECT r."code", r."name" || ', ' || p."full_name"
+    FROM "regions" r JOIN "paths" p ON r."parent_code" = p."code"
+)
+UPDATE "regions" SET "full_name" = "paths"."full_name"
+FROM "paths" WHERE "regions"."code" = "paths"."code";
+
+-- CreateIndex
+CREATE INDEX "regions_full_name_trgm_idx" ON "regions" USING GIN ("f_unaccent"(lower("full_name")) gin_trgm_ops);
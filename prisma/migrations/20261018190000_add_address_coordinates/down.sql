@@ -0,0 +1,13 @@
+-- DropIndex; the cube and earthdistance extensions stay installed, as they may be used elsewhere
+DROP INDEX IF EXISTS "addresses_earth_idx";
+DROP INDEX "addresses_latitude_longitude_idx";
+
+-- AlterTable
+ALTER TABLE "addresses" DROP CONSTRAINT "addresses_coordinates_check",
+DROP COLUMN "latitude",
+DROP COLUMN "longitude",
+DROP COLUMN "coordinate_source";
+
+-- AlterTable
+ALTER TABLE "regions" DROP COLUMN "latitude",
+DROP COLUMN "longitude";
@@ -0,0 +1,30 @@
+-- AlterTable
+ALTER TABLE "regions" ADD COLUMN "latitude" DOUBLE PRECISION,
+ADD COLUMN "longitude" DOUBLE PRECISION;
+
+-- AlterTable
+ALTER TABLE "addresses" ADD COLUMN "latitude" DOUBLE PRECISION,
+ADD COLUMN "longitude" DOUBLE PRECISION,
+ADD COLUMN "coordinate_source" VARCHAR(20);
+
+-- AddCheckConstraint
+ALTER TABLE "addresses" ADD CONSTRAINT "addresses_coordinates_check" CHECK (
+    ("latitude" IS NULL AND "longitude" IS NULL)
+    OR ("latitude" BETWEEN -90 AND 90 AND "longitude" BETWEEN -180 AND 180)
+);
+
+-- CreateIndex
+CREATE INDEX "addresses_latitude_longitude_idx" ON "addresses"("latitude", "longitude");
+
+-- Radius queries use earthdistance when the extension can be installed, and fall back to Haversine in the application otherwise
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_available_extensions WHERE "name" = 'earthdistance') THEN
+        CREATE EXTENSION IF NOT EXISTS "cube";
+        CREATE EXTENSION IF NOT EXISTS "earthdistance";
+        CREATE INDEX "addresses_earth_idx" ON "addresses" USING GIST (ll_to_earth("latitude", "longitude")) WHERE "latitude" IS NOT NULL;
+    END IF;
+EXCEPTION WHEN insufficient_privilege THEN
+    RAISE NOTICE 'earthdistance is not installed, radius queries use the application fallback';
+END
+$$;
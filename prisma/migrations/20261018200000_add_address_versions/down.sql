@@ -0,0 +1,9 @@
+-- AlterTable
+ALTER TABLE "addresses" DROP COLUMN "current_version_id",
+DROP COLUMN "version";
+
+-- DropTable
+DROP TABLE "address_versions";
+
+-- DropFunction
+DROP FUNCTION "address_versions_prevent_update"();
@@ -0,0 +1,65 @@
+-- CreateTable
+CREATE TABLE "address_versions" (
+    "id" UUID NOT NULL DEFAULT gen_random_uuid(),
+    "address_id" UUID NOT NULL,
+    "user_id" UUID NOT NULL,
+    "version" INTEGER NOT NULL,
+    "label" VARCHAR(100) NOT NULL,
+    "recipient_name" VARCHAR(255) NOT NULL,
+    "phone" VARCHAR(20) NOT NULL,
+    "province" VARCHAR(100) NOT NULL,
+    "city" VARCHAR(100) NOT NULL,
+    "district" VARCHAR(100) NOT NULL,
+    "sub_district" VARCHAR(100) NOT NULL,
+    "postal_code" VARCHAR(10) NOT NULL,
+    "full_address" TEXT NOT NULL,
+    "province_code" VARCHAR(13),
+    "regency_code" VARCHAR(13),
+    "district_code" VARCHAR(13),
+    "village_code" VARCHAR(13),
+    "latitude" DOUBLE PRECISION,
+    "longitude" DOUBLE PRECISION,
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "created_by" UUID,
+
+    CONSTRAINT "address_versions_pkey" PRIMARY KEY ("id")
+);
+
+-- AlterTable
+ALTER TABLE "addresses" ADD COLUMN "version" INTEGER NOT NULL DEFAULT 1,
+ADD COLUMN "current_version_id" UUID;
+
+-- CreateIndex
+CREATE UNIQUE INDEX "address_versions_address_id_version_key" ON "address_versions"("address_id", "version");
+
+-- CreateIndex
+CREATE INDEX "address_versions_user_id_idx" ON "address_versions"("user_id");
+
+-- AddForeignKey
+ALTER TABLE "address_versions" ADD CONSTRAINT "address_versions_address_id_fkey" FOREIGN KEY ("address_id") REFERENCES "addresses"("id") ON DELETE CASCADE ON UPDATE CASCADE;
+
+-- AddForeignKey
+ALTER TABLE "address_versions" ADD CONSTRAINT "address_versions_user_id_fkey" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE ON UPDATE CASCADE;
+
+-- Versions are immutable: rows can only be inserted, or removed together with their address
+CREATE OR REPLACE FUNCTION "address_versions_prevent_update"() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    RAISE EXCEPTION 'address versions are immutable';
+END
+$$;
+
+-- CreateTrigger
+CREATE TRIGGER "address_versions_immutable" BEFORE UPDATE ON "address_versions"
+FOR EACH ROW EXECUTE FUNCTION "address_versions_prevent_update"();
+
+-- Backfill the first version of every existing address
+INSERT INTO "address_versions" ("address_id", "user_id", "version", "label", "recipient_name", "phone", "province", "city", "district", "sub_district", "postal_code", "full_address", "province_code", "regency_code", "district_code", "village_code", "latitude", "longitude", "created_at", "created_by")
+SELECT "id", "user_id", 1, "label", "recipient_name", "phone", "province", "city", "district", "sub_district", "postal_code", "full_address", "province_code", "regency_code", "district_code", "village_code", "latitude", "longitude", "updated_at", COALESCE("updated_by", "created_by")
+FROM "addresses";
+
+UPDATE "addresses" SET "current_version_id" = v."id"
+FROM "address_versions" v WHERE v."address_id" = "addresses"."id" AND v."version" = 1;
+
+-- AddForeignKey
+ALTER TABLE "addresses" ADD CONSTRAINT "addresses_current_version_id_fkey" FOREIGN KEY ("current_version_id") REFERENCES "address_versions"("id") ON DELETE SET NULL ON UPDATE CASCADE;
@@ -0,0 +1,5 @@
+-- DropTable
+DROP TABLE "attachments";
+
+-- DropEnum
+DROP TYPE "AttachmentKind";
@@ -0,0 +1,25 @@
+-- CreateEnum
+CREATE TYPE "AttachmentKind" AS ENUM ('IMAGE', 'DOCUMENT', 'PRODUCT_IMAGE', 'PROFILE_IMAGE');
+
+-- CreateTable
+CREATE TABLE "attachments" (
+    "id" UUID NOT NULL DEFAULT gen_random_uuid(),
+    "owner_id" UUID NOT NULL,
+    "kind" "AttachmentKind" NOT NULL,
+    "provider_file_id" VARCHAR(255) NOT NULL,
+    "file_name" VARCHAR(255) NOT NULL,
+    "path" TEXT NOT NULL,
+    "url" TEXT NOT NULL,
+    "mime_type" VARCHAR(255) NOT NULL,
+    "size" BIGINT NOT NULL,
+    "checksum" VARCHAR(64) NOT NULL,
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "attachments_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE INDEX "attachments_owner_id_created_at_idx" ON "attachments"("owner_id", "created_at");
+
+-- AddForeignKey
+ALTER TABLE "attachments" ADD CONSTRAINT "attachments_owner_id_fkey" FOREIGN KEY ("owner_id") REFERENCES "users"("id") ON DELETE CASCADE ON UPDATE CASCADE;
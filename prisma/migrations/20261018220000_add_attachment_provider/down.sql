@@ -0,0 +1,2 @@
+-- AlterTable; the paths stay without a leading slash
+ALTER TABLE "attachments" DROP COLUMN "provider";
@@ -0,0 +1,6 @@
+-- AlterTable: existing attachments were all uploaded to ImageKit
+ALTER TABLE "attachments" ADD COLUMN "provider" VARCHAR(20) NOT NULL DEFAULT 'imagekit';
+ALTER TABLE "attachments" ALTER COLUMN "provider" DROP DEFAULT;
+
+-- Keys are stored without the leading slash ImageKit reports
+UPDATE "attachments" SET "path" = ltrim("path", '/');
@@ -0,0 +1,4 @@
+-- AlterTable
+ALTER TABLE "attachments" DROP COLUMN "width",
+DROP COLUMN "height",
+DROP COLUMN "variants";
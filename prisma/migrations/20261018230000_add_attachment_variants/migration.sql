@@ -0,0 +1,4 @@
+-- AlterTable: pixel size of image attachments and the resized copies generated for them
+ALTER TABLE "attachments" ADD COLUMN "width" INTEGER,
+ADD COLUMN "height" INTEGER,
+ADD COLUMN "variants" JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,9 @@
+-- DropIndex
+DROP INDEX "attachments_status_upload_expires_at_idx";
+
+-- AlterTable
+ALTER TABLE "attachments" DROP COLUMN "status",
+DROP COLUMN "upload_expires_at";
+
+-- DropEnum
+DROP TYPE "AttachmentStatus";
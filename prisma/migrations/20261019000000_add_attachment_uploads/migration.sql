@@ -0,0 +1,9 @@
+-- CreateEnum
+CREATE TYPE "AttachmentStatus" AS ENUM ('PENDING_UPLOAD', 'READY');
+
+-- AlterTable: direct uploads are registered before the file exists and expire if never completed
+ALTER TABLE "attachments" ADD COLUMN "status" "AttachmentStatus" NOT NULL DEFAULT 'READY',
+ADD COLUMN "upload_expires_at" TIMESTAMP(3);
+
+-- CreateIndex
+CREATE INDEX "attachments_status_upload_expires_at_idx" ON "attachments"("status", "upload_expires_at");
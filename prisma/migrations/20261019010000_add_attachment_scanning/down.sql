@@ -0,0 +1,12 @@
+-- AlterTable
+ALTER TABLE "attachments" DROP COLUMN "scanned_at",
+DROP COLUMN "malware_signature";
+
+-- AlterEnum: values cannot be dropped, so the enum is recreated without them. This fails
+-- while attachments are waiting for a scan or quarantined, rather than releasing them.
+ALTER TYPE "AttachmentStatus" RENAME TO "AttachmentStatus_old";
+CREATE TYPE "AttachmentStatus" AS ENUM ('PENDING_UPLOAD', 'READY');
+ALTER TABLE "attachments" ALTER COLUMN "status" DROP DEFAULT;
+ALTER TABLE "attachments" ALTER COLUMN "status" TYPE "AttachmentStatus" USING ("status"::TEXT::"AttachmentStatus");
+ALTER TABLE "attachments" ALTER COLUMN "status" SET DEFAULT 'READY';
+DROP TYPE "AttachmentStatus_old";
@@ -0,0 +1,7 @@
+-- AlterEnum: files stored as sent are scanned for malware before they can be downloaded
+ALTER TYPE "AttachmentStatus" ADD VALUE 'PENDING_SCAN' BEFORE 'READY';
+ALTER TYPE "AttachmentStatus" ADD VALUE 'QUARANTINED';
+
+-- AlterTable
+ALTER TABLE "attachments" ADD COLUMN "scanned_at" TIMESTAMP(3),
+ADD COLUMN "malware_signature" TEXT;
@@ -0,0 +1,5 @@
+-- AlterTable
+ALTER TABLE "attachments" DROP COLUMN "visibility";
+
+-- DropEnum
+DROP TYPE "AttachmentVisibility";
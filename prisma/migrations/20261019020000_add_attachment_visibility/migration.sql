@@ -0,0 +1,6 @@
+-- CreateEnum
+CREATE TYPE "AttachmentVisibility" AS ENUM ('PUBLIC', 'PRIVATE');
+
+-- AlterTable: private files are only reachable through signed, expiring download URLs.
+-- Existing files stay public as they are already stored at public URLs.
+ALTER TABLE "attachments" ADD COLUMN "visibility" "AttachmentVisibility" NOT NULL DEFAULT 'PUBLIC';
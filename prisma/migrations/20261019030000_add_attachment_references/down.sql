@@ -0,0 +1,5 @@
+-- DropIndex
+DROP INDEX "attachments_owner_id_checksum_idx";
+
+-- DropTable
+DROP TABLE "attachment_references";
@@ -0,0 +1,26 @@
+-- CreateTable: users and products referencing an attachment; attachments of the
+-- referenced kinds without any reference are deleted after a grace period
+CREATE TABLE "attachment_references" (
+    "attachment_id" UUID NOT NULL,
+    "subject_type" VARCHAR(50) NOT NULL,
+    "subject_id" UUID NOT NULL,
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "attachment_references_pkey" PRIMARY KEY ("attachment_id", "subject_type", "subject_id")
+);
+
+-- CreateIndex
+CREATE INDEX "attachment_references_subject_type_subject_id_idx" ON "attachment_references"("subject_type", "subject_id");
+
+-- CreateIndex: finds stored content of the same owner to deduplicate uploads
+CREATE INDEX "attachments_owner_id_checksum_idx" ON "attachments"("owner_id", "checksum");
+
+-- AddForeignKey
+ALTER TABLE "attachment_references" ADD CONSTRAINT "attachment_references_attachment_id_fkey" FOREIGN KEY ("attachment_id") REFERENCES "attachments"("id") ON DELETE CASCADE ON UPDATE CASCADE;
+
+-- Backfill: the latest profile image of each user is the one in use
+INSERT INTO "attachment_references" ("attachment_id", "subject_type", "subject_id")
+SELECT DISTINCT ON ("owner_id") "id", 'user', "owner_id"
+FROM "attachments"
+WHERE "kind" = 'PROFILE_IMAGE' AND "status" = 'READY'
+ORDER BY "owner_id", "created_at" DESC;
@@ -0,0 +1,12 @@
+-- AlterTable: the profile image shown for the user; a generated one with their initials is used when empty
+ALTER TABLE "users" ADD COLUMN "avatar_id" UUID;
+
+-- AddForeignKey
+ALTER TABLE "users" ADD CONSTRAINT "users_avatar_id_fkey" FOREIGN KEY ("avatar_id") REFERENCES "attachments"("id") ON DELETE SET NULL ON UPDATE CASCADE;
+
+-- Backfill: the profile image each user references becomes their avatar
+UPDATE "users" u
+SET "avatar_id" = ar."attachment_id"
+FROM "attachment_references" ar
+JOIN "attachments" a ON a."id" = ar."attachment_id"
+WHERE ar."subject_type" = 'user' AND ar."subject_id" = u."id" AND a."kind" = 'PROFILE_IMAGE';
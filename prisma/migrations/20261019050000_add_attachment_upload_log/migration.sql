@@ -0,0 +1,21 @@
+-- CreateTable: one row per upload a user started, kept for the uploads-per-hour quota
+-- after the attachment itself is deleted
+CREATE TABLE "attachment_uploads" (
+    "id" UUID NOT NULL DEFAULT gen_random_uuid(),
+    "owner_id" UUID NOT NULL,
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "attachment_uploads_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE INDEX "attachment_uploads_owner_id_created_at_idx" ON "attachment_uploads"("owner_id", "created_at");
+
+-- AddForeignKey
+ALTER TABLE "attachment_uploads" ADD CONSTRAINT "attachment_uploads_owner_id_fkey" FOREIGN KEY ("owner_id") REFERENCES "users"("id") ON DELETE CASCADE ON UPDATE CASCADE;
+
+-- Backfill: uploads of the last hour that still exist
+INSERT INTO "attachment_uploads" ("owner_id", "created_at")
+SELECT "owner_id", "created_at"
+FROM "attachments"
+WHERE "created_at" > CURRENT_TIMESTAMP - INTERVAL '1 hour';
@@ -0,0 +1,4 @@
+-- Fails while a deleted user shares an email with another user
+DROP INDEX "users_email_key";
+
+CREATE UNIQUE INDEX "users_email_key" ON "users"("email");
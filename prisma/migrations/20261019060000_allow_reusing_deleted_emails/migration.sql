@@ -0,0 +1,5 @@
+-- DropIndex: the email of a deleted user may be registered again
+DROP INDEX "users_email_key";
+
+-- CreateIndex (partial unique index, not expressible in schema.prisma)
+CREATE UNIQUE INDEX "users_email_key" ON "users"("email") WHERE "deleted_at" IS NULL;